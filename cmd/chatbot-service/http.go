@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,6 +14,8 @@ import (
 	"github.com/rs/zerolog"
 
 	"github.com/david7482/aws-serverless-service/internal/app"
+	"github.com/david7482/aws-serverless-service/internal/app/service/auth"
+	"github.com/david7482/aws-serverless-service/internal/domain"
 	"github.com/david7482/aws-serverless-service/internal/router"
 )
 
@@ -22,7 +25,7 @@ func runHTTPServer(rootCtx context.Context, wg *sync.WaitGroup, port int, app *a
 
 	// Create gin router
 	ginRouter := gin.New()
-	setMiddlewares(rootCtx, ginRouter)
+	setMiddlewares(rootCtx, ginRouter, app)
 
 	// Register all handlers
 	router.RegisterHandlers(ginRouter, app)
@@ -61,10 +64,69 @@ func runHTTPServer(rootCtx context.Context, wg *sync.WaitGroup, port int, app *a
 	}()
 }
 
-func setMiddlewares(ctx context.Context, ginRouter *gin.Engine) {
+func setMiddlewares(ctx context.Context, ginRouter *gin.Engine, app *app.Application) {
 	ginRouter.Use(gin.Recovery())
 	ginRouter.Use(requestid.New())
 	ginRouter.Use(loggerMiddleware(ctx))
+	ginRouter.Use(authMiddleware(app))
+}
+
+// publicAPIPaths are API routes called by LINE or load balancers, which are not authenticated
+var publicAPIPaths = map[string]bool{
+	"/api/v1/health": true,
+	"/api/v1/webhook/line/:external_channel_id/events": true,
+}
+
+// privatePagePaths are pages outside /api showing resources of an organization, which are
+// authenticated like the admin API
+var privatePagePaths = map[string]bool{
+	"/orgs/:org_id/channels/:channel_id/slide": true,
+}
+
+// authMiddleware authenticates requests to the admin API and private pages with either an API key or a
+// bearer token exchanged with it, both sent in the Authorization header as "Bearer <credential>".
+// Other pages and unknown routes are left to their handlers.
+func authMiddleware(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fullPath := c.FullPath()
+		isAPI := strings.HasPrefix(fullPath, "/api/") && !publicAPIPaths[fullPath]
+		if !isAPI && !privatePagePaths[fullPath] {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		header := c.GetHeader("Authorization")
+		credential := strings.TrimPrefix(header, "Bearer ")
+		if credential == header || credential == "" {
+			msg := "missing bearer credential"
+			abortUnauthorized(c, domain.NewUnauthorizedError(msg, errors.New(msg)))
+			return
+		}
+
+		var principal *domain.Principal
+		var err domain.Error
+		if strings.HasPrefix(credential, auth.APIKeyPrefix) {
+			principal, err = app.AccountService.AuthenticateAPIKey(ctx, credential)
+		} else {
+			principal, err = app.TokenService.VerifyToken(ctx, credential)
+		}
+		if err != nil {
+			abortUnauthorized(c, err)
+			return
+		}
+
+		c.Request = c.Request.WithContext(auth.WithPrincipal(ctx, *principal))
+		c.Next()
+	}
+}
+
+func abortUnauthorized(c *gin.Context, err domain.Error) {
+	var unauthorized domain.UnauthorizedError
+	if errors.As(err, &unauthorized) {
+		c.Header("WWW-Authenticate", `Bearer realm="chatbot"`)
+	}
+	router.AbortWithError(c, err)
 }
 
 // This logger is referenced from gin's logger implementation with additional capabilities:
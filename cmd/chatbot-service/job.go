@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/david7482/aws-serverless-service/internal/app"
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+// runPeriodically runs fn in a goroutine right away and then every interval until rootCtx is done
+func runPeriodically(rootCtx context.Context, wg *sync.WaitGroup, name string, interval time.Duration, fn func(ctx context.Context)) {
+	logger := zerolog.Ctx(rootCtx).With().Str("job", name).Logger()
+	ctx := logger.WithContext(rootCtx)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		logger.Info().Dur("interval", interval).Msgf("%s is running", name)
+		for {
+			fn(ctx)
+
+			select {
+			case <-ticker.C:
+			case <-rootCtx.Done():
+				// Notify when job is closed
+				logger.Info().Msgf("%s is closed", name)
+				wg.Done()
+				return
+			}
+		}
+	}()
+}
+
+func runTokenRefresher(rootCtx context.Context, wg *sync.WaitGroup, interval time.Duration, app *app.Application) {
+	runPeriodically(rootCtx, wg, "token refresher", interval, func(ctx context.Context) {
+		// errors are logged by the service, and the next round would retry
+		_ = app.ChannelService.RefreshExpiringAccessTokens(ctx)
+	})
+}
+
+func runTokenRevocationRetrier(rootCtx context.Context, wg *sync.WaitGroup, interval time.Duration, app *app.Application) {
+	runPeriodically(rootCtx, wg, "token revocation retrier", interval, func(ctx context.Context) {
+		// errors are logged by the service, and the next round would retry
+		_ = app.ChannelService.RetryAccessTokenRevocations(ctx)
+	})
+}
+
+func runOutboxRelay(rootCtx context.Context, wg *sync.WaitGroup, interval time.Duration, app *app.Application) {
+	runPeriodically(rootCtx, wg, "outbox relay", interval, func(ctx context.Context) {
+		// errors are logged by the service, and the next round would retry
+		_ = app.MsgService.RelayOutboxEvents(ctx)
+	})
+}
+
+func runOutboxPurger(rootCtx context.Context, wg *sync.WaitGroup, interval time.Duration, app *app.Application) {
+	runPeriodically(rootCtx, wg, "outbox purger", interval, func(ctx context.Context) {
+		// errors are logged by the service, and the next round would retry
+		_ = app.MsgService.PurgeSentOutboxEvents(ctx)
+	})
+}
+
+func runMemberProfileRefresher(rootCtx context.Context, wg *sync.WaitGroup, interval time.Duration, app *app.Application) {
+	runPeriodically(rootCtx, wg, "member profile refresher", interval, func(ctx context.Context) {
+		// errors are logged by the service, and the next round would retry
+		_ = app.MsgService.RefreshMemberProfiles(ctx)
+	})
+}
+
+func runGroupSummaryRefresher(rootCtx context.Context, wg *sync.WaitGroup, interval time.Duration, app *app.Application) {
+	runPeriodically(rootCtx, wg, "group summary refresher", interval, func(ctx context.Context) {
+		// errors are logged by the service, and the next round would retry
+		_ = app.MsgService.RefreshGroupSummaries(ctx)
+	})
+}
+
+func runWebhookEventPurger(rootCtx context.Context, wg *sync.WaitGroup, interval time.Duration, app *app.Application) {
+	runPeriodically(rootCtx, wg, "webhook event purger", interval, func(ctx context.Context) {
+		// errors are logged by the service, and the next round would retry
+		_ = app.MsgService.PurgeExpiredWebhookEvents(ctx)
+	})
+}
+
+func runHealthChecker(rootCtx context.Context, wg *sync.WaitGroup, interval time.Duration, app *app.Application) {
+	runPeriodically(rootCtx, wg, "health checker", interval, func(ctx context.Context) {
+		// errors are logged by the service, and the next round would retry
+		_ = app.ChannelService.CheckChannelsHealth(ctx)
+	})
+}
+
+// runLocalWorker handles events published to the in-memory event bus one by one until rootCtx is done.
+// Events still in the bus are dropped when it's closed.
+func runLocalWorker(rootCtx context.Context, wg *sync.WaitGroup, app *app.Application) {
+	logger := zerolog.Ctx(rootCtx).With().Str("job", "local worker").Logger()
+	ctx := logger.WithContext(rootCtx)
+
+	go func() {
+		logger.Info().Msg("local worker is running")
+		for {
+			select {
+			case data := <-app.LocalEventBus.Events():
+				var e domain.ChannelEvent
+				if err := json.Unmarshal([]byte(data), &e); err != nil {
+					logger.Error().Err(err).Msg("fail to unmarshal event")
+					continue
+				}
+				// errors are logged by the worker, and tracked in the task of the event
+				_ = app.Worker.HandleEvent(ctx, newLocalEventID(), e)
+			case <-rootCtx.Done():
+				// Notify when job is closed
+				logger.Info().Msg("local worker is closed")
+				wg.Done()
+				return
+			}
+		}
+	}()
+}
+
+// runChannelListener invalidates cached channels on changes made by any instance until rootCtx is done
+func runChannelListener(rootCtx context.Context, wg *sync.WaitGroup, app *app.Application) {
+	logger := zerolog.Ctx(rootCtx).With().Str("job", "channel listener").Logger()
+	ctx := logger.WithContext(rootCtx)
+
+	go func() {
+		logger.Info().Msg("channel listener is running")
+		app.ChannelListener.Listen(ctx, app.ChannelCache)
+
+		// Notify when job is closed
+		logger.Info().Msg("channel listener is closed")
+		wg.Done()
+	}()
+}
+
+// newLocalEventID returns a random ID for events handled by the local worker, like EventBridge does
+func newLocalEventID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return "local-" + hex.EncodeToString(id)
+}
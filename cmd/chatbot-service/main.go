@@ -22,10 +22,29 @@ var (
 )
 
 const (
-	defaultEnv       = "staging"
-	defaultLogLevel  = "info"
-	defaultPort      = "8000"
-	defaultAWSRegion = "us-west-2"
+	defaultEnv                          = "staging"
+	defaultLogLevel                     = "info"
+	defaultPort                         = "8000"
+	defaultAWSRegion                    = "us-west-2"
+	defaultEncryptionProvider           = "static"
+	defaultEventBus                     = "eventbridge"
+	defaultTokenRefreshInterval         = "10m"
+	defaultTokenRefreshWindow           = "120h"
+	defaultTokenRevocationRetryInterval = "5m"
+	defaultTokenRevocationGrace         = "24h"
+	defaultHealthCheckPollInterval      = "1m"
+	defaultHealthCheckInterval          = "1h"
+	defaultTokenTTL                     = "1h"
+	defaultMemberProfileTTL             = "24h"
+	defaultMemberProfileRefreshInterval = "1m"
+	defaultGroupSummaryRefreshInterval  = "1m"
+	defaultOutboxRelayInterval          = "1s"
+	defaultOutboxPurgeInterval          = "1h"
+	defaultWebhookDedupeStore           = "postgres"
+	defaultWebhookDedupeTTL             = "24h"
+	defaultWebhookDedupePurgeInterval   = "1h"
+	defaultChannelCacheTTL              = "1m"
+	defaultChannelCacheNegativeTTL      = "10s"
 )
 
 type AppConfig struct {
@@ -39,9 +58,42 @@ type AppConfig struct {
 	// Database configuration
 	DatabaseDSN *string
 
+	// Encryption configuration
+	EncryptionKeyProvider *string
+	EncryptionStaticKey   *string
+	ReencryptChannels     *bool
+
 	// AWS configuration
 	AWSRegion          *string
 	AWSEventBridgeName *string
+	AWSSQSQueueURL     *string
+	AWSKMSKeyID        *string
+
+	// Event bus configuration
+	EventBus *string
+	Local    *bool
+
+	// Token refresher configuration
+	TokenRefreshInterval         *time.Duration
+	TokenRefreshWindow           *time.Duration
+	TokenRevocationRetryInterval *time.Duration
+	TokenRevocationGrace         *time.Duration
+	PublicBaseURL                *string
+	HealthCheckPollInterval      *time.Duration
+	HealthCheckInterval          *time.Duration
+	AdminAPIKey                  *string
+	TokenSecret                  *string
+	TokenTTL                     *time.Duration
+	MemberProfileTTL             *time.Duration
+	MemberProfileRefreshInterval *time.Duration
+	GroupSummaryRefreshInterval  *time.Duration
+	OutboxRelayInterval          *time.Duration
+	OutboxPurgeInterval          *time.Duration
+	WebhookDedupeStore           *string
+	WebhookDedupeTTL             *time.Duration
+	WebhookDedupePurgeInterval   *time.Duration
+	ChannelCacheTTL              *time.Duration
+	ChannelCacheNegativeTTL      *time.Duration
 }
 
 func initAppConfig() AppConfig {
@@ -67,13 +119,121 @@ func initAppConfig() AppConfig {
 		Flag("database_dsn", "The database DSN").
 		Envar("DATABASE_DSN").Required().String()
 
+	config.EncryptionKeyProvider = app.
+		Flag("encryption_key_provider", "The key provider to encrypt channel credentials at rest").
+		Envar("ENCRYPTION_KEY_PROVIDER").Default(defaultEncryptionProvider).Enum("static", "kms")
+
+	config.EncryptionStaticKey = app.
+		Flag("encryption_static_key", "The base64 encoded 256-bit key used by the static key provider").
+		Envar("ENCRYPTION_STATIC_KEY").String()
+
+	config.ReencryptChannels = app.
+		Flag("reencrypt_channels", "Re-encrypt credentials of all channels with the current key and exit").
+		Envar("REENCRYPT_CHANNELS").Bool()
+
 	config.AWSRegion = app.
 		Flag("aws_region", "The AWS region").
 		Envar("AWS_REGION").Default(defaultAWSRegion).String()
 
+	config.EventBus = app.
+		Flag("event_bus", "The event bus which webhook events are published to, which is ignored in local mode").
+		Envar("EVENT_BUS").Default(defaultEventBus).Enum("eventbridge", "sqs")
+
+	config.Local = app.
+		Flag("local", "Run the worker in the service with an in-memory event bus for local development").
+		Envar("LOCAL").Bool()
+
 	config.AWSEventBridgeName = app.
-		Flag("aws_eventbridge_name", "The AWS EventBridge bus name").
-		Envar("AWS_EVENTBRIDGE_NAME").Required().String()
+		Flag("aws_eventbridge_name", "The AWS EventBridge bus name used by the eventbridge event bus").
+		Envar("AWS_EVENTBRIDGE_NAME").String()
+
+	config.AWSSQSQueueURL = app.
+		Flag("aws_sqs_queue_url", "The AWS SQS queue URL used by the sqs event bus").
+		Envar("AWS_SQS_QUEUE_URL").String()
+
+	config.AWSKMSKeyID = app.
+		Flag("aws_kms_key_id", "The AWS KMS key used by the kms key provider").
+		Envar("AWS_KMS_KEY_ID").String()
+
+	config.TokenRefreshInterval = app.
+		Flag("token_refresh_interval", "How often to look for channel access tokens to refresh").
+		Envar("TOKEN_REFRESH_INTERVAL").Default(defaultTokenRefreshInterval).Duration()
+
+	config.TokenRefreshWindow = app.
+		Flag("token_refresh_window", "How long before expiry a channel access token gets refreshed").
+		Envar("TOKEN_REFRESH_WINDOW").Default(defaultTokenRefreshWindow).Duration()
+
+	config.TokenRevocationRetryInterval = app.
+		Flag("token_revocation_retry_interval", "How often to retry failed channel access token revocations").
+		Envar("TOKEN_REVOCATION_RETRY_INTERVAL").Default(defaultTokenRevocationRetryInterval).Duration()
+
+	config.TokenRevocationGrace = app.
+		Flag("token_revocation_grace", "How long a replaced channel access token stays valid before it is revoked, which should outlast retries of events carrying it").
+		Envar("TOKEN_REVOCATION_GRACE").Default(defaultTokenRevocationGrace).Duration()
+
+	config.PublicBaseURL = app.
+		Flag("public_base_url", "The base URL LINE reaches this service with, e.g. https://chatbot.example.com").
+		Envar("PUBLIC_BASE_URL").String()
+
+	config.HealthCheckPollInterval = app.
+		Flag("health_check_poll_interval", "How often to look for channels due to be health checked").
+		Envar("HEALTH_CHECK_POLL_INTERVAL").Default(defaultHealthCheckPollInterval).Duration()
+
+	config.HealthCheckInterval = app.
+		Flag("health_check_interval", "How often each channel gets its health checked").
+		Envar("HEALTH_CHECK_INTERVAL").Default(defaultHealthCheckInterval).Duration()
+
+	config.AdminAPIKey = app.
+		Flag("admin_api_key", "The API key of the admin, who could access all organizations. It must start with cbk_").
+		Envar("ADMIN_API_KEY").String()
+
+	config.TokenSecret = app.
+		Flag("token_secret", "The secret to sign bearer tokens, which must be shared by all instances").
+		Envar("TOKEN_SECRET").String()
+
+	config.TokenTTL = app.
+		Flag("token_ttl", "The lifetime of bearer tokens exchanged with API keys").
+		Envar("TOKEN_TTL").Default(defaultTokenTTL).Duration()
+
+	config.MemberProfileTTL = app.
+		Flag("member_profile_ttl", "How long member profiles and group summaries fetched from LINE are used before they are fetched again").
+		Envar("MEMBER_PROFILE_TTL").Default(defaultMemberProfileTTL).Duration()
+
+	config.MemberProfileRefreshInterval = app.
+		Flag("member_profile_refresh_interval", "How often to fetch member profiles which are new or expired from LINE").
+		Envar("MEMBER_PROFILE_REFRESH_INTERVAL").Default(defaultMemberProfileRefreshInterval).Duration()
+
+	config.GroupSummaryRefreshInterval = app.
+		Flag("group_summary_refresh_interval", "How often to fetch group summaries which are new or expired from LINE").
+		Envar("GROUP_SUMMARY_REFRESH_INTERVAL").Default(defaultGroupSummaryRefreshInterval).Duration()
+
+	config.OutboxRelayInterval = app.
+		Flag("outbox_relay_interval", "How often to publish events in the outbox to the event bus").
+		Envar("OUTBOX_RELAY_INTERVAL").Default(defaultOutboxRelayInterval).Duration()
+
+	config.OutboxPurgeInterval = app.
+		Flag("outbox_purge_interval", "How often to purge sent events from the outbox").
+		Envar("OUTBOX_PURGE_INTERVAL").Default(defaultOutboxPurgeInterval).Duration()
+
+	config.WebhookDedupeStore = app.
+		Flag("webhook_dedupe_store", "The store remembering received webhook events to drop redeliveries").
+		Envar("WEBHOOK_DEDUPE_STORE").Default(defaultWebhookDedupeStore).Enum("postgres", "memory")
+
+	config.WebhookDedupeTTL = app.
+		Flag("webhook_dedupe_ttl", "How long received webhook events are remembered").
+		Envar("WEBHOOK_DEDUPE_TTL").Default(defaultWebhookDedupeTTL).Duration()
+
+	config.WebhookDedupePurgeInterval = app.
+		Flag("webhook_dedupe_purge_interval", "How often to purge expired webhook events from the dedupe store").
+		Envar("WEBHOOK_DEDUPE_PURGE_INTERVAL").Default(defaultWebhookDedupePurgeInterval).Duration()
+
+	config.ChannelCacheTTL = app.
+		Flag("channel_cache_ttl", "How long channels looked up by webhooks are cached, which bounds how stale they get if a change notification is missed").
+		Envar("CHANNEL_CACHE_TTL").Default(defaultChannelCacheTTL).Duration()
+
+	config.ChannelCacheNegativeTTL = app.
+		Flag("channel_cache_negative_ttl", "How long unknown channel IDs of webhooks are cached").
+		Envar("CHANNEL_CACHE_NEGATIVE_TTL").Default(defaultChannelCacheNegativeTTL).Duration()
 
 	kingpin.MustParse(app.Parse(os.Args[1:]))
 
@@ -119,16 +279,67 @@ func main() {
 
 	// Create application
 	app := app.MustNewApplication(rootCtx, app.ApplicationParams{
-		DatabaseDSN:        *cfg.DatabaseDSN,
-		AWSRegion:          *cfg.AWSRegion,
-		AWSEventBridgeName: *cfg.AWSEventBridgeName,
+		DatabaseDSN:             *cfg.DatabaseDSN,
+		EncryptionKeyProvider:   *cfg.EncryptionKeyProvider,
+		EncryptionStaticKey:     *cfg.EncryptionStaticKey,
+		AWSRegion:               *cfg.AWSRegion,
+		EventBus:                *cfg.EventBus,
+		Local:                   *cfg.Local,
+		AWSEventBridgeName:      *cfg.AWSEventBridgeName,
+		AWSSQSQueueURL:          *cfg.AWSSQSQueueURL,
+		AWSKMSKeyID:             *cfg.AWSKMSKeyID,
+		TokenRefreshWindow:      *cfg.TokenRefreshWindow,
+		TokenRevocationGrace:    *cfg.TokenRevocationGrace,
+		PublicBaseURL:           *cfg.PublicBaseURL,
+		HealthCheckInterval:     *cfg.HealthCheckInterval,
+		AdminAPIKey:             *cfg.AdminAPIKey,
+		TokenSecret:             *cfg.TokenSecret,
+		TokenTTL:                *cfg.TokenTTL,
+		MemberProfileTTL:        *cfg.MemberProfileTTL,
+		WebhookDedupeStore:      *cfg.WebhookDedupeStore,
+		WebhookDedupeTTL:        *cfg.WebhookDedupeTTL,
+		ChannelCacheTTL:         *cfg.ChannelCacheTTL,
+		ChannelCacheNegativeTTL: *cfg.ChannelCacheNegativeTTL,
 	})
 
+	// Re-encrypt channel credentials only if requested
+	if *cfg.ReencryptChannels {
+		if _, err := app.ChannelService.ReencryptChannels(rootCtx); err != nil {
+			rootLogger.Fatal().Err(err).Msg("fail to re-encrypt channels")
+		}
+		rootCtxCancelFunc()
+		return
+	}
+
 	// Run server
 	wg := sync.WaitGroup{}
 	wg.Add(1)
 	runHTTPServer(rootCtx, &wg, *cfg.Port, app)
 
+	// Run background jobs
+	wg.Add(1)
+	runTokenRefresher(rootCtx, &wg, *cfg.TokenRefreshInterval, app)
+	wg.Add(1)
+	runTokenRevocationRetrier(rootCtx, &wg, *cfg.TokenRevocationRetryInterval, app)
+	wg.Add(1)
+	runHealthChecker(rootCtx, &wg, *cfg.HealthCheckPollInterval, app)
+	wg.Add(1)
+	runOutboxRelay(rootCtx, &wg, *cfg.OutboxRelayInterval, app)
+	wg.Add(1)
+	runOutboxPurger(rootCtx, &wg, *cfg.OutboxPurgeInterval, app)
+	wg.Add(1)
+	runWebhookEventPurger(rootCtx, &wg, *cfg.WebhookDedupePurgeInterval, app)
+	wg.Add(1)
+	runMemberProfileRefresher(rootCtx, &wg, *cfg.MemberProfileRefreshInterval, app)
+	wg.Add(1)
+	runGroupSummaryRefresher(rootCtx, &wg, *cfg.GroupSummaryRefreshInterval, app)
+	wg.Add(1)
+	runChannelListener(rootCtx, &wg, app)
+	if *cfg.Local {
+		wg.Add(1)
+		runLocalWorker(rootCtx, &wg, app)
+	}
+
 	// Listen to SIGTERM/SIGINT to close
 	var gracefulStop = make(chan os.Signal, 1)
 	signal.Notify(gracefulStop, syscall.SIGTERM, syscall.SIGINT)
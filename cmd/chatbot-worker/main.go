@@ -5,21 +5,23 @@ import (
 	"encoding/json"
 	"os"
 
+	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-lambda-go/lambdacontext"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
-	"github.com/line/line-bot-sdk-go/v7/linebot"
 	"github.com/rs/zerolog"
 
 	"github.com/david7482/aws-serverless-service/internal/adapter/line"
 	"github.com/david7482/aws-serverless-service/internal/adapter/postgres"
+	"github.com/david7482/aws-serverless-service/internal/app/service/outbound"
+	"github.com/david7482/aws-serverless-service/internal/app/service/workertask"
+	"github.com/david7482/aws-serverless-service/internal/domain"
+	"github.com/david7482/aws-serverless-service/internal/worker"
 )
 
 var rootLogger zerolog.Logger
-var pgRepo *postgres.PostgresRepository
-
-const channelID = 1
+var eventWorker *worker.Worker
 
 func main() {
 	const rfc3339Milli = "2006-01-02T15:04:05.000Z07:00"
@@ -34,72 +36,80 @@ func main() {
 		rootLogger.Error().Err(err).Str("databaseDSN", databaseDSN).Msg("fail to connect to database")
 		return
 	}
-	pgRepo = postgres.NewPostgresRepository(context.Background(), db)
+	// The worker doesn't touch channel credentials, so it has no key provider for them
+	pgRepo := postgres.NewPostgresRepository(context.Background(), db, nil)
+	lineSrv := line.NewLineService(context.Background())
+	eventWorker = worker.NewWorker(context.Background(), worker.WorkerParam{
+		SlideRepo: pgRepo,
+		TaskService: workertask.NewWorkerTaskService(context.Background(), workertask.WorkerTaskServiceParam{
+			TaskRepo: pgRepo,
+		}),
+		LineService: lineSrv,
+		// Only attempts are recorded here, which don't need channel credentials
+		OutboundService: outbound.NewOutboundService(context.Background(), outbound.OutboundServiceParam{
+			ChannelRepo:  pgRepo,
+			OutboundRepo: pgRepo,
+			LineService:  lineSrv,
+		}),
+	})
 
 	lambda.Start(handler)
 }
 
 type event struct {
-	DetailType string `json:"detail-type"`
-	Source     string `json:"source"`
-	Detail     struct {
-		ChannelAccessToken string        `json:"channelAccessToken"`
-		ExternalMemberID   string        `json:"externalMemberID"`
-		EventType          string        `json:"eventType"`
-		ReplyToken         string        `json:"replyToken"`
-		EventContent       linebot.Event `json:"eventContent"`
-	} `json:"detail"`
-}
-
-func isDownloadSlideMsg(e linebot.Event) bool {
-	switch message := e.Message.(type) {
-	case *linebot.TextMessage:
-		if message.Text == "Download Slide" {
-			return true
-		}
-	}
-	return false
+	// ID is assigned by EventBridge, which is kept when the event is retried
+	ID         string              `json:"id"`
+	DetailType string              `json:"detail-type"`
+	Source     string              `json:"source"`
+	Detail     domain.ChannelEvent `json:"detail"`
 }
 
-func handler(ctx context.Context, msg json.RawMessage) error {
+// handler handles an event delivered by EventBridge, or a batch of messages delivered by SQS
+func handler(ctx context.Context, msg json.RawMessage) (interface{}, error) {
 	var logger zerolog.Logger
 	lambdaCtx, ok := lambdacontext.FromContext(ctx)
 	if ok {
 		logger = rootLogger.With().Str("requestID", lambdaCtx.AwsRequestID).Logger()
 	}
 	logger.Info().RawJSON("msg", msg).Msg("raw message")
+	ctx = logger.WithContext(ctx)
+
+	// EventBridge events have no records
+	var sqsEvent events.SQSEvent
+	if err := json.Unmarshal(msg, &sqsEvent); err == nil && len(sqsEvent.Records) > 0 {
+		return handleSQSEvent(ctx, sqsEvent), nil
+	}
 
 	var e event
 	err := json.Unmarshal(msg, &e)
 	if err != nil {
 		logger.Error().Err(err).Msg("fail to unmarshal msg to event")
-		return err
-	}
-
-	if !isDownloadSlideMsg(e.Detail.EventContent) {
-		// do nothing if it's not DownloadSlide message
-		return nil
+		return nil, err
 	}
 
-	// Query necessary information from repository
-	url, err := pgRepo.GetEnabledSlideURL(ctx, channelID)
-	if err != nil {
-		zerolog.Ctx(ctx).Error().Err(err).Msg("fail to get enabled slide URL")
-		return err
-	}
+	return nil, eventWorker.HandleEvent(ctx, e.ID, e.Detail)
+}
 
-	// Reply the image message with slide URL
-	lineSrv := line.NewLineService(ctx)
-	err = lineSrv.SendMessage(ctx, line.SendMessageParams{
-		AccessToken: e.Detail.ChannelAccessToken,
-		ReplyToken:  e.Detail.ReplyToken,
-		To:          e.Detail.ExternalMemberID,
-		Messages:    []linebot.SendingMessage{linebot.NewImageMessage(url, url)},
-	})
-	if err != nil {
-		logger.Error().Err(err).Msg("fail to unmarshal msg to event")
-		return err
+// handleSQSEvent handles each message of the batch, and reports the failed ones, so only they are
+// delivered again
+func handleSQSEvent(ctx context.Context, sqsEvent events.SQSEvent) events.SQSEventResponse {
+	var response events.SQSEventResponse
+	for _, record := range sqsEvent.Records {
+		logger := zerolog.Ctx(ctx).With().Str("messageID", record.MessageId).Logger()
+
+		var detail domain.ChannelEvent
+		err := json.Unmarshal([]byte(record.Body), &detail)
+		if err != nil {
+			logger.Error().Err(err).Msg("fail to unmarshal sqs message to event")
+		} else {
+			// the message ID is kept when SQS delivers the message again
+			err = eventWorker.HandleEvent(logger.WithContext(ctx), record.MessageId, detail)
+		}
+		if err != nil {
+			response.BatchItemFailures = append(response.BatchItemFailures, events.SQSBatchItemFailure{
+				ItemIdentifier: record.MessageId,
+			})
+		}
 	}
-
-	return nil
+	return response
 }
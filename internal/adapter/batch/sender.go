@@ -0,0 +1,148 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+// SendFunc sends the events in one request, and returns the result of each event in the same order,
+// which is nil if the event is sent. Events failed with a ParameterError are not sent again, since
+// they are the cause of the failure.
+type SendFunc func(ctx context.Context, data []string) []domain.Error
+
+// Sender sends events in as few requests as the limits of the destination allow, and sends failed
+// events again with exponential backoff. The destination only supplies how to send a batch.
+type Sender struct {
+	name         string
+	maxEntries   int
+	maxSize      int
+	entrySize    func(data string) int
+	maxAttempts  int
+	retryBackoff time.Duration
+	send         SendFunc
+}
+
+type SenderParam struct {
+	// Name is the destination shown in logs and errors
+	Name string
+	// MaxEntries and MaxSize are the limits of a request
+	MaxEntries int
+	MaxSize    int
+	// EntrySize returns the size of an event counted against MaxSize, which is the length of the
+	// event if it's nil
+	EntrySize func(data string) int
+	// MaxAttempts is how many times failed events are sent before they are reported as failed
+	MaxAttempts int
+	// RetryBackoff is the delay before failed events are sent again, doubled on each attempt
+	RetryBackoff time.Duration
+	Send         SendFunc
+}
+
+func NewSender(_ context.Context, param SenderParam) *Sender {
+	entrySize := param.EntrySize
+	if entrySize == nil {
+		entrySize = func(data string) int { return len(data) }
+	}
+	return &Sender{
+		name:         param.Name,
+		maxEntries:   param.MaxEntries,
+		maxSize:      param.MaxSize,
+		entrySize:    entrySize,
+		maxAttempts:  param.MaxAttempts,
+		retryBackoff: param.RetryBackoff,
+		send:         param.Send,
+	}
+}
+
+// Send sends the events, and returns the result of each event in the same order, which is nil if the
+// event is sent. Events exceeding the size limit are never sent and fail with a ParameterError.
+func (s *Sender) Send(ctx context.Context, data []string) []domain.Error {
+	results := make([]domain.Error, len(data))
+
+	pending := make([]int, 0, len(data))
+	for i, d := range data {
+		if s.entrySize(d) > s.maxSize {
+			results[i] = domain.NewParameterError("", fmt.Errorf("event exceeds the size limit of %s", s.name))
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	backoff := s.retryBackoff
+	for attempt := 1; len(pending) > 0; attempt++ {
+		var failed []int
+		for _, batch := range s.batches(data, pending) {
+			failed = append(failed, s.sendBatch(ctx, data, batch, results)...)
+		}
+		if len(failed) == 0 || attempt >= s.maxAttempts {
+			break
+		}
+
+		zerolog.Ctx(ctx).Warn().
+			Str("destination", s.name).
+			Int("failed", len(failed)).
+			Int("attempt", attempt).
+			Msg("retry failed entries")
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return results
+		}
+		backoff *= 2
+		pending = failed
+	}
+	return results
+}
+
+// batches splits indexes of events into batches within the entry count and size limits
+func (s *Sender) batches(data []string, indexes []int) [][]int {
+	var batches [][]int
+	var batch []int
+	size := 0
+	for _, i := range indexes {
+		entrySize := s.entrySize(data[i])
+		if len(batch) == s.maxEntries || size+entrySize > s.maxSize {
+			batches = append(batches, batch)
+			batch, size = nil, 0
+		}
+		batch = append(batch, i)
+		size += entrySize
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// sendBatch sends the batch of events and records their results. Indexes of the failed events which
+// are worth retrying are returned.
+func (s *Sender) sendBatch(ctx context.Context, data []string, batch []int, results []domain.Error) []int {
+	entries := make([]string, 0, len(batch))
+	for _, i := range batch {
+		entries = append(entries, data[i])
+	}
+
+	sent := s.send(ctx, entries)
+
+	var failed []int
+	for j, i := range batch {
+		if j >= len(sent) {
+			results[i] = domain.NewExternalError("", nil, fmt.Errorf("%s returns no result for the entry", s.name))
+			failed = append(failed, i)
+			continue
+		}
+
+		results[i] = sent[j]
+		var invalid domain.ParameterError
+		if sent[j] != nil && !errors.As(sent[j], &invalid) {
+			failed = append(failed, i)
+		}
+	}
+	return failed
+}
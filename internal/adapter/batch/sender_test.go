@@ -0,0 +1,129 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+// fakeDestination records the batches sent to it, and fails events by their content
+type fakeDestination struct {
+	batches [][]string
+	// failures is how many times an event fails before it's sent
+	failures map[string]int
+	invalid  map[string]bool
+}
+
+func (d *fakeDestination) send(_ context.Context, data []string) []domain.Error {
+	d.batches = append(d.batches, data)
+
+	results := make([]domain.Error, len(data))
+	for i, event := range data {
+		switch {
+		case d.invalid[event]:
+			results[i] = domain.NewParameterError("", errors.New("invalid entry"))
+		case d.failures[event] > 0:
+			d.failures[event]--
+			results[i] = domain.NewExternalError("", nil, errors.New("internal failure"))
+		}
+	}
+	return results
+}
+
+func newTestSender(d *fakeDestination) *Sender {
+	return NewSender(context.Background(), SenderParam{
+		Name:         "test",
+		MaxEntries:   3,
+		MaxSize:      10,
+		MaxAttempts:  3,
+		RetryBackoff: time.Millisecond,
+		Send:         d.send,
+	})
+}
+
+func TestSender_Send(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        []string
+		failures    map[string]int
+		invalid     map[string]bool
+		wantBatches [][]string
+		wantFailed  []bool
+	}{
+		{
+			name:        "split by entry count",
+			data:        []string{"a", "b", "c", "d"},
+			wantBatches: [][]string{{"a", "b", "c"}, {"d"}},
+			wantFailed:  []bool{false, false, false, false},
+		},
+		{
+			name:        "split by size",
+			data:        []string{"aaaa", "bbbb", "cccc"},
+			wantBatches: [][]string{{"aaaa", "bbbb"}, {"cccc"}},
+			wantFailed:  []bool{false, false, false},
+		},
+		{
+			name:        "too large event is never sent",
+			data:        []string{"a", strings.Repeat("x", 11)},
+			wantBatches: [][]string{{"a"}},
+			wantFailed:  []bool{false, true},
+		},
+		{
+			name:        "failed event is sent again",
+			data:        []string{"a", "b"},
+			failures:    map[string]int{"b": 2},
+			wantBatches: [][]string{{"a", "b"}, {"b"}, {"b"}},
+			wantFailed:  []bool{false, false},
+		},
+		{
+			name:        "failed event runs out of attempts",
+			data:        []string{"a", "b"},
+			failures:    map[string]int{"b": 3},
+			wantBatches: [][]string{{"a", "b"}, {"b"}, {"b"}},
+			wantFailed:  []bool{false, true},
+		},
+		{
+			name:        "invalid event is not sent again",
+			data:        []string{"a", "b"},
+			invalid:     map[string]bool{"b": true},
+			wantBatches: [][]string{{"a", "b"}},
+			wantFailed:  []bool{false, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &fakeDestination{failures: tt.failures, invalid: tt.invalid}
+			results := newTestSender(d).Send(context.Background(), tt.data)
+
+			if got, want := strings.Join(joinBatches(d.batches), "|"), strings.Join(joinBatches(tt.wantBatches), "|"); got != want {
+				t.Errorf("batches = %s, want %s", got, want)
+			}
+			for i, failed := range tt.wantFailed {
+				if (results[i] != nil) != failed {
+					t.Errorf("result of %q = %v, want failed %v", tt.data[i], results[i], failed)
+				}
+			}
+		})
+	}
+}
+
+func TestSender_Send_TooLargeIsParameterError(t *testing.T) {
+	results := newTestSender(&fakeDestination{}).Send(context.Background(), []string{strings.Repeat("x", 11)})
+
+	var invalid domain.ParameterError
+	if !errors.As(results[0], &invalid) {
+		t.Fatalf("Send() error = %v, want a ParameterError", results[0])
+	}
+}
+
+func joinBatches(batches [][]string) []string {
+	joined := make([]string, 0, len(batches))
+	for _, batch := range batches {
+		joined = append(joined, strings.Join(batch, ","))
+	}
+	return joined
+}
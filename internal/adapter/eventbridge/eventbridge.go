@@ -2,46 +2,109 @@ package eventbridge
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/eventbridge"
 	"github.com/rs/zerolog"
 
+	"github.com/david7482/aws-serverless-service/internal/adapter/batch"
 	"github.com/david7482/aws-serverless-service/internal/domain"
 )
 
 const (
 	eventBridgeSource     = "chatbot"
 	eventBridgeDetailType = "line-message"
+
+	// maxBatchEntries and maxBatchSize are the limits of a PutEvents request
+	// Reference: https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-putevent-size.html
+	maxBatchEntries = 10
+	maxBatchSize    = 256 * 1024
+	// maxPutAttempts is how many times failed entries are sent before they are reported as failed
+	maxPutAttempts = 3
+	// retryBackoff is the delay before failed entries are sent again, doubled on each attempt
+	retryBackoff = 100 * time.Millisecond
 )
 
 type EventBridge struct {
 	evb     *eventbridge.EventBridge
 	busName string
+	sender  *batch.Sender
 }
 
-func NewEventBridge(_ context.Context, s *session.Session, busName string) *EventBridge {
-	evb := eventbridge.New(s)
-	return &EventBridge{
-		evb:     evb,
+func NewEventBridge(ctx context.Context, s *session.Session, busName string) *EventBridge {
+	e := &EventBridge{
+		evb:     eventbridge.New(s),
 		busName: busName,
 	}
+	e.sender = batch.NewSender(ctx, batch.SenderParam{
+		Name:         "eventbridge",
+		MaxEntries:   maxBatchEntries,
+		MaxSize:      maxBatchSize,
+		EntrySize:    entrySize,
+		MaxAttempts:  maxPutAttempts,
+		RetryBackoff: retryBackoff,
+		Send:         e.putBatch,
+	})
+	return e
 }
 
 func (e *EventBridge) PutEvent(ctx context.Context, data string) domain.Error {
-	entries := []*eventbridge.PutEventsRequestEntry{{
-		Source:       aws.String(eventBridgeSource),
-		DetailType:   aws.String(eventBridgeDetailType),
-		Detail:       aws.String(data),
-		EventBusName: aws.String(e.busName),
-	}}
-
-	_, err := e.evb.PutEventsWithContext(ctx, &eventbridge.PutEventsInput{Entries: entries})
+	return e.PutEvents(ctx, []string{data})[0]
+}
+
+// PutEvents publishes the events in as few requests as the limits of EventBridge allow. Entries
+// failed by EventBridge are sent again, and the returned errors tell the result of each event in the
+// same order, which is nil if the event is published.
+func (e *EventBridge) PutEvents(ctx context.Context, data []string) []domain.Error {
+	return e.sender.Send(ctx, data)
+}
+
+// putBatch publishes the batch of events in one request
+func (e *EventBridge) putBatch(ctx context.Context, data []string) []domain.Error {
+	entries := make([]*eventbridge.PutEventsRequestEntry, 0, len(data))
+	for _, d := range data {
+		entries = append(entries, &eventbridge.PutEventsRequestEntry{
+			Source:       aws.String(eventBridgeSource),
+			DetailType:   aws.String(eventBridgeDetailType),
+			Detail:       aws.String(d),
+			EventBusName: aws.String(e.busName),
+		})
+	}
+
+	results := make([]domain.Error, len(data))
+	output, err := e.evb.PutEventsWithContext(ctx, &eventbridge.PutEventsInput{Entries: entries})
 	if err != nil {
-		zerolog.Ctx(ctx).Error().Err(err).Msg("fail to put event to eventbridge")
-		return domain.NewExternalError("", nil, err)
+		zerolog.Ctx(ctx).Error().Err(err).Msg("fail to put events to eventbridge")
+		for i := range results {
+			results[i] = domain.NewExternalError("", nil, err)
+		}
+		return results
+	}
+
+	// Entries of the output are in the same order as the request
+	for i := range results {
+		if i >= len(output.Entries) {
+			results[i] = domain.NewExternalError("", nil, errors.New("eventbridge returns no result for the entry"))
+			continue
+		}
+		entry := output.Entries[i]
+		if aws.StringValue(entry.ErrorCode) != "" {
+			results[i] = domain.NewExternalError("", nil, fmt.Errorf("%s: %s", aws.StringValue(entry.ErrorCode), aws.StringValue(entry.ErrorMessage)))
+		}
 	}
+	if aws.Int64Value(output.FailedEntryCount) > 0 {
+		zerolog.Ctx(ctx).Error().
+			Int64("failedEntryCount", aws.Int64Value(output.FailedEntryCount)).
+			Msg("eventbridge fails some entries")
+	}
+	return results
+}
 
-	return nil
+// entrySize calculates the size of the event counted by EventBridge
+func entrySize(data string) int {
+	return len(eventBridgeSource) + len(eventBridgeDetailType) + len(data)
 }
@@ -0,0 +1,51 @@
+package kms
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/rs/zerolog"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+// KeyProvider generates and decrypts data keys with an AWS KMS key, so the master key never
+// leaves KMS.
+type KeyProvider struct {
+	kms   *kms.KMS
+	keyID string
+}
+
+func NewKeyProvider(_ context.Context, s *session.Session, keyID string) *KeyProvider {
+	return &KeyProvider{
+		kms:   kms.New(s),
+		keyID: keyID,
+	}
+}
+
+func (p *KeyProvider) GenerateDataKey(ctx context.Context) ([]byte, []byte, domain.Error) {
+	output, err := p.kms.GenerateDataKeyWithContext(ctx, &kms.GenerateDataKeyInput{
+		KeyId:   aws.String(p.keyID),
+		KeySpec: aws.String(kms.DataKeySpecAes256),
+	})
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Msg("fail to generate data key from kms")
+		return nil, nil, domain.NewExternalError("", nil, err)
+	}
+	return output.Plaintext, output.CiphertextBlob, nil
+}
+
+func (p *KeyProvider) DecryptDataKey(ctx context.Context, encrypted []byte) ([]byte, domain.Error) {
+	// The key ID is embedded in the blob, so data keys generated by a previous key could still be
+	// decrypted after switching to a new key.
+	output, err := p.kms.DecryptWithContext(ctx, &kms.DecryptInput{
+		CiphertextBlob: encrypted,
+	})
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Msg("fail to decrypt data key from kms")
+		return nil, domain.NewExternalError("", nil, err)
+	}
+	return output.Plaintext, nil
+}
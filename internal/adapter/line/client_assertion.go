@@ -0,0 +1,126 @@
+package line
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	// clientAssertionLifetime is how long the JWT itself is valid, which is at most 30 minutes
+	clientAssertionLifetime = 30 * time.Minute
+	// accessTokenV21Lifetime is the lifetime of issued v2.1 access tokens, which is at most 30 days
+	accessTokenV21Lifetime = 30 * 24 * time.Hour
+)
+
+// newClientAssertion builds the JWT used to issue v2.1 channel access tokens.
+// Reference: https://developers.line.biz/en/docs/messaging-api/generate-json-web-token/
+func newClientAssertion(externalChannelID, keyID, privateKey string) (string, error) {
+	key, err := parseAssertionPrivateKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+		"kid": keyID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"iss":       externalChannelID,
+		"sub":       externalChannelID,
+		"aud":       "https://api.line.me/",
+		"exp":       time.Now().Add(clientAssertionLifetime).Unix(),
+		"token_exp": int64(accessTokenV21Lifetime / time.Second),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// parseAssertionPrivateKey parses an RSA private key in JWK, PKCS#8 PEM or PKCS#1 PEM format
+func parseAssertionPrivateKey(privateKey string) (*rsa.PrivateKey, error) {
+	privateKey = strings.TrimSpace(privateKey)
+	if strings.HasPrefix(privateKey, "{") {
+		return parseJWKPrivateKey(privateKey)
+	}
+
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return nil, errors.New("assertion private key is neither JWK nor PEM")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("assertion private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+func parseJWKPrivateKey(privateKey string) (*rsa.PrivateKey, error) {
+	var jwk struct {
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		D   string `json:"d"`
+		P   string `json:"p"`
+		Q   string `json:"q"`
+	}
+	if err := json.Unmarshal([]byte(privateKey), &jwk); err != nil {
+		return nil, err
+	}
+	if jwk.Kty != "RSA" {
+		return nil, errors.New("assertion private key is not an RSA key")
+	}
+
+	values := make([]*big.Int, 0, 5)
+	for _, field := range []string{jwk.N, jwk.E, jwk.D, jwk.P, jwk.Q} {
+		decoded, err := base64.RawURLEncoding.DecodeString(field)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, new(big.Int).SetBytes(decoded))
+	}
+
+	key := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{
+			N: values[0],
+			E: int(values[1].Int64()),
+		},
+		D:      values[2],
+		Primes: []*big.Int{values[3], values[4]},
+	}
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+	key.Precompute()
+	return key, nil
+}
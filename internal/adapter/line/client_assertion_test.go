@@ -0,0 +1,156 @@
+package line
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func encodeJWK(key *rsa.PrivateKey, kty string) string {
+	encode := func(i *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(i.Bytes())
+	}
+	jwk, _ := json.Marshal(map[string]string{
+		"kty": kty,
+		"alg": "RS256",
+		"n":   encode(key.N),
+		"e":   encode(big.NewInt(int64(key.E))),
+		"d":   encode(key.D),
+		"p":   encode(key.Primes[0]),
+		"q":   encode(key.Primes[1]),
+	})
+	return string(jwk)
+}
+
+// decodeJWTPart decodes the header or payload of a JWT
+func decodeJWTPart(t *testing.T, part string) map[string]interface{} {
+	t.Helper()
+
+	decoded, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		t.Fatalf("part is not base64url: %v", err)
+	}
+	var values map[string]interface{}
+	if err = json.Unmarshal(decoded, &values); err != nil {
+		t.Fatalf("part is not JSON: %v", err)
+	}
+	return values
+}
+
+func TestNewClientAssertion(t *testing.T) {
+	key := newTestRSAKey(t)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		privateKey string
+	}{
+		{name: "JWK", privateKey: encodeJWK(key, "RSA")},
+		{name: "PKCS#1 PEM", privateKey: string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))},
+		{name: "PKCS#8 PEM", privateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}))},
+		{name: "JWK with surrounding spaces", privateKey: "\n  " + encodeJWK(key, "RSA") + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := time.Now()
+			assertion, err := newClientAssertion("1234567890", "key-id", tt.privateKey)
+			if err != nil {
+				t.Fatalf("newClientAssertion() error = %v", err)
+			}
+
+			parts := strings.Split(assertion, ".")
+			if len(parts) != 3 {
+				t.Fatalf("assertion has %d parts, want 3", len(parts))
+			}
+
+			header := decodeJWTPart(t, parts[0])
+			wantHeader := map[string]interface{}{"alg": "RS256", "typ": "JWT", "kid": "key-id"}
+			for name, want := range wantHeader {
+				if header[name] != want {
+					t.Errorf("header %s = %v, want %v", name, header[name], want)
+				}
+			}
+
+			claims := decodeJWTPart(t, parts[1])
+			wantClaims := map[string]interface{}{
+				"iss":       "1234567890",
+				"sub":       "1234567890",
+				"aud":       "https://api.line.me/",
+				"token_exp": float64(accessTokenV21Lifetime / time.Second),
+			}
+			for name, want := range wantClaims {
+				if claims[name] != want {
+					t.Errorf("claim %s = %v, want %v", name, claims[name], want)
+				}
+			}
+			exp, _ := claims["exp"].(float64)
+			if wantExp := before.Add(clientAssertionLifetime).Unix(); int64(exp) < wantExp || int64(exp) > wantExp+5 {
+				t.Errorf("claim exp = %v, want about %v", int64(exp), wantExp)
+			}
+
+			signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+			if err != nil {
+				t.Fatalf("signature is not base64url: %v", err)
+			}
+			digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+			if err = rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+				t.Errorf("signature is not verified by the public key: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewClientAssertion_InvalidKey(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecPKCS8, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		privateKey string
+	}{
+		{name: "empty", privateKey: ""},
+		{name: "neither JWK nor PEM", privateKey: "not a key"},
+		{name: "malformed JWK", privateKey: "{"},
+		{name: "JWK of another key type", privateKey: encodeJWK(newTestRSAKey(t), "EC")},
+		{name: "JWK with invalid base64", privateKey: `{"kty":"RSA","n":"!","e":"AQAB","d":"!","p":"!","q":"!"}`},
+		{name: "PEM of an EC key", privateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: ecPKCS8}))},
+		{name: "PEM of garbage", privateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("garbage")}))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newClientAssertion("1234567890", "key-id", tt.privateKey); err == nil {
+				t.Fatalf("newClientAssertion() error = nil, want an error")
+			}
+		})
+	}
+}
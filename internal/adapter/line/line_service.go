@@ -7,6 +7,8 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
 	"net/url"
 	"strings"
 	"time"
@@ -73,6 +75,60 @@ func (s *LineService) IssueAccessToken(ctx context.Context, ExternalChannelID st
 	return ret.AccessToken, time.Now().Add(time.Duration(ret.ExpiresIn) * time.Second), nil
 }
 
+// IssueAccessTokenV21 issues a channel access token v2.1 with a JWT signed by the assertion key.
+// The token would be valid for 30 days, which is the longest lifetime LINE allows.
+// Reference: https://developers.line.biz/en/reference/messaging-api/#issue-channel-access-token-v2-1
+func (s *LineService) IssueAccessTokenV21(ctx context.Context, externalChannelID, assertionKeyID, assertionPrivateKey string) (*domain.AccessToken, domain.Error) {
+	assertion, err := newClientAssertion(externalChannelID, assertionKeyID, assertionPrivateKey)
+	if err != nil {
+		return nil, domain.NewParameterError("invalid assertion key", err)
+	}
+
+	bot, _ := linebot.New("not-used", "not-used")
+	ret, err := bot.IssueAccessTokenV2(assertion).WithContext(ctx).Do()
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Msg("failed to issue access token v2.1")
+		return nil, newExternalErrorFromLine(err)
+	}
+	return &domain.AccessToken{
+		Token:     ret.AccessToken,
+		ExpiredAt: time.Now().Add(time.Duration(ret.ExpiresIn) * time.Second),
+		KeyID:     ret.KeyID,
+	}, nil
+}
+
+// RevokeAccessToken revokes a channel access token v2
+func (s *LineService) RevokeAccessToken(ctx context.Context, accessToken string) domain.Error {
+	bot, _ := linebot.New("not-used", "not-used")
+	_, err := bot.RevokeAccessToken(accessToken).WithContext(ctx).Do()
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Msg("failed to revoke access token v2")
+		return newExternalErrorFromLine(err)
+	}
+	return nil
+}
+
+// RevokeAccessTokenV21 revokes a channel access token v2.1
+func (s *LineService) RevokeAccessTokenV21(ctx context.Context, externalChannelID, externalChannelSecret, accessToken string) domain.Error {
+	bot, _ := linebot.New("not-used", "not-used")
+	_, err := bot.RevokeAccessTokenV2(externalChannelID, externalChannelSecret, accessToken).WithContext(ctx).Do()
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Msg("failed to revoke access token v2.1")
+		return newExternalErrorFromLine(err)
+	}
+	return nil
+}
+
+// newExternalErrorFromLine keeps the status code of LINE API errors
+func newExternalErrorFromLine(err error) domain.Error {
+	var apiErr *linebot.APIError
+	if errors.As(err, &apiErr) {
+		code := apiErr.Code
+		return domain.NewExternalError("", &code, err)
+	}
+	return domain.NewExternalError("", nil, err)
+}
+
 func (s *LineService) ValidateSignature(_ context.Context, externalChannelSecret, signature string, payload []byte) bool {
 	decoded, err := base64.StdEncoding.DecodeString(signature)
 	if err != nil {
@@ -99,11 +155,20 @@ func (s *LineService) ParseLineEvents(_ context.Context, payload []byte) ([]doma
 	for _, lineEvent := range request.LineEvents {
 		content, _ := lineEvent.MarshalJSON()
 		event := domain.LineEvent{
-			EventType:        domain.LineEventType(lineEvent.Type),
-			ExternalMemberID: lineEvent.Source.UserID,
-			ReplyToken:       lineEvent.ReplyToken,
-			EventContent:     content,
+			EventType:      domain.LineEventType(lineEvent.Type),
+			ReplyToken:     lineEvent.ReplyToken,
+			EventContent:   content,
+			Timestamp:      lineEvent.Timestamp,
+			WebhookEventID: lineEvent.WebhookEventID,
+			IsRedelivery:   lineEvent.DeliveryContext.IsRedelivery,
 		}
+		if source := lineEvent.Source; source != nil {
+			event.SourceType = domain.LineSourceType(source.Type)
+			event.ExternalMemberID = source.UserID
+			event.ExternalGroupID = source.GroupID
+			event.ExternalRoomID = source.RoomID
+		}
+		setLineEventPayload(&event, lineEvent)
 
 		events = append(events, event)
 	}
@@ -111,6 +176,146 @@ func (s *LineService) ParseLineEvents(_ context.Context, payload []byte) ([]doma
 	return events, nil
 }
 
+// setLineEventPayload decodes the payload of the event by its type
+func setLineEventPayload(event *domain.LineEvent, lineEvent linebot.Event) {
+	switch lineEvent.Type {
+	case linebot.EventTypeMessage:
+		event.Message = newLineMessage(lineEvent.Message)
+	case linebot.EventTypePostback:
+		if lineEvent.Postback != nil {
+			event.Postback = &domain.LinePostback{Data: lineEvent.Postback.Data}
+			if p := lineEvent.Postback.Params; p != nil {
+				params := map[string]string{
+					"date":               p.Date,
+					"time":               p.Time,
+					"datetime":           p.Datetime,
+					"newRichMenuAliasId": p.NewRichMenuAliasID,
+					"status":             p.Status,
+				}
+				for k, v := range params {
+					if v == "" {
+						delete(params, k)
+					}
+				}
+				event.Postback.Params = params
+			}
+		}
+	case linebot.EventTypeMemberJoined:
+		if lineEvent.Joined != nil {
+			event.Members = newLineMembers(lineEvent.Joined.Members)
+		}
+	case linebot.EventTypeMemberLeft:
+		if lineEvent.Left != nil {
+			event.Members = newLineMembers(lineEvent.Left.Members)
+		}
+	case linebot.EventTypeUnsend:
+		if lineEvent.Unsend != nil {
+			event.Unsend = &domain.LineUnsend{MessageID: lineEvent.Unsend.MessageID}
+		}
+	case linebot.EventTypeBeacon:
+		if lineEvent.Beacon != nil {
+			event.Beacon = &domain.LineBeacon{
+				HWID:          lineEvent.Beacon.Hwid,
+				Type:          string(lineEvent.Beacon.Type),
+				DeviceMessage: lineEvent.Beacon.DeviceMessage,
+			}
+		}
+	case linebot.EventTypeAccountLink:
+		if lineEvent.AccountLink != nil {
+			event.AccountLink = &domain.LineAccountLink{
+				Result: string(lineEvent.AccountLink.Result),
+				Nonce:  lineEvent.AccountLink.Nonce,
+			}
+		}
+	case linebot.EventTypeVideoPlayComplete:
+		if lineEvent.VideoPlayComplete != nil {
+			event.VideoPlayComplete = &domain.LineVideoPlayComplete{TrackingID: lineEvent.VideoPlayComplete.TrackingID}
+		}
+	}
+}
+
+func newLineMessage(message linebot.Message) *domain.LineMessage {
+	switch m := message.(type) {
+	case *linebot.TextMessage:
+		return &domain.LineMessage{ID: m.ID, Type: string(linebot.MessageTypeText), Text: m.Text}
+	case *linebot.ImageMessage:
+		return &domain.LineMessage{ID: m.ID, Type: string(linebot.MessageTypeImage)}
+	case *linebot.VideoMessage:
+		return &domain.LineMessage{ID: m.ID, Type: string(linebot.MessageTypeVideo)}
+	case *linebot.AudioMessage:
+		return &domain.LineMessage{ID: m.ID, Type: string(linebot.MessageTypeAudio)}
+	case *linebot.FileMessage:
+		return &domain.LineMessage{ID: m.ID, Type: string(linebot.MessageTypeFile)}
+	case *linebot.LocationMessage:
+		return &domain.LineMessage{ID: m.ID, Type: string(linebot.MessageTypeLocation)}
+	case *linebot.StickerMessage:
+		return &domain.LineMessage{ID: m.ID, Type: string(linebot.MessageTypeSticker)}
+	default:
+		return nil
+	}
+}
+
+func newLineMembers(sources []linebot.EventSource) *domain.LineMembers {
+	members := &domain.LineMembers{ExternalMemberIDs: make([]string, 0, len(sources))}
+	for _, source := range sources {
+		members.ExternalMemberIDs = append(members.ExternalMemberIDs, source.UserID)
+	}
+	return members
+}
+
+// SetWebhookEndpoint sets the webhook endpoint URL of the channel
+func (s *LineService) SetWebhookEndpoint(ctx context.Context, accessToken, endpoint string) domain.Error {
+	bot, _ := linebot.New("not-used", accessToken)
+	_, err := bot.SetWebhookEndpointURL(endpoint).WithContext(ctx).Do()
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Str("endpoint", endpoint).Msg("failed to set webhook endpoint")
+		return newExternalErrorFromLine(err)
+	}
+	return nil
+}
+
+type testWebhookResponse struct {
+	Success    bool      `json:"success"`
+	Timestamp  time.Time `json:"timestamp"`
+	StatusCode int       `json:"statusCode"`
+	Reason     string    `json:"reason"`
+	Detail     string    `json:"detail"`
+}
+
+// TestWebhookEndpoint asks LINE to send a test webhook event to the endpoint. The SDK sends the test
+// call with GET, which LINE doesn't accept, so it is called directly here.
+// Reference: https://developers.line.biz/en/reference/messaging-api/#test-webhook-endpoint
+func (s *LineService) TestWebhookEndpoint(ctx context.Context, accessToken, endpoint string) (*domain.LineWebhookTestResult, domain.Error) {
+	uri := "https://api.line.me/v2/bot/channel/webhook/test"
+
+	var ret testWebhookResponse
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetAuthToken(accessToken).
+		SetBody(map[string]string{"endpoint": endpoint}).
+		SetResult(&ret).
+		Post(uri)
+
+	if err != nil {
+		return nil, domain.NewExternalError("", nil, err)
+	}
+	if !resp.IsSuccess() {
+		code := resp.StatusCode()
+		s.logger(ctx).Error().
+			Int("statusCode", code).
+			Str("endpoint", endpoint).
+			Msg("failed to test webhook endpoint")
+		return nil, domain.NewExternalError("", &code, errors.New("failed to test webhook endpoint"))
+	}
+	return &domain.LineWebhookTestResult{
+		Success:    ret.Success,
+		StatusCode: ret.StatusCode,
+		Reason:     ret.Reason,
+		Detail:     ret.Detail,
+		Timestamp:  ret.Timestamp,
+	}, nil
+}
+
 func (s *LineService) GetChannelInfo(ctx context.Context, accessToken string) (*linebot.BotInfoResponse, domain.Error) {
 	bot, err := linebot.New("no use", accessToken)
 	if err != nil {
@@ -124,6 +329,79 @@ func (s *LineService) GetChannelInfo(ctx context.Context, accessToken string) (*
 	return info, nil
 }
 
+// GetProfile returns the profile of the user, who must be a friend of the channel
+func (s *LineService) GetProfile(ctx context.Context, accessToken, externalMemberID string) (*domain.LineProfile, domain.Error) {
+	bot, err := linebot.New("not-used", accessToken)
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	profile, err := bot.GetProfile(externalMemberID).WithContext(ctx).Do()
+	if err != nil {
+		return nil, newExternalErrorFromLine(err)
+	}
+	return &domain.LineProfile{
+		DisplayName:   profile.DisplayName,
+		PictureURL:    profile.PictureURL,
+		StatusMessage: profile.StatusMessage,
+		Language:      profile.Language,
+	}, nil
+}
+
+// GetGroupMemberProfile returns the profile of the user in the group or the room the channel is in.
+// The user needn't be a friend of the channel, but the profile has no status message and language.
+func (s *LineService) GetGroupMemberProfile(ctx context.Context, accessToken string, sourceType domain.LineSourceType, externalGroupID, externalMemberID string) (*domain.LineProfile, domain.Error) {
+	bot, err := linebot.New("not-used", accessToken)
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	var profile *linebot.UserProfileResponse
+	if sourceType == domain.LineSourceTypeRoom {
+		profile, err = bot.GetRoomMemberProfile(externalGroupID, externalMemberID).WithContext(ctx).Do()
+	} else {
+		profile, err = bot.GetGroupMemberProfile(externalGroupID, externalMemberID).WithContext(ctx).Do()
+	}
+	if err != nil {
+		return nil, newExternalErrorFromLine(err)
+	}
+	return &domain.LineProfile{
+		DisplayName: profile.DisplayName,
+		PictureURL:  profile.PictureURL,
+	}, nil
+}
+
+// GetGroupSummary returns the summary of the group or the room the channel is in. Only the member
+// count is returned for rooms, which have no summary at LINE.
+func (s *LineService) GetGroupSummary(ctx context.Context, accessToken string, sourceType domain.LineSourceType, externalGroupID string) (*domain.LineGroupSummary, domain.Error) {
+	bot, err := linebot.New("not-used", accessToken)
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	if sourceType == domain.LineSourceTypeRoom {
+		count, err := bot.GetRoomMemberCount(externalGroupID).WithContext(ctx).Do()
+		if err != nil {
+			return nil, newExternalErrorFromLine(err)
+		}
+		return &domain.LineGroupSummary{MemberCount: count.Count}, nil
+	}
+
+	summary, err := bot.GetGroupSummary(externalGroupID).WithContext(ctx).Do()
+	if err != nil {
+		return nil, newExternalErrorFromLine(err)
+	}
+	count, err := bot.GetGroupMemberCount(externalGroupID).WithContext(ctx).Do()
+	if err != nil {
+		return nil, newExternalErrorFromLine(err)
+	}
+	return &domain.LineGroupSummary{
+		Name:        summary.GroupName,
+		PictureURL:  summary.PictureURL,
+		MemberCount: count.Count,
+	}, nil
+}
+
 type SendMessageParams struct {
 	AccessToken string
 	Messages    []linebot.SendingMessage
@@ -132,18 +410,50 @@ type SendMessageParams struct {
 	RetryKey    string
 }
 
+// responseRecorder records the request ID and the status of the last response from LINE, which the
+// SDK doesn't return on errors
+type responseRecorder struct {
+	transport  http.RoundTripper
+	requestID  string
+	httpStatus int
+}
+
+func (r *responseRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.requestID, r.httpStatus = "", 0
+	res, err := r.transport.RoundTrip(req)
+	if err == nil {
+		r.requestID, r.httpStatus = res.Header.Get("X-Line-Request-Id"), res.StatusCode
+	}
+	return res, err
+}
+
+func (r *responseRecorder) attempt(mode domain.LineSendMode, err error) domain.LineSendAttempt {
+	return domain.LineSendAttempt{
+		Mode:       mode,
+		RequestID:  r.requestID,
+		HTTPStatus: r.httpStatus,
+		Err:        err,
+	}
+}
+
 // SendMessage would take care of PushMessage and ReplyMessage internally. It would also fall back
-// to PushMessage if ReplyMessage fail.
-func (s *LineService) SendMessage(ctx context.Context, params SendMessageParams) (err error) {
-	bot, _ := linebot.New("not-used", params.AccessToken)
+// to PushMessage if ReplyMessage fail. Every call to LINE is returned as an attempt, and the error is
+// the one of the last attempt.
+func (s *LineService) SendMessage(ctx context.Context, params SendMessageParams) (attempts []domain.LineSendAttempt, err error) {
+	recorder := &responseRecorder{transport: http.DefaultTransport}
+	bot, err := linebot.New("not-used", params.AccessToken, linebot.WithHTTPClient(&http.Client{Transport: recorder}))
+	if err != nil {
+		return nil, err
+	}
 
 	// If we have reply token, we would try ReplyMessage() first.
 	if params.ReplyToken != "" {
 		_, err = bot.ReplyMessage(params.ReplyToken, params.Messages...).
 			WithContext(ctx).
 			Do()
+		attempts = append(attempts, recorder.attempt(domain.LineSendModeReply, err))
 		if err == nil {
-			return nil
+			return attempts, nil
 		}
 	}
 
@@ -153,10 +463,53 @@ func (s *LineService) SendMessage(ctx context.Context, params SendMessageParams)
 			WithContext(ctx).
 			WithRetryKey(params.RetryKey).
 			Do()
+		attempts = append(attempts, recorder.attempt(domain.LineSendModePush, err))
 		if err == nil {
-			return nil
+			return attempts, nil
 		}
 	}
 
-	return err
+	return attempts, err
+}
+
+type pushMessageErrorResponse struct {
+	Message string `json:"message"`
+}
+
+// PushRawMessages pushes messages in the JSON array to the recipient, which is used to resend
+// messages recorded before. The retry key keeps pushes of the same messages from being sent twice; a
+// push whose retry key has been accepted before is taken as sent.
+// Reference: https://developers.line.biz/en/reference/messaging-api/#send-push-message
+func (s *LineService) PushRawMessages(ctx context.Context, accessToken, to, retryKey string, messages json.RawMessage) domain.LineSendAttempt {
+	uri := "https://api.line.me/v2/bot/message/push"
+	attempt := domain.LineSendAttempt{Mode: domain.LineSendModePush}
+
+	var ret pushMessageErrorResponse
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetAuthToken(accessToken).
+		SetHeader("X-Line-Retry-Key", retryKey).
+		SetBody(map[string]interface{}{
+			"to":       to,
+			"messages": messages,
+		}).
+		SetError(&ret).
+		Post(uri)
+	if err != nil {
+		attempt.Err = domain.NewExternalError("", nil, err)
+		return attempt
+	}
+
+	attempt.RequestID = resp.Header().Get("X-Line-Request-Id")
+	attempt.HTTPStatus = resp.StatusCode()
+	if acceptedRequestID := resp.Header().Get("X-Line-Accepted-Request-Id"); resp.StatusCode() == http.StatusConflict && acceptedRequestID != "" {
+		// the messages have been sent by the accepted request
+		attempt.RequestID = acceptedRequestID
+		return attempt
+	}
+	if !resp.IsSuccess() {
+		code := resp.StatusCode()
+		attempt.Err = domain.NewExternalError("", &code, fmt.Errorf("failed to push messages: %s", ret.Message))
+	}
+	return attempt
 }
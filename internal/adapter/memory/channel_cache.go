@@ -0,0 +1,144 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+// ChannelRepository is the repository the cache loads channels from
+type ChannelRepository interface {
+	GetChannelByExternalID(ctx context.Context, externalChannelID string) (*domain.Channel, domain.Error)
+	GetChannelByID(ctx context.Context, organizationID, channelID int) (*domain.Channel, domain.Error)
+	GetChannelsByIDs(ctx context.Context, channelIDs []int) ([]domain.Channel, domain.Error)
+}
+
+type channelCacheEntry struct {
+	// channel is nil if the channel is not found
+	channel  *domain.Channel
+	expireAt time.Time
+}
+
+// ChannelCache caches channels looked up by external ID in front of the repository. Unknown external
+// IDs are cached as well, so webhooks of removed channels don't reach the repository either. Every
+// instance has its own cache, which is invalidated on changes of channels made by any instance, see
+// postgres.ChannelListener. The TTL only bounds how stale an entry gets if a change is missed.
+type ChannelCache struct {
+	repo        ChannelRepository
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	mu      sync.Mutex
+	entries map[string]channelCacheEntry
+	// version increases on every invalidation, so a load started before the invalidation doesn't
+	// put the stale channel back into the cache
+	version uint64
+}
+
+type ChannelCacheParam struct {
+	Repo ChannelRepository
+	// TTL is how long a found channel is cached
+	TTL time.Duration
+	// NegativeTTL is how long an unknown external ID is cached
+	NegativeTTL time.Duration
+}
+
+func NewChannelCache(_ context.Context, param ChannelCacheParam) *ChannelCache {
+	return &ChannelCache{
+		repo:        param.Repo,
+		ttl:         param.TTL,
+		negativeTTL: param.NegativeTTL,
+		entries:     map[string]channelCacheEntry{},
+	}
+}
+
+// GetChannelByExternalID returns the cached channel, or loads it from the repository if it is not
+// cached or has expired. Errors other than not found are not cached.
+func (c *ChannelCache) GetChannelByExternalID(ctx context.Context, externalChannelID string) (*domain.Channel, domain.Error) {
+	entry, version, ok := c.get(externalChannelID)
+	if ok {
+		if entry.channel == nil {
+			return nil, domain.NewResourceNotFoundError("channel is not found", nil)
+		}
+		channel := *entry.channel
+		return &channel, nil
+	}
+
+	channel, err := c.repo.GetChannelByExternalID(ctx, externalChannelID)
+	if err != nil {
+		var notFound domain.ResourceNotFoundError
+		if errors.As(err, &notFound) {
+			c.set(externalChannelID, nil, c.negativeTTL, version)
+		}
+		return nil, err
+	}
+
+	cached := *channel
+	c.set(externalChannelID, &cached, c.ttl, version)
+	return channel, nil
+}
+
+// GetChannelByID is not cached since it is off the webhook path
+func (c *ChannelCache) GetChannelByID(ctx context.Context, organizationID, channelID int) (*domain.Channel, domain.Error) {
+	return c.repo.GetChannelByID(ctx, organizationID, channelID)
+}
+
+// GetChannelsByIDs is not cached since it is off the webhook path
+func (c *ChannelCache) GetChannelsByIDs(ctx context.Context, channelIDs []int) ([]domain.Channel, domain.Error) {
+	return c.repo.GetChannelsByIDs(ctx, channelIDs)
+}
+
+// InvalidateChannel drops the cached channel, so the next lookup loads it from the repository
+func (c *ChannelCache) InvalidateChannel(_ context.Context, externalChannelID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, externalChannelID)
+	c.version++
+}
+
+// InvalidateAllChannels drops every cached channel, which is used when changes could have been missed
+func (c *ChannelCache) InvalidateAllChannels(_ context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = map[string]channelCacheEntry{}
+	c.version++
+}
+
+// get returns the unexpired entry and the current version of the cache
+func (c *ChannelCache) get(externalChannelID string) (channelCacheEntry, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[externalChannelID]
+	if !ok {
+		return channelCacheEntry{}, c.version, false
+	}
+	if !time.Now().Before(entry.expireAt) {
+		delete(c.entries, externalChannelID)
+		return channelCacheEntry{}, c.version, false
+	}
+	return entry, c.version, true
+}
+
+// set caches the entry unless the cache has been invalidated since the given version
+func (c *ChannelCache) set(externalChannelID string, channel *domain.Channel, ttl time.Duration, version uint64) {
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.version != version {
+		return
+	}
+	c.entries[externalChannelID] = channelCacheEntry{
+		channel:  channel,
+		expireAt: time.Now().Add(ttl),
+	}
+}
@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"context"
+	"errors"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+// EventBus passes events to consumers in the same process through a buffered channel. Events are lost
+// when the process exits, so it's meant for local development and tests.
+type EventBus struct {
+	events chan string
+}
+
+func NewEventBus(_ context.Context, size int) *EventBus {
+	return &EventBus{
+		events: make(chan string, size),
+	}
+}
+
+// PutEvents queues the events. It blocks while the buffer is full, and events not queued before ctx is
+// done are reported as failed.
+func (b *EventBus) PutEvents(ctx context.Context, data []string) []domain.Error {
+	results := make([]domain.Error, len(data))
+	for i, d := range data {
+		select {
+		case b.events <- d:
+		case <-ctx.Done():
+			results[i] = domain.NewExternalError("", nil, errors.New("event bus is full"))
+		}
+	}
+	return results
+}
+
+// Events returns the channel which consumers receive events from
+func (b *EventBus) Events() <-chan string {
+	return b.events
+}
@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+// OutboxRepository stores events in the outbox
+type OutboxRepository interface {
+	CreateOutboxEvents(ctx context.Context, payloads []string) domain.Error
+}
+
+// WebhookEventStore keeps marks of received webhook events in memory. Marks are not shared among
+// instances and are lost when the instance restarts, so it only suits a single instance.
+type WebhookEventStore struct {
+	outbox OutboxRepository
+
+	mu    sync.Mutex
+	marks map[string]time.Time
+}
+
+func NewWebhookEventStore(_ context.Context, outbox OutboxRepository) *WebhookEventStore {
+	return &WebhookEventStore{
+		outbox: outbox,
+		marks:  map[string]time.Time{},
+	}
+}
+
+// StoreWebhookEvents marks the webhook events received until expireAt and stores them in the outbox.
+// The marks are taken back if the events fail to be stored. Events whose marks haven't expired are
+// duplicates, which are not stored, and their webhook event IDs are returned.
+func (s *WebhookEventStore) StoreWebhookEvents(ctx context.Context, events []domain.WebhookOutboxEvent, expireAt time.Time) ([]string, domain.Error) {
+	payloads, marked, dropped := s.mark(events, expireAt)
+
+	if err := s.outbox.CreateOutboxEvents(ctx, payloads); err != nil {
+		s.unmark(marked)
+		return nil, err
+	}
+	return dropped, nil
+}
+
+// mark marks the events, and returns payloads of the events to be stored, IDs of the marked events and
+// IDs of the duplicates
+func (s *WebhookEventStore) mark(events []domain.WebhookOutboxEvent, expireAt time.Time) ([]string, []string, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var payloads, marked, dropped []string
+	for _, e := range events {
+		if e.WebhookEventID != "" {
+			if expire, ok := s.marks[e.WebhookEventID]; ok && time.Now().Before(expire) {
+				dropped = append(dropped, e.WebhookEventID)
+				continue
+			}
+			s.marks[e.WebhookEventID] = expireAt
+			marked = append(marked, e.WebhookEventID)
+		}
+		payloads = append(payloads, e.Payload)
+	}
+	return payloads, marked, dropped
+}
+
+func (s *WebhookEventStore) unmark(webhookEventIDs []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range webhookEventIDs {
+		delete(s.marks, id)
+	}
+}
+
+// DeleteExpiredWebhookEvents deletes marks expired before the given time, and returns how many are
+// deleted.
+func (s *WebhookEventStore) DeleteExpiredWebhookEvents(_ context.Context, expiredBefore time.Time) (int, domain.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for id, expire := range s.marks {
+		if expire.Before(expiredBefore) {
+			delete(s.marks, id)
+			count++
+		}
+	}
+	return count, nil
+}
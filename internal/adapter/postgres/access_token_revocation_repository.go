@@ -0,0 +1,213 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+type repoAccessTokenRevocation struct {
+	ID                    int          `db:"id"`
+	ExternalChannelID     string       `db:"external_channel_id"`
+	ExternalChannelSecret string       `db:"external_channel_secret"`
+	AccessToken           string       `db:"access_token"`
+	AccessTokenVersion    string       `db:"access_token_version"`
+	AccessTokenKeyID      string       `db:"access_token_key_id"`
+	AccessTokenExpiredAt  time.Time    `db:"access_token_expired_at"`
+	Attempts              int          `db:"attempts"`
+	LastError             string       `db:"last_error"`
+	NextAttemptAt         time.Time    `db:"next_attempt_at"`
+	RevokedAt             sql.NullTime `db:"revoked_at"`
+	CreatedAt             time.Time    `db:"created_at"`
+}
+
+type repoColumnPatternAccessTokenRevocation struct {
+	ID                    string
+	ExternalChannelID     string
+	ExternalChannelSecret string
+	AccessToken           string
+	AccessTokenVersion    string
+	AccessTokenKeyID      string
+	AccessTokenExpiredAt  string
+	Attempts              string
+	LastError             string
+	NextAttemptAt         string
+	RevokedAt             string
+	CreatedAt             string
+}
+
+const repoTableAccessTokenRevocation = "access_token_revocation"
+
+var repoColumnAccessTokenRevocation = repoColumnPatternAccessTokenRevocation{
+	ID:                    "id",
+	ExternalChannelID:     "external_channel_id",
+	ExternalChannelSecret: "external_channel_secret",
+	AccessToken:           "access_token",
+	AccessTokenVersion:    "access_token_version",
+	AccessTokenKeyID:      "access_token_key_id",
+	AccessTokenExpiredAt:  "access_token_expired_at",
+	Attempts:              "attempts",
+	LastError:             "last_error",
+	NextAttemptAt:         "next_attempt_at",
+	RevokedAt:             "revoked_at",
+	CreatedAt:             "created_at",
+}
+
+func (c *repoColumnPatternAccessTokenRevocation) columns() string {
+	return strings.Join([]string{
+		c.ID,
+		c.ExternalChannelID,
+		c.ExternalChannelSecret,
+		c.AccessToken,
+		c.AccessTokenVersion,
+		c.AccessTokenKeyID,
+		c.AccessTokenExpiredAt,
+		c.Attempts,
+		c.LastError,
+		c.NextAttemptAt,
+		c.RevokedAt,
+		c.CreatedAt,
+	}, ", ")
+}
+
+// toDomainAccessTokenRevocation maps the row back to domain model with decrypted credentials
+func (r *PostgresRepository) toDomainAccessTokenRevocation(ctx context.Context, row repoAccessTokenRevocation) (*domain.AccessTokenRevocation, domain.Error) {
+	secret, err := r.encryptor.decrypt(ctx, row.ExternalChannelSecret)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := r.encryptor.decrypt(ctx, row.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	revocation := domain.AccessTokenRevocation{
+		ID:                    row.ID,
+		ExternalChannelID:     row.ExternalChannelID,
+		ExternalChannelSecret: secret,
+		AccessToken:           accessToken,
+		AccessTokenVersion:    domain.AccessTokenVersion(row.AccessTokenVersion),
+		AccessTokenKeyID:      row.AccessTokenKeyID,
+		AccessTokenExpiredAt:  row.AccessTokenExpiredAt,
+		Attempts:              row.Attempts,
+		LastError:             row.LastError,
+		NextAttemptAt:         row.NextAttemptAt,
+		CreatedAt:             row.CreatedAt,
+	}
+	if row.RevokedAt.Valid {
+		revocation.RevokedAt = &row.RevokedAt.Time
+	}
+	return &revocation, nil
+}
+
+func (r *PostgresRepository) CreateAccessTokenRevocation(ctx context.Context, revocation domain.AccessTokenRevocation) (*domain.AccessTokenRevocation, domain.Error) {
+	// encrypt credentials before they are stored
+	secret, err := r.encryptor.encrypt(ctx, revocation.ExternalChannelSecret)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := r.encryptor.encrypt(ctx, revocation.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	insert := map[string]interface{}{
+		repoColumnAccessTokenRevocation.ExternalChannelID:     revocation.ExternalChannelID,
+		repoColumnAccessTokenRevocation.ExternalChannelSecret: secret,
+		repoColumnAccessTokenRevocation.AccessToken:           accessToken,
+		repoColumnAccessTokenRevocation.AccessTokenVersion:    string(revocation.AccessTokenVersion),
+		repoColumnAccessTokenRevocation.AccessTokenKeyID:      revocation.AccessTokenKeyID,
+		repoColumnAccessTokenRevocation.AccessTokenExpiredAt:  revocation.AccessTokenExpiredAt,
+		repoColumnAccessTokenRevocation.Attempts:              revocation.Attempts,
+		repoColumnAccessTokenRevocation.LastError:             revocation.LastError,
+		repoColumnAccessTokenRevocation.NextAttemptAt:         revocation.NextAttemptAt,
+	}
+	// build SQL query
+	query, args, sqlErr := r.pgsq.Insert(repoTableAccessTokenRevocation).
+		SetMap(insert).
+		Suffix(fmt.Sprintf("returning %s", repoColumnAccessTokenRevocation.columns())).
+		ToSql()
+	if sqlErr != nil {
+		return nil, domain.NewInternalError("", sqlErr)
+	}
+
+	// execute SQL query
+	row := repoAccessTokenRevocation{}
+	if sqlErr = r.db.GetContext(ctx, &row, query, args...); sqlErr != nil {
+		return nil, domain.NewExternalError("", nil, sqlErr)
+	}
+
+	// map the query result back to domain model
+	return r.toDomainAccessTokenRevocation(ctx, row)
+}
+
+// ClaimAccessTokenRevocations claims revocations which are due to retry and whose access token is
+// not expired yet. Claimed revocations are not claimable again until leaseUntil, and rows locked by
+// other claimers are skipped.
+func (r *PostgresRepository) ClaimAccessTokenRevocations(ctx context.Context, leaseUntil time.Time, limit int) ([]domain.AccessTokenRevocation, domain.Error) {
+	var rows []repoAccessTokenRevocation
+	err := r.claimRows(ctx, &rows, claimParams{
+		table:       repoTableAccessTokenRevocation,
+		idColumn:    repoColumnAccessTokenRevocation.ID,
+		leaseColumn: repoColumnAccessTokenRevocation.NextAttemptAt,
+		columns:     repoColumnAccessTokenRevocation.columns(),
+		where: []sq.Sqlizer{
+			sq.Eq{repoColumnAccessTokenRevocation.RevokedAt: nil},
+			sq.Expr(fmt.Sprintf("%s > now()", repoColumnAccessTokenRevocation.AccessTokenExpiredAt)),
+		},
+		orderBy:    []string{repoColumnAccessTokenRevocation.NextAttemptAt},
+		leaseUntil: leaseUntil,
+		limit:      limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// map the query result back to domain model
+	revocations := make([]domain.AccessTokenRevocation, 0, len(rows))
+	for _, row := range rows {
+		revocation, err := r.toDomainAccessTokenRevocation(ctx, row)
+		if err != nil {
+			return nil, err
+		}
+		revocations = append(revocations, *revocation)
+	}
+	return revocations, nil
+}
+
+func (r *PostgresRepository) UpdateAccessTokenRevocation(ctx context.Context, revocationID int, params domain.UpdateAccessTokenRevocationParams) domain.Error {
+	update := map[string]interface{}{}
+	if params.Attempts != nil {
+		update[repoColumnAccessTokenRevocation.Attempts] = *params.Attempts
+	}
+	if params.LastError != nil {
+		update[repoColumnAccessTokenRevocation.LastError] = *params.LastError
+	}
+	if params.NextAttemptAt != nil {
+		update[repoColumnAccessTokenRevocation.NextAttemptAt] = *params.NextAttemptAt
+	}
+	if params.RevokedAt != nil {
+		update[repoColumnAccessTokenRevocation.RevokedAt] = *params.RevokedAt
+	}
+	if len(update) == 0 {
+		return nil
+	}
+
+	query, args, err := r.pgsq.Update(repoTableAccessTokenRevocation).
+		SetMap(update).
+		Where(sq.Eq{repoColumnAccessTokenRevocation.ID: revocationID}).
+		ToSql()
+	if err != nil {
+		return domain.NewInternalError("", err)
+	}
+	if _, err = r.db.ExecContext(ctx, query, args...); err != nil {
+		return domain.NewExternalError("", nil, err)
+	}
+	return nil
+}
@@ -0,0 +1,246 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+type repoAPIKey struct {
+	ID             int          `db:"id"`
+	OrganizationID int          `db:"organization_id"`
+	Name           string       `db:"name"`
+	Role           string       `db:"role"`
+	KeyPrefix      string       `db:"key_prefix"`
+	KeyHash        string       `db:"key_hash"`
+	CreatedBy      string       `db:"created_by"`
+	LastUsedAt     sql.NullTime `db:"last_used_at"`
+	ExpiredAt      sql.NullTime `db:"expired_at"`
+	RevokedAt      sql.NullTime `db:"revoked_at"`
+	CreatedAt      time.Time    `db:"created_at"`
+}
+
+type repoColumnPatternAPIKey struct {
+	ID             string
+	OrganizationID string
+	Name           string
+	Role           string
+	KeyPrefix      string
+	KeyHash        string
+	CreatedBy      string
+	LastUsedAt     string
+	ExpiredAt      string
+	RevokedAt      string
+	CreatedAt      string
+}
+
+const repoTableAPIKey = "api_key"
+
+var repoColumnAPIKey = repoColumnPatternAPIKey{
+	ID:             "id",
+	OrganizationID: "organization_id",
+	Name:           "name",
+	Role:           "role",
+	KeyPrefix:      "key_prefix",
+	KeyHash:        "key_hash",
+	CreatedBy:      "created_by",
+	LastUsedAt:     "last_used_at",
+	ExpiredAt:      "expired_at",
+	RevokedAt:      "revoked_at",
+	CreatedAt:      "created_at",
+}
+
+func (c *repoColumnPatternAPIKey) columns() string {
+	return strings.Join([]string{
+		c.ID,
+		c.OrganizationID,
+		c.Name,
+		c.Role,
+		c.KeyPrefix,
+		c.KeyHash,
+		c.CreatedBy,
+		c.LastUsedAt,
+		c.ExpiredAt,
+		c.RevokedAt,
+		c.CreatedAt,
+	}, ", ")
+}
+
+func nullTimeToPtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func (row repoAPIKey) toDomain() domain.APIKey {
+	return domain.APIKey{
+		ID:             row.ID,
+		OrganizationID: row.OrganizationID,
+		Name:           row.Name,
+		Role:           domain.Role(row.Role),
+		Prefix:         row.KeyPrefix,
+		Hash:           row.KeyHash,
+		CreatedBy:      row.CreatedBy,
+		LastUsedAt:     nullTimeToPtr(row.LastUsedAt),
+		ExpiredAt:      nullTimeToPtr(row.ExpiredAt),
+		RevokedAt:      nullTimeToPtr(row.RevokedAt),
+		CreatedAt:      row.CreatedAt,
+	}
+}
+
+func (r *PostgresRepository) CreateAPIKey(ctx context.Context, key domain.APIKey) (*domain.APIKey, domain.Error) {
+	insert := map[string]interface{}{
+		repoColumnAPIKey.OrganizationID: key.OrganizationID,
+		repoColumnAPIKey.Name:           key.Name,
+		repoColumnAPIKey.Role:           key.Role,
+		repoColumnAPIKey.KeyPrefix:      key.Prefix,
+		repoColumnAPIKey.KeyHash:        key.Hash,
+		repoColumnAPIKey.CreatedBy:      key.CreatedBy,
+		repoColumnAPIKey.ExpiredAt:      key.ExpiredAt,
+	}
+	// build SQL query
+	query, args, err := r.pgsq.Insert(repoTableAPIKey).
+		SetMap(insert).
+		Suffix(fmt.Sprintf("returning %s", repoColumnAPIKey.columns())).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	// execute SQL query
+	row := repoAPIKey{}
+	if err = r.db.GetContext(ctx, &row, query, args...); err != nil {
+		return nil, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	created := row.toDomain()
+	return &created, nil
+}
+
+func (r *PostgresRepository) getAPIKey(ctx context.Context, where sq.Eq) (*domain.APIKey, domain.Error) {
+	query, args, err := r.pgsq.Select(repoColumnAPIKey.columns()).
+		From(repoTableAPIKey).
+		Where(where).
+		Limit(1).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	// get one row from result
+	row := repoAPIKey{}
+	if err = r.db.GetContext(ctx, &row, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.NewResourceNotFoundError("API key is not found", err)
+		}
+		return nil, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	key := row.toDomain()
+	return &key, nil
+}
+
+func (r *PostgresRepository) GetAPIKeyByID(ctx context.Context, keyID int) (*domain.APIKey, domain.Error) {
+	return r.getAPIKey(ctx, sq.Eq{repoColumnAPIKey.ID: keyID})
+}
+
+func (r *PostgresRepository) GetAPIKeyByHash(ctx context.Context, hash string) (*domain.APIKey, domain.Error) {
+	return r.getAPIKey(ctx, sq.Eq{repoColumnAPIKey.KeyHash: hash})
+}
+
+// ListAPIKeys returns all API keys of the organization including revoked ones, latest first
+func (r *PostgresRepository) ListAPIKeys(ctx context.Context, organizationID int) ([]domain.APIKey, domain.Error) {
+	query, args, err := r.pgsq.Select(repoColumnAPIKey.columns()).
+		From(repoTableAPIKey).
+		Where(sq.Eq{repoColumnAPIKey.OrganizationID: organizationID}).
+		OrderBy(fmt.Sprintf("%s desc", repoColumnAPIKey.ID)).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	var rows []repoAPIKey
+	if err = r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	keys := make([]domain.APIKey, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, row.toDomain())
+	}
+	return keys, nil
+}
+
+func (r *PostgresRepository) RevokeAPIKey(ctx context.Context, organizationID, keyID int) domain.Error {
+	query, args, err := r.pgsq.Update(repoTableAPIKey).
+		Set(repoColumnAPIKey.RevokedAt, sq.Expr(fmt.Sprintf("coalesce(%s, now())", repoColumnAPIKey.RevokedAt))).
+		Where(sq.Eq{
+			repoColumnAPIKey.OrganizationID: organizationID,
+			repoColumnAPIKey.ID:             keyID,
+		}).
+		ToSql()
+	if err != nil {
+		return domain.NewInternalError("", err)
+	}
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return domain.NewExternalError("", nil, err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return domain.NewExternalError("", nil, err)
+	} else if affected == 0 {
+		return domain.NewResourceNotFoundError("API key is not found", sql.ErrNoRows)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) UpdateAPIKeyRole(ctx context.Context, organizationID, keyID int, role domain.Role) (*domain.APIKey, domain.Error) {
+	query, args, err := r.pgsq.Update(repoTableAPIKey).
+		Set(repoColumnAPIKey.Role, role).
+		Where(sq.Eq{
+			repoColumnAPIKey.OrganizationID: organizationID,
+			repoColumnAPIKey.ID:             keyID,
+		}).
+		Suffix(fmt.Sprintf("returning %s", repoColumnAPIKey.columns())).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	row := repoAPIKey{}
+	if err = r.db.GetContext(ctx, &row, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.NewResourceNotFoundError("API key is not found", err)
+		}
+		return nil, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	key := row.toDomain()
+	return &key, nil
+}
+
+func (r *PostgresRepository) UpdateAPIKeyLastUsedAt(ctx context.Context, keyID int, lastUsedAt time.Time) domain.Error {
+	query, args, err := r.pgsq.Update(repoTableAPIKey).
+		Set(repoColumnAPIKey.LastUsedAt, lastUsedAt).
+		Where(sq.Eq{repoColumnAPIKey.ID: keyID}).
+		ToSql()
+	if err != nil {
+		return domain.NewInternalError("", err)
+	}
+	if _, err = r.db.ExecContext(ctx, query, args...); err != nil {
+		return domain.NewExternalError("", nil, err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+type repoAuditLog struct {
+	ID             int       `db:"id"`
+	ActorID        string    `db:"actor_id"`
+	ActorRequestID string    `db:"actor_request_id"`
+	ActorClientIP  string    `db:"actor_client_ip"`
+	Action         string    `db:"action"`
+	ResourceType   string    `db:"resource_type"`
+	ResourceID     string    `db:"resource_id"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+type repoColumnPatternAuditLog struct {
+	ID             string
+	ActorID        string
+	ActorRequestID string
+	ActorClientIP  string
+	Action         string
+	ResourceType   string
+	ResourceID     string
+	CreatedAt      string
+}
+
+const repoTableAuditLog = "audit_log"
+
+var repoColumnAuditLog = repoColumnPatternAuditLog{
+	ID:             "id",
+	ActorID:        "actor_id",
+	ActorRequestID: "actor_request_id",
+	ActorClientIP:  "actor_client_ip",
+	Action:         "action",
+	ResourceType:   "resource_type",
+	ResourceID:     "resource_id",
+	CreatedAt:      "created_at",
+}
+
+func (c *repoColumnPatternAuditLog) columns() string {
+	return strings.Join([]string{
+		c.ID,
+		c.ActorID,
+		c.ActorRequestID,
+		c.ActorClientIP,
+		c.Action,
+		c.ResourceType,
+		c.ResourceID,
+		c.CreatedAt,
+	}, ", ")
+}
+
+func (r *PostgresRepository) CreateAuditLog(ctx context.Context, log domain.AuditLog) (*domain.AuditLog, domain.Error) {
+	insert := map[string]interface{}{
+		repoColumnAuditLog.ActorID:        log.Actor.ID,
+		repoColumnAuditLog.ActorRequestID: log.Actor.RequestID,
+		repoColumnAuditLog.ActorClientIP:  log.Actor.ClientIP,
+		repoColumnAuditLog.Action:         string(log.Action),
+		repoColumnAuditLog.ResourceType:   string(log.ResourceType),
+		repoColumnAuditLog.ResourceID:     log.ResourceID,
+	}
+	// build SQL query
+	query, args, err := r.pgsq.Insert(repoTableAuditLog).
+		SetMap(insert).
+		Suffix(fmt.Sprintf("returning %s", repoColumnAuditLog.columns())).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	// execute SQL query
+	row := repoAuditLog{}
+	if err = r.db.GetContext(ctx, &row, query, args...); err != nil {
+		return nil, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	return &domain.AuditLog{
+		ID: row.ID,
+		Actor: domain.Actor{
+			ID:        row.ActorID,
+			RequestID: row.ActorRequestID,
+			ClientIP:  row.ActorClientIP,
+		},
+		Action:       domain.AuditAction(row.Action),
+		ResourceType: domain.AuditResourceType(row.ResourceType),
+		ResourceID:   row.ResourceID,
+		CreatedAt:    row.CreatedAt,
+	}, nil
+}
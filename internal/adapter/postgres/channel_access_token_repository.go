@@ -0,0 +1,222 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+type repoChannelAccessToken struct {
+	ID          int          `db:"id"`
+	ChannelID   int          `db:"channel_id"`
+	KeyID       string       `db:"key_id"`
+	AccessToken string       `db:"access_token"`
+	ExpiredAt   time.Time    `db:"expired_at"`
+	RevokedAt   sql.NullTime `db:"revoked_at"`
+	CreatedAt   time.Time    `db:"created_at"`
+}
+
+type repoColumnPatternChannelAccessToken struct {
+	ID          string
+	ChannelID   string
+	KeyID       string
+	AccessToken string
+	ExpiredAt   string
+	RevokedAt   string
+	CreatedAt   string
+}
+
+const repoTableChannelAccessToken = "channel_access_token"
+
+var repoColumnChannelAccessToken = repoColumnPatternChannelAccessToken{
+	ID:          "id",
+	ChannelID:   "channel_id",
+	KeyID:       "key_id",
+	AccessToken: "access_token",
+	ExpiredAt:   "expired_at",
+	RevokedAt:   "revoked_at",
+	CreatedAt:   "created_at",
+}
+
+func (c *repoColumnPatternChannelAccessToken) columns() string {
+	return strings.Join([]string{
+		c.ID,
+		c.ChannelID,
+		c.KeyID,
+		c.AccessToken,
+		c.ExpiredAt,
+		c.RevokedAt,
+		c.CreatedAt,
+	}, ", ")
+}
+
+// toDomainChannelAccessToken maps the row back to domain model with decrypted access token
+func (r *PostgresRepository) toDomainChannelAccessToken(ctx context.Context, row repoChannelAccessToken) (*domain.ChannelAccessToken, domain.Error) {
+	accessToken, err := r.encryptor.decrypt(ctx, row.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	token := domain.ChannelAccessToken{
+		ID:          row.ID,
+		ChannelID:   row.ChannelID,
+		KeyID:       row.KeyID,
+		AccessToken: accessToken,
+		ExpiredAt:   row.ExpiredAt,
+		CreatedAt:   row.CreatedAt,
+	}
+	if row.RevokedAt.Valid {
+		token.RevokedAt = &row.RevokedAt.Time
+	}
+	return &token, nil
+}
+
+func (r *PostgresRepository) CreateChannelAccessToken(ctx context.Context, token domain.ChannelAccessToken) (*domain.ChannelAccessToken, domain.Error) {
+	// encrypt the access token before it is stored
+	accessToken, err := r.encryptor.encrypt(ctx, token.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	insert := map[string]interface{}{
+		repoColumnChannelAccessToken.ChannelID:   token.ChannelID,
+		repoColumnChannelAccessToken.KeyID:       token.KeyID,
+		repoColumnChannelAccessToken.AccessToken: accessToken,
+		repoColumnChannelAccessToken.ExpiredAt:   token.ExpiredAt,
+	}
+	// build SQL query
+	query, args, sqlErr := r.pgsq.Insert(repoTableChannelAccessToken).
+		SetMap(insert).
+		Suffix(fmt.Sprintf("returning %s", repoColumnChannelAccessToken.columns())).
+		ToSql()
+	if sqlErr != nil {
+		return nil, domain.NewInternalError("", sqlErr)
+	}
+
+	// execute SQL query
+	row := repoChannelAccessToken{}
+	if sqlErr = r.db.GetContext(ctx, &row, query, args...); sqlErr != nil {
+		return nil, domain.NewExternalError("", nil, sqlErr)
+	}
+
+	// map the query result back to domain model
+	return r.toDomainChannelAccessToken(ctx, row)
+}
+
+// ListChannelAccessTokens returns the unexpired access tokens of the channel, including revoked ones
+func (r *PostgresRepository) ListChannelAccessTokens(ctx context.Context, channelID int) ([]domain.ChannelAccessToken, domain.Error) {
+	query, args, err := r.pgsq.Select(repoColumnChannelAccessToken.columns()).
+		From(repoTableChannelAccessToken).
+		Where(sq.Eq{repoColumnChannelAccessToken.ChannelID: channelID}).
+		Where(sq.Expr(fmt.Sprintf("%s > now()", repoColumnChannelAccessToken.ExpiredAt))).
+		OrderBy(fmt.Sprintf("%s desc", repoColumnChannelAccessToken.ID)).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	var rows []repoChannelAccessToken
+	if err = r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	tokens := make([]domain.ChannelAccessToken, 0, len(rows))
+	for _, row := range rows {
+		token, err := r.toDomainChannelAccessToken(ctx, row)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *token)
+	}
+	return tokens, nil
+}
+
+func (r *PostgresRepository) GetChannelAccessTokenByID(ctx context.Context, channelID, tokenID int) (*domain.ChannelAccessToken, domain.Error) {
+	query, args, err := r.pgsq.Select(repoColumnChannelAccessToken.columns()).
+		From(repoTableChannelAccessToken).
+		Where(sq.Eq{
+			repoColumnChannelAccessToken.ID:        tokenID,
+			repoColumnChannelAccessToken.ChannelID: channelID,
+		}).
+		Limit(1).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	// get one row from result
+	row := repoChannelAccessToken{}
+	if err = r.db.GetContext(ctx, &row, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.NewResourceNotFoundError("access token is not found", err)
+		}
+		return nil, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	return r.toDomainChannelAccessToken(ctx, row)
+}
+
+// MarkChannelAccessTokenRevoked marks the access token of the key ID revoked. Key IDs are issued by
+// LINE, so it works even if the token is revoked after its channel has been rotated.
+func (r *PostgresRepository) MarkChannelAccessTokenRevoked(ctx context.Context, keyID string) domain.Error {
+	query, args, err := r.pgsq.Update(repoTableChannelAccessToken).
+		Set(repoColumnChannelAccessToken.RevokedAt, time.Now()).
+		Where(sq.Eq{repoColumnChannelAccessToken.KeyID: keyID}).
+		Where(sq.Eq{repoColumnChannelAccessToken.RevokedAt: nil}).
+		ToSql()
+	if err != nil {
+		return domain.NewInternalError("", err)
+	}
+	if _, err = r.db.ExecContext(ctx, query, args...); err != nil {
+		return domain.NewExternalError("", nil, err)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) reencryptChannelAccessTokens(ctx context.Context, db sqlContextGetter, channelID int) domain.Error {
+	query, args, err := r.pgsq.Select(repoColumnChannelAccessToken.columns()).
+		From(repoTableChannelAccessToken).
+		Where(sq.Eq{repoColumnChannelAccessToken.ChannelID: channelID}).
+		Suffix("for update").
+		ToSql()
+	if err != nil {
+		return domain.NewInternalError("", err)
+	}
+
+	var rows []repoChannelAccessToken
+	if err = db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return domain.NewExternalError("", nil, err)
+	}
+
+	for _, row := range rows {
+		token, err := r.toDomainChannelAccessToken(ctx, row)
+		if err != nil {
+			return err
+		}
+		accessToken, err := r.encryptor.encrypt(ctx, token.AccessToken)
+		if err != nil {
+			return err
+		}
+
+		query, args, sqlErr := r.pgsq.Update(repoTableChannelAccessToken).
+			Set(repoColumnChannelAccessToken.AccessToken, accessToken).
+			Where(sq.Eq{repoColumnChannelAccessToken.ID: token.ID}).
+			ToSql()
+		if sqlErr != nil {
+			return domain.NewInternalError("", sqlErr)
+		}
+		if _, sqlErr = db.ExecContext(ctx, query, args...); sqlErr != nil {
+			return domain.NewExternalError("", nil, sqlErr)
+		}
+	}
+	return nil
+}
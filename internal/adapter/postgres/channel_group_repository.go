@@ -0,0 +1,297 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+type repoChannelGroup struct {
+	ID                     int          `db:"id"`
+	ChannelID              int          `db:"channel_id"`
+	ExternalGroupID        string       `db:"external_group_id"`
+	Type                   string       `db:"type"`
+	Name                   string       `db:"name"`
+	PictureURL             string       `db:"picture_url"`
+	MemberCount            int          `db:"member_count"`
+	JoinedAt               sql.NullTime `db:"joined_at"`
+	LeftAt                 sql.NullTime `db:"left_at"`
+	HasLeft                bool         `db:"has_left"`
+	LastSeenAt             time.Time    `db:"last_seen_at"`
+	SummaryFetchedAt       sql.NullTime `db:"summary_fetched_at"`
+	SummaryRefreshAttempts int          `db:"summary_refresh_attempts"`
+	SummaryRefreshAfter    time.Time    `db:"summary_refresh_after"`
+	CreatedAt              time.Time    `db:"created_at"`
+	UpdatedAt              time.Time    `db:"updated_at"`
+}
+
+type repoColumnPatternChannelGroup struct {
+	ID                     string
+	ChannelID              string
+	ExternalGroupID        string
+	Type                   string
+	Name                   string
+	PictureURL             string
+	MemberCount            string
+	JoinedAt               string
+	LeftAt                 string
+	HasLeft                string
+	LastSeenAt             string
+	SummaryFetchedAt       string
+	SummaryRefreshAttempts string
+	SummaryRefreshAfter    string
+	CreatedAt              string
+	UpdatedAt              string
+}
+
+const repoTableChannelGroup = "channel_group"
+
+var repoColumnChannelGroup = repoColumnPatternChannelGroup{
+	ID:                     "id",
+	ChannelID:              "channel_id",
+	ExternalGroupID:        "external_group_id",
+	Type:                   "type",
+	Name:                   "name",
+	PictureURL:             "picture_url",
+	MemberCount:            "member_count",
+	JoinedAt:               "joined_at",
+	LeftAt:                 "left_at",
+	HasLeft:                "has_left",
+	LastSeenAt:             "last_seen_at",
+	SummaryFetchedAt:       "summary_fetched_at",
+	SummaryRefreshAttempts: "summary_refresh_attempts",
+	SummaryRefreshAfter:    "summary_refresh_after",
+	CreatedAt:              "created_at",
+	UpdatedAt:              "updated_at",
+}
+
+func (c *repoColumnPatternChannelGroup) columns() string {
+	return strings.Join([]string{
+		c.ID,
+		c.ChannelID,
+		c.ExternalGroupID,
+		c.Type,
+		c.Name,
+		c.PictureURL,
+		c.MemberCount,
+		c.JoinedAt,
+		c.LeftAt,
+		c.HasLeft,
+		c.LastSeenAt,
+		c.SummaryFetchedAt,
+		c.SummaryRefreshAttempts,
+		c.SummaryRefreshAfter,
+		c.CreatedAt,
+		c.UpdatedAt,
+	}, ", ")
+}
+
+func (row repoChannelGroup) toDomain() domain.ChannelGroup {
+	return domain.ChannelGroup{
+		ID:              row.ID,
+		ChannelID:       row.ChannelID,
+		ExternalGroupID: row.ExternalGroupID,
+		Type:            domain.LineSourceType(row.Type),
+		JoinedAt:        nullTimeToPtr(row.JoinedAt),
+		LeftAt:          nullTimeToPtr(row.LeftAt),
+		Left:            row.HasLeft,
+		LastSeenAt:      row.LastSeenAt,
+		Summary: domain.LineGroupSummary{
+			Name:        row.Name,
+			PictureURL:  row.PictureURL,
+			MemberCount: row.MemberCount,
+		},
+		SummaryFetchedAt:       nullTimeToPtr(row.SummaryFetchedAt),
+		SummaryRefreshAttempts: row.SummaryRefreshAttempts,
+		SummaryRefreshAfter:    row.SummaryRefreshAfter,
+		CreatedAt:              row.CreatedAt,
+		UpdatedAt:              row.UpdatedAt,
+	}
+}
+
+// UpsertChannelGroup creates the group or merges the event into it. Only the latest timestamps are
+// kept, and the left flag is derived from them, so redelivered or out-of-order events don't roll the
+// group back.
+func (r *PostgresRepository) UpsertChannelGroup(ctx context.Context, params domain.UpsertChannelGroupParams) (*domain.ChannelGroup, domain.Error) {
+	c := repoColumnChannelGroup
+	left := params.LeftAt != nil && (params.JoinedAt == nil || params.LeftAt.After(*params.JoinedAt))
+
+	latest := func(column string) string {
+		return fmt.Sprintf("greatest(%s.%s, excluded.%s)", repoTableChannelGroup, column, column)
+	}
+	updates := []string{
+		fmt.Sprintf("%s = %s", c.JoinedAt, latest(c.JoinedAt)),
+		fmt.Sprintf("%s = %s", c.LeftAt, latest(c.LeftAt)),
+		fmt.Sprintf("%s = coalesce(%s, '-infinity') > coalesce(%s, '-infinity')", c.HasLeft, latest(c.LeftAt), latest(c.JoinedAt)),
+		fmt.Sprintf("%s = %s", c.LastSeenAt, latest(c.LastSeenAt)),
+		fmt.Sprintf("%s = now()", c.UpdatedAt),
+	}
+	if params.RefreshSummary {
+		updates = append(updates, fmt.Sprintf("%s = least(%s.%s, now())", c.SummaryRefreshAfter, repoTableChannelGroup, c.SummaryRefreshAfter))
+	}
+	onConflict := fmt.Sprintf("on conflict (%s, %s) do update set ", c.ChannelID, c.ExternalGroupID) + strings.Join(updates, ", ")
+
+	// build SQL query
+	query, args, err := r.pgsq.Insert(repoTableChannelGroup).
+		SetMap(map[string]interface{}{
+			c.ChannelID:       params.ChannelID,
+			c.ExternalGroupID: params.ExternalGroupID,
+			c.Type:            params.Type,
+			c.JoinedAt:        params.JoinedAt,
+			c.LeftAt:          params.LeftAt,
+			c.HasLeft:         left,
+			c.LastSeenAt:      params.SeenAt,
+		}).
+		Suffix(fmt.Sprintf("%s returning %s", onConflict, c.columns())).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	// execute SQL query
+	row := repoChannelGroup{}
+	if err = r.db.GetContext(ctx, &row, query, args...); err != nil {
+		return nil, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	group := row.toDomain()
+	return &group, nil
+}
+
+func (r *PostgresRepository) UpdateChannelGroupSummary(ctx context.Context, groupID int, params domain.UpdateChannelGroupSummaryParams) domain.Error {
+	c := repoColumnChannelGroup
+	values := map[string]interface{}{
+		c.UpdatedAt: time.Now(),
+	}
+	if params.Summary != nil {
+		values[c.Name] = params.Summary.Name
+		values[c.PictureURL] = params.Summary.PictureURL
+		values[c.MemberCount] = params.Summary.MemberCount
+	}
+	if params.SummaryFetchedAt != nil {
+		values[c.SummaryFetchedAt] = *params.SummaryFetchedAt
+	}
+	if params.SummaryRefreshAttempts != nil {
+		values[c.SummaryRefreshAttempts] = *params.SummaryRefreshAttempts
+	}
+	if params.SummaryRefreshAfter != nil {
+		values[c.SummaryRefreshAfter] = *params.SummaryRefreshAfter
+	}
+
+	query, args, err := r.pgsq.Update(repoTableChannelGroup).
+		SetMap(values).
+		Where(sq.Eq{c.ID: groupID}).
+		ToSql()
+	if err != nil {
+		return domain.NewInternalError("", err)
+	}
+	if _, err = r.db.ExecContext(ctx, query, args...); err != nil {
+		return domain.NewExternalError("", nil, err)
+	}
+	return nil
+}
+
+// ClaimChannelGroupsForSummaryRefresh claims groups whose summary is due to be fetched. Groups the
+// channel has left are skipped since LINE only returns summaries of groups the channel is in.
+func (r *PostgresRepository) ClaimChannelGroupsForSummaryRefresh(ctx context.Context, leaseUntil time.Time, limit int) ([]domain.ChannelGroup, domain.Error) {
+	var rows []repoChannelGroup
+	err := r.claimRows(ctx, &rows, claimParams{
+		table:       repoTableChannelGroup,
+		idColumn:    repoColumnChannelGroup.ID,
+		leaseColumn: repoColumnChannelGroup.SummaryRefreshAfter,
+		columns:     repoColumnChannelGroup.columns(),
+		where:       []sq.Sqlizer{sq.Eq{repoColumnChannelGroup.HasLeft: false}},
+		orderBy:     []string{repoColumnChannelGroup.SummaryRefreshAfter},
+		leaseUntil:  leaseUntil,
+		limit:       limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// map the query result back to domain model
+	groups := make([]domain.ChannelGroup, 0, len(rows))
+	for _, row := range rows {
+		groups = append(groups, row.toDomain())
+	}
+	return groups, nil
+}
+
+func (r *PostgresRepository) GetChannelGroup(ctx context.Context, channelID int, externalGroupID string) (*domain.ChannelGroup, domain.Error) {
+	query, args, err := r.pgsq.Select(repoColumnChannelGroup.columns()).
+		From(repoTableChannelGroup).
+		Where(sq.Eq{
+			repoColumnChannelGroup.ChannelID:       channelID,
+			repoColumnChannelGroup.ExternalGroupID: externalGroupID,
+		}).
+		Limit(1).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	// get one row from result
+	row := repoChannelGroup{}
+	if err = r.db.GetContext(ctx, &row, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.NewResourceNotFoundError("channel group is not found", err)
+		}
+		return nil, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	group := row.toDomain()
+	return &group, nil
+}
+
+// ListChannelGroups returns groups of the channel in the given page, recently active first, and the
+// total number of them.
+func (r *PostgresRepository) ListChannelGroups(ctx context.Context, channelID int, pagination domain.Pagination) ([]domain.ChannelGroup, int, domain.Error) {
+	where := sq.Eq{repoColumnChannelGroup.ChannelID: channelID}
+
+	// count all groups for pagination
+	query, args, err := r.pgsq.Select("count(*)").
+		From(repoTableChannelGroup).
+		Where(where).
+		ToSql()
+	if err != nil {
+		return nil, 0, domain.NewInternalError("", err)
+	}
+
+	var total int
+	if err = r.db.GetContext(ctx, &total, query, args...); err != nil {
+		return nil, 0, domain.NewExternalError("", nil, err)
+	}
+
+	// get groups of the requested page
+	query, args, err = r.pgsq.Select(repoColumnChannelGroup.columns()).
+		From(repoTableChannelGroup).
+		Where(where).
+		OrderBy(fmt.Sprintf("%s desc", repoColumnChannelGroup.LastSeenAt), repoColumnChannelGroup.ID).
+		Limit(uint64(pagination.Limit)).
+		Offset(uint64(pagination.Offset)).
+		ToSql()
+	if err != nil {
+		return nil, 0, domain.NewInternalError("", err)
+	}
+
+	var rows []repoChannelGroup
+	if err = r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, 0, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	groups := make([]domain.ChannelGroup, 0, len(rows))
+	for _, row := range rows {
+		groups = append(groups, row.toDomain())
+	}
+	return groups, total, nil
+}
@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// channelChangedChannel is notified by the trigger on the channel table with the external channel
+	// ID of the inserted, updated or deleted channel
+	channelChangedChannel = "channel_changed"
+
+	channelListenerMinReconnectInterval = time.Second
+	channelListenerMaxReconnectInterval = time.Minute
+	// channelListenerPingInterval is how often the connection is checked while no notification
+	// arrives, so a broken connection is reconnected
+	channelListenerPingInterval = 90 * time.Second
+)
+
+// ChannelInvalidator is told which channels are changed
+type ChannelInvalidator interface {
+	InvalidateChannel(ctx context.Context, externalChannelID string)
+	InvalidateAllChannels(ctx context.Context)
+}
+
+// ChannelListener listens to changes of channels made by any instance
+type ChannelListener struct {
+	listener *pq.Listener
+}
+
+// NewChannelListener opens a dedicated connection to the database listening to channel changes
+func NewChannelListener(ctx context.Context, dsn string) (*ChannelListener, error) {
+	logger := zerolog.Ctx(ctx).With().Str("component", "channel listener").Logger()
+	listener := pq.NewListener(dsn, channelListenerMinReconnectInterval, channelListenerMaxReconnectInterval,
+		func(event pq.ListenerEventType, err error) {
+			if err != nil {
+				logger.Warn().Err(err).Int("event", int(event)).Msg("channel listener connection is broken")
+			}
+		})
+	if err := listener.Listen(channelChangedChannel); err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	return &ChannelListener{listener: listener}, nil
+}
+
+// Listen invalidates changed channels until ctx is done. Notifications are lost while the connection
+// is broken, so all channels are invalidated once it is reconnected.
+func (l *ChannelListener) Listen(ctx context.Context, invalidator ChannelInvalidator) {
+	defer func() {
+		_ = l.listener.Close()
+	}()
+
+	ticker := time.NewTicker(channelListenerPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case notification := <-l.listener.Notify:
+			// a nil notification is sent after the connection is reconnected
+			if notification == nil {
+				invalidator.InvalidateAllChannels(ctx)
+				continue
+			}
+			invalidator.InvalidateChannel(ctx, notification.Extra)
+		case <-ticker.C:
+			// an error means the connection is broken, which the listener reconnects by itself
+			_ = l.listener.Ping()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
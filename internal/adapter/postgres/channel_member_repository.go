@@ -0,0 +1,326 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+type repoChannelMember struct {
+	ID                     int          `db:"id"`
+	ChannelID              int          `db:"channel_id"`
+	ExternalMemberID       string       `db:"external_member_id"`
+	FollowedAt             sql.NullTime `db:"followed_at"`
+	UnfollowedAt           sql.NullTime `db:"unfollowed_at"`
+	Blocked                bool         `db:"blocked"`
+	LastSeenAt             time.Time    `db:"last_seen_at"`
+	SourceType             string       `db:"source_type"`
+	ExternalGroupID        string       `db:"external_group_id"`
+	DisplayName            string       `db:"display_name"`
+	PictureURL             string       `db:"picture_url"`
+	StatusMessage          string       `db:"status_message"`
+	Language               string       `db:"language"`
+	ProfileFetchedAt       sql.NullTime `db:"profile_fetched_at"`
+	ProfileRefreshAttempts int          `db:"profile_refresh_attempts"`
+	ProfileRefreshAfter    time.Time    `db:"profile_refresh_after"`
+	CreatedAt              time.Time    `db:"created_at"`
+	UpdatedAt              time.Time    `db:"updated_at"`
+}
+
+type repoColumnPatternChannelMember struct {
+	ID                     string
+	ChannelID              string
+	ExternalMemberID       string
+	FollowedAt             string
+	UnfollowedAt           string
+	Blocked                string
+	LastSeenAt             string
+	SourceType             string
+	ExternalGroupID        string
+	DisplayName            string
+	PictureURL             string
+	StatusMessage          string
+	Language               string
+	ProfileFetchedAt       string
+	ProfileRefreshAttempts string
+	ProfileRefreshAfter    string
+	CreatedAt              string
+	UpdatedAt              string
+}
+
+const repoTableChannelMember = "channel_member"
+
+var repoColumnChannelMember = repoColumnPatternChannelMember{
+	ID:                     "id",
+	ChannelID:              "channel_id",
+	ExternalMemberID:       "external_member_id",
+	FollowedAt:             "followed_at",
+	UnfollowedAt:           "unfollowed_at",
+	Blocked:                "blocked",
+	LastSeenAt:             "last_seen_at",
+	SourceType:             "source_type",
+	ExternalGroupID:        "external_group_id",
+	DisplayName:            "display_name",
+	PictureURL:             "picture_url",
+	StatusMessage:          "status_message",
+	Language:               "language",
+	ProfileFetchedAt:       "profile_fetched_at",
+	ProfileRefreshAttempts: "profile_refresh_attempts",
+	ProfileRefreshAfter:    "profile_refresh_after",
+	CreatedAt:              "created_at",
+	UpdatedAt:              "updated_at",
+}
+
+func (c *repoColumnPatternChannelMember) columns() string {
+	return strings.Join([]string{
+		c.ID,
+		c.ChannelID,
+		c.ExternalMemberID,
+		c.FollowedAt,
+		c.UnfollowedAt,
+		c.Blocked,
+		c.LastSeenAt,
+		c.SourceType,
+		c.ExternalGroupID,
+		c.DisplayName,
+		c.PictureURL,
+		c.StatusMessage,
+		c.Language,
+		c.ProfileFetchedAt,
+		c.ProfileRefreshAttempts,
+		c.ProfileRefreshAfter,
+		c.CreatedAt,
+		c.UpdatedAt,
+	}, ", ")
+}
+
+func (row repoChannelMember) toDomain() domain.ChannelMember {
+	return domain.ChannelMember{
+		ID:               row.ID,
+		ChannelID:        row.ChannelID,
+		ExternalMemberID: row.ExternalMemberID,
+		FollowedAt:       nullTimeToPtr(row.FollowedAt),
+		UnfollowedAt:     nullTimeToPtr(row.UnfollowedAt),
+		Blocked:          row.Blocked,
+		LastSeenAt:       row.LastSeenAt,
+		SourceType:       domain.LineSourceType(row.SourceType),
+		ExternalGroupID:  row.ExternalGroupID,
+		Profile: domain.LineProfile{
+			DisplayName:   row.DisplayName,
+			PictureURL:    row.PictureURL,
+			StatusMessage: row.StatusMessage,
+			Language:      row.Language,
+		},
+		ProfileFetchedAt:       nullTimeToPtr(row.ProfileFetchedAt),
+		ProfileRefreshAttempts: row.ProfileRefreshAttempts,
+		ProfileRefreshAfter:    row.ProfileRefreshAfter,
+		CreatedAt:              row.CreatedAt,
+		UpdatedAt:              row.UpdatedAt,
+	}
+}
+
+// UpsertChannelMember creates the member or merges the event into it. Only the latest timestamps
+// are kept, and the blocked flag and the source are derived from them, so redelivered or
+// out-of-order events don't roll the member back.
+func (r *PostgresRepository) UpsertChannelMember(ctx context.Context, params domain.UpsertChannelMemberParams) (*domain.ChannelMember, domain.Error) {
+	c := repoColumnChannelMember
+	blocked := params.UnfollowedAt != nil && (params.FollowedAt == nil || params.UnfollowedAt.After(*params.FollowedAt))
+
+	latest := func(column string) string {
+		return fmt.Sprintf("greatest(%s.%s, excluded.%s)", repoTableChannelMember, column, column)
+	}
+	// the source of the latest event wins
+	latestSeen := func(column string) string {
+		return fmt.Sprintf("case when excluded.%s >= %s.%s then excluded.%s else %s.%s end",
+			c.LastSeenAt, repoTableChannelMember, c.LastSeenAt,
+			column, repoTableChannelMember, column)
+	}
+	onConflict := fmt.Sprintf("on conflict (%s, %s) do update set ", c.ChannelID, c.ExternalMemberID) +
+		strings.Join([]string{
+			fmt.Sprintf("%s = %s", c.FollowedAt, latest(c.FollowedAt)),
+			fmt.Sprintf("%s = %s", c.UnfollowedAt, latest(c.UnfollowedAt)),
+			fmt.Sprintf("%s = coalesce(%s, '-infinity') > coalesce(%s, '-infinity')", c.Blocked, latest(c.UnfollowedAt), latest(c.FollowedAt)),
+			fmt.Sprintf("%s = %s", c.SourceType, latestSeen(c.SourceType)),
+			fmt.Sprintf("%s = %s", c.ExternalGroupID, latestSeen(c.ExternalGroupID)),
+			fmt.Sprintf("%s = %s", c.LastSeenAt, latest(c.LastSeenAt)),
+			fmt.Sprintf("%s = now()", c.UpdatedAt),
+		}, ", ")
+
+	sourceType := params.SourceType
+	if sourceType == "" {
+		sourceType = domain.LineSourceTypeUser
+	}
+
+	// build SQL query
+	query, args, err := r.pgsq.Insert(repoTableChannelMember).
+		SetMap(map[string]interface{}{
+			c.ChannelID:        params.ChannelID,
+			c.ExternalMemberID: params.ExternalMemberID,
+			c.FollowedAt:       params.FollowedAt,
+			c.UnfollowedAt:     params.UnfollowedAt,
+			c.Blocked:          blocked,
+			c.LastSeenAt:       params.SeenAt,
+			c.SourceType:       sourceType,
+			c.ExternalGroupID:  params.ExternalGroupID,
+		}).
+		Suffix(fmt.Sprintf("%s returning %s", onConflict, c.columns())).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	// execute SQL query
+	row := repoChannelMember{}
+	if err = r.db.GetContext(ctx, &row, query, args...); err != nil {
+		return nil, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	member := row.toDomain()
+	return &member, nil
+}
+
+func (r *PostgresRepository) UpdateChannelMemberProfile(ctx context.Context, memberID int, params domain.UpdateChannelMemberProfileParams) domain.Error {
+	c := repoColumnChannelMember
+	values := map[string]interface{}{
+		c.UpdatedAt: time.Now(),
+	}
+	if params.Profile != nil {
+		values[c.DisplayName] = params.Profile.DisplayName
+		values[c.PictureURL] = params.Profile.PictureURL
+		values[c.StatusMessage] = params.Profile.StatusMessage
+		values[c.Language] = params.Profile.Language
+	}
+	if params.ProfileFetchedAt != nil {
+		values[c.ProfileFetchedAt] = *params.ProfileFetchedAt
+	}
+	if params.ProfileRefreshAttempts != nil {
+		values[c.ProfileRefreshAttempts] = *params.ProfileRefreshAttempts
+	}
+	if params.ProfileRefreshAfter != nil {
+		values[c.ProfileRefreshAfter] = *params.ProfileRefreshAfter
+	}
+
+	query, args, err := r.pgsq.Update(repoTableChannelMember).
+		SetMap(values).
+		Where(sq.Eq{c.ID: memberID}).
+		ToSql()
+	if err != nil {
+		return domain.NewInternalError("", err)
+	}
+	if _, err = r.db.ExecContext(ctx, query, args...); err != nil {
+		return domain.NewExternalError("", nil, err)
+	}
+	return nil
+}
+
+// ClaimChannelMembersForProfileRefresh claims members whose profile is due to be fetched. Members
+// blocking the channel are skipped unless they are last seen in a group or a room, since LINE only
+// returns their profiles there.
+func (r *PostgresRepository) ClaimChannelMembersForProfileRefresh(ctx context.Context, leaseUntil time.Time, limit int) ([]domain.ChannelMember, domain.Error) {
+	var rows []repoChannelMember
+	err := r.claimRows(ctx, &rows, claimParams{
+		table:       repoTableChannelMember,
+		idColumn:    repoColumnChannelMember.ID,
+		leaseColumn: repoColumnChannelMember.ProfileRefreshAfter,
+		columns:     repoColumnChannelMember.columns(),
+		where: []sq.Sqlizer{sq.Or{
+			sq.Eq{repoColumnChannelMember.Blocked: false},
+			sq.NotEq{repoColumnChannelMember.SourceType: domain.LineSourceTypeUser},
+		}},
+		orderBy:    []string{repoColumnChannelMember.ProfileRefreshAfter},
+		leaseUntil: leaseUntil,
+		limit:      limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// map the query result back to domain model
+	members := make([]domain.ChannelMember, 0, len(rows))
+	for _, row := range rows {
+		members = append(members, row.toDomain())
+	}
+	return members, nil
+}
+
+func (r *PostgresRepository) GetChannelMember(ctx context.Context, channelID int, externalMemberID string) (*domain.ChannelMember, domain.Error) {
+	query, args, err := r.pgsq.Select(repoColumnChannelMember.columns()).
+		From(repoTableChannelMember).
+		Where(sq.Eq{
+			repoColumnChannelMember.ChannelID:        channelID,
+			repoColumnChannelMember.ExternalMemberID: externalMemberID,
+		}).
+		Limit(1).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	// get one row from result
+	row := repoChannelMember{}
+	if err = r.db.GetContext(ctx, &row, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.NewResourceNotFoundError("channel member is not found", err)
+		}
+		return nil, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	member := row.toDomain()
+	return &member, nil
+}
+
+// ListChannelMembers returns members of the channel in the given page, most recently seen first, and
+// the total number of members matching the filter.
+func (r *PostgresRepository) ListChannelMembers(ctx context.Context, channelID int, filter domain.ChannelMemberFilter, pagination domain.Pagination) ([]domain.ChannelMember, int, domain.Error) {
+	where := sq.Eq{repoColumnChannelMember.ChannelID: channelID}
+	if filter.Blocked != nil {
+		where[repoColumnChannelMember.Blocked] = *filter.Blocked
+	}
+
+	// count all matched members for pagination
+	query, args, err := r.pgsq.Select("count(*)").
+		From(repoTableChannelMember).
+		Where(where).
+		ToSql()
+	if err != nil {
+		return nil, 0, domain.NewInternalError("", err)
+	}
+
+	var total int
+	if err = r.db.GetContext(ctx, &total, query, args...); err != nil {
+		return nil, 0, domain.NewExternalError("", nil, err)
+	}
+
+	// get members of the requested page
+	query, args, err = r.pgsq.Select(repoColumnChannelMember.columns()).
+		From(repoTableChannelMember).
+		Where(where).
+		OrderBy(fmt.Sprintf("%s desc", repoColumnChannelMember.LastSeenAt), repoColumnChannelMember.ID).
+		Limit(uint64(pagination.Limit)).
+		Offset(uint64(pagination.Offset)).
+		ToSql()
+	if err != nil {
+		return nil, 0, domain.NewInternalError("", err)
+	}
+
+	var rows []repoChannelMember
+	if err = r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, 0, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	members := make([]domain.ChannelMember, 0, len(rows))
+	for _, row := range rows {
+		members = append(members, row.toDomain())
+	}
+	return members, total, nil
+}
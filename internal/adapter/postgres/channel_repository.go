@@ -14,85 +14,203 @@ import (
 )
 
 type repoChannel struct {
-	ID                    int       `db:"id"`
-	Name                  string    `db:"name"`
-	ExternalChannelID     string    `db:"external_channel_id"`
-	ExternalChannelSecret string    `db:"external_channel_secret"`
-	AccessToken           string    `db:"access_token"`
-	AccessTokenExpiredAt  time.Time `db:"access_token_expired_at"`
-	CreatedAt             time.Time `db:"created_at"`
-	UpdatedAt             time.Time `db:"updated_at"`
+	ID                         int          `db:"id"`
+	OrganizationID             int          `db:"organization_id"`
+	Name                       string       `db:"name"`
+	ExternalChannelID          string       `db:"external_channel_id"`
+	ExternalChannelSecret      string       `db:"external_channel_secret"`
+	AccessToken                string       `db:"access_token"`
+	AccessTokenExpiredAt       time.Time    `db:"access_token_expired_at"`
+	AccessTokenVersion         string       `db:"access_token_version"`
+	AccessTokenKeyID           string       `db:"access_token_key_id"`
+	AssertionKeyID             string       `db:"assertion_key_id"`
+	AssertionPrivateKey        string       `db:"assertion_private_key"`
+	AccessTokenRefreshAttempts int          `db:"access_token_refresh_attempts"`
+	AccessTokenRefreshAfter    time.Time    `db:"access_token_refresh_after"`
+	HealthStatus               string       `db:"health_status"`
+	HealthCheckedAt            sql.NullTime `db:"health_checked_at"`
+	HealthError                string       `db:"health_error"`
+	HealthCheckAfter           time.Time    `db:"health_check_after"`
+	CreatedAt                  time.Time    `db:"created_at"`
+	UpdatedAt                  time.Time    `db:"updated_at"`
 }
 
 type repoColumnPatternChannel struct {
-	ID                    string
-	Name                  string
-	ExternalChannelID     string
-	ExternalChannelSecret string
-	AccessToken           string
-	AccessTokenExpiredAt  string
-	CreatedAt             string
-	UpdatedAt             string
+	ID                         string
+	OrganizationID             string
+	Name                       string
+	ExternalChannelID          string
+	ExternalChannelSecret      string
+	AccessToken                string
+	AccessTokenExpiredAt       string
+	AccessTokenVersion         string
+	AccessTokenKeyID           string
+	AssertionKeyID             string
+	AssertionPrivateKey        string
+	AccessTokenRefreshAttempts string
+	AccessTokenRefreshAfter    string
+	HealthStatus               string
+	HealthCheckedAt            string
+	HealthError                string
+	HealthCheckAfter           string
+	CreatedAt                  string
+	UpdatedAt                  string
 }
 
 const repoTableChannel = "channel"
 
 var repoColumnChannel = repoColumnPatternChannel{
-	ID:                    "id",
-	Name:                  "name",
-	ExternalChannelID:     "external_channel_id",
-	ExternalChannelSecret: "external_channel_secret",
-	AccessToken:           "access_token",
-	AccessTokenExpiredAt:  "access_token_expired_at",
-	CreatedAt:             "created_at",
-	UpdatedAt:             "updated_at",
+	ID:                         "id",
+	OrganizationID:             "organization_id",
+	Name:                       "name",
+	ExternalChannelID:          "external_channel_id",
+	ExternalChannelSecret:      "external_channel_secret",
+	AccessToken:                "access_token",
+	AccessTokenExpiredAt:       "access_token_expired_at",
+	AccessTokenVersion:         "access_token_version",
+	AccessTokenKeyID:           "access_token_key_id",
+	AssertionKeyID:             "assertion_key_id",
+	AssertionPrivateKey:        "assertion_private_key",
+	AccessTokenRefreshAttempts: "access_token_refresh_attempts",
+	AccessTokenRefreshAfter:    "access_token_refresh_after",
+	HealthStatus:               "health_status",
+	HealthCheckedAt:            "health_checked_at",
+	HealthError:                "health_error",
+	HealthCheckAfter:           "health_check_after",
+	CreatedAt:                  "created_at",
+	UpdatedAt:                  "updated_at",
 }
 
 func (c *repoColumnPatternChannel) columns() string {
 	return strings.Join([]string{
 		c.ID,
+		c.OrganizationID,
 		c.Name,
 		c.ExternalChannelID,
 		c.ExternalChannelSecret,
 		c.AccessToken,
 		c.AccessTokenExpiredAt,
+		c.AccessTokenVersion,
+		c.AccessTokenKeyID,
+		c.AssertionKeyID,
+		c.AssertionPrivateKey,
+		c.AccessTokenRefreshAttempts,
+		c.AccessTokenRefreshAfter,
+		c.HealthStatus,
+		c.HealthCheckedAt,
+		c.HealthError,
+		c.HealthCheckAfter,
 		c.CreatedAt,
 		c.UpdatedAt,
 	}, ", ")
 }
 
+// toDomainChannel maps the row back to domain model with decrypted credentials
+func (r *PostgresRepository) toDomainChannel(ctx context.Context, row repoChannel) (*domain.Channel, domain.Error) {
+	secret, err := r.encryptor.decrypt(ctx, row.ExternalChannelSecret)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := r.encryptor.decrypt(ctx, row.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	assertionPrivateKey, err := r.encryptor.decrypt(ctx, row.AssertionPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	channel := domain.Channel{
+		ID:                         row.ID,
+		OrganizationID:             row.OrganizationID,
+		Name:                       row.Name,
+		ExternalChannelID:          row.ExternalChannelID,
+		ExternalChannelSecret:      secret,
+		AccessToken:                accessToken,
+		AccessTokenExpiredAt:       row.AccessTokenExpiredAt,
+		AccessTokenVersion:         domain.AccessTokenVersion(row.AccessTokenVersion),
+		AccessTokenKeyID:           row.AccessTokenKeyID,
+		AssertionKeyID:             row.AssertionKeyID,
+		AssertionPrivateKey:        assertionPrivateKey,
+		AccessTokenRefreshAttempts: row.AccessTokenRefreshAttempts,
+		AccessTokenRefreshAfter:    row.AccessTokenRefreshAfter,
+		HealthStatus:               domain.ChannelHealthStatus(row.HealthStatus),
+		HealthError:                row.HealthError,
+		HealthCheckAfter:           row.HealthCheckAfter,
+		CreatedAt:                  row.CreatedAt,
+		UpdatedAt:                  row.UpdatedAt,
+	}
+	if row.HealthCheckedAt.Valid {
+		channel.HealthCheckedAt = &row.HealthCheckedAt.Time
+	}
+	return &channel, nil
+}
+
+func (r *PostgresRepository) toDomainChannels(ctx context.Context, rows []repoChannel) ([]domain.Channel, domain.Error) {
+	channels := make([]domain.Channel, 0, len(rows))
+	for _, row := range rows {
+		channel, err := r.toDomainChannel(ctx, row)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, *channel)
+	}
+	return channels, nil
+}
+
 func (r *PostgresRepository) CreateChannel(ctx context.Context, channel domain.Channel) (*domain.Channel, domain.Error) {
+	// encrypt credentials before they are stored
+	secret, err := r.encryptor.encrypt(ctx, channel.ExternalChannelSecret)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := r.encryptor.encrypt(ctx, channel.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	assertionPrivateKey, err := r.encryptor.encrypt(ctx, channel.AssertionPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
 	update := map[string]interface{}{
+		repoColumnChannel.OrganizationID:        channel.OrganizationID,
 		repoColumnChannel.Name:                  channel.Name,
 		repoColumnChannel.ExternalChannelID:     channel.ExternalChannelID,
-		repoColumnChannel.ExternalChannelSecret: channel.ExternalChannelSecret,
-		repoColumnChannel.AccessToken:           channel.AccessToken,
+		repoColumnChannel.ExternalChannelSecret: secret,
+		repoColumnChannel.AccessToken:           accessToken,
 		repoColumnChannel.AccessTokenExpiredAt:  channel.AccessTokenExpiredAt,
+		repoColumnChannel.AccessTokenVersion:    string(channel.AccessTokenVersion),
+		repoColumnChannel.AccessTokenKeyID:      channel.AccessTokenKeyID,
+		repoColumnChannel.AssertionKeyID:        channel.AssertionKeyID,
+		repoColumnChannel.AssertionPrivateKey:   assertionPrivateKey,
 	}
 	// build SQL query
-	query, args, err := r.pgsq.Insert(repoTableChannel).
+	query, args, sqlErr := r.pgsq.Insert(repoTableChannel).
 		SetMap(update).
 		Suffix(fmt.Sprintf("returning %s", repoColumnChannel.columns())).
 		ToSql()
-	if err != nil {
-		return nil, domain.NewInternalError("", err)
+	if sqlErr != nil {
+		return nil, domain.NewInternalError("", sqlErr)
 	}
 
 	// execute SQL query
 	row := repoChannel{}
-	if err = r.db.GetContext(ctx, &row, query, args...); err != nil {
-		return nil, domain.NewExternalError("", nil, err)
+	if sqlErr = r.db.GetContext(ctx, &row, query, args...); sqlErr != nil {
+		return nil, domain.NewExternalError("", nil, sqlErr)
 	}
 
 	// map the query result back to domain model
-	d := domain.Channel(row)
-	return &d, nil
+	return r.toDomainChannel(ctx, row)
 }
 
-func (r *PostgresRepository) GetChannelByID(ctx context.Context, channelID int) (*domain.Channel, domain.Error) {
+func (r *PostgresRepository) GetChannelByID(ctx context.Context, organizationID, channelID int) (*domain.Channel, domain.Error) {
 	query, args, err := r.pgsq.Select(repoColumnChannel.columns()).
 		From(repoTableChannel).
-		Where(sq.Eq{repoColumnChannel.ID: channelID}).
+		Where(sq.Eq{
+			repoColumnChannel.OrganizationID: organizationID,
+			repoColumnChannel.ID:             channelID,
+		}).
 		Limit(1).
 		ToSql()
 	if err != nil {
@@ -109,8 +227,27 @@ func (r *PostgresRepository) GetChannelByID(ctx context.Context, channelID int)
 	}
 
 	// map the query result back to domain model
-	channel := domain.Channel(row)
-	return &channel, nil
+	return r.toDomainChannel(ctx, row)
+}
+
+// GetChannelsByIDs returns channels of the given IDs regardless of their organization, which is meant
+// for background jobs. IDs of missing channels are ignored.
+func (r *PostgresRepository) GetChannelsByIDs(ctx context.Context, channelIDs []int) ([]domain.Channel, domain.Error) {
+	query, args, err := r.pgsq.Select(repoColumnChannel.columns()).
+		From(repoTableChannel).
+		Where(sq.Eq{repoColumnChannel.ID: channelIDs}).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	var rows []repoChannel
+	if err = r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	return r.toDomainChannels(ctx, rows)
 }
 
 func (r *PostgresRepository) GetChannelByExternalID(ctx context.Context, externalChannelID string) (*domain.Channel, domain.Error) {
@@ -133,6 +270,318 @@ func (r *PostgresRepository) GetChannelByExternalID(ctx context.Context, externa
 	}
 
 	// map the query result back to domain model
-	channel := domain.Channel(row)
-	return &channel, nil
+	return r.toDomainChannel(ctx, row)
+}
+
+func (r *PostgresRepository) ListChannels(ctx context.Context, organizationID int, pagination domain.Pagination) ([]domain.Channel, int, domain.Error) {
+	// count all channels of the organization for pagination
+	query, args, err := r.pgsq.Select("count(*)").
+		From(repoTableChannel).
+		Where(sq.Eq{repoColumnChannel.OrganizationID: organizationID}).
+		ToSql()
+	if err != nil {
+		return nil, 0, domain.NewInternalError("", err)
+	}
+
+	var total int
+	if err = r.db.GetContext(ctx, &total, query, args...); err != nil {
+		return nil, 0, domain.NewExternalError("", nil, err)
+	}
+
+	// get channels of the requested page
+	query, args, err = r.pgsq.Select(repoColumnChannel.columns()).
+		From(repoTableChannel).
+		Where(sq.Eq{repoColumnChannel.OrganizationID: organizationID}).
+		OrderBy(repoColumnChannel.ID).
+		Limit(uint64(pagination.Limit)).
+		Offset(uint64(pagination.Offset)).
+		ToSql()
+	if err != nil {
+		return nil, 0, domain.NewInternalError("", err)
+	}
+
+	var rows []repoChannel
+	if err = r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, 0, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	channels, mapErr := r.toDomainChannels(ctx, rows)
+	if mapErr != nil {
+		return nil, 0, mapErr
+	}
+	return channels, total, nil
+}
+
+func (r *PostgresRepository) UpdateChannel(ctx context.Context, organizationID, channelID int, params domain.UpdateChannelParams) (*domain.Channel, domain.Error) {
+	update := map[string]interface{}{
+		repoColumnChannel.UpdatedAt: time.Now(),
+	}
+	if params.Name != nil {
+		update[repoColumnChannel.Name] = *params.Name
+	}
+	if params.ExternalChannelSecret != nil {
+		secret, err := r.encryptor.encrypt(ctx, *params.ExternalChannelSecret)
+		if err != nil {
+			return nil, err
+		}
+		update[repoColumnChannel.ExternalChannelSecret] = secret
+	}
+	if params.AccessToken != nil {
+		accessToken, err := r.encryptor.encrypt(ctx, *params.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+		update[repoColumnChannel.AccessToken] = accessToken
+	}
+	if params.AccessTokenExpiredAt != nil {
+		update[repoColumnChannel.AccessTokenExpiredAt] = *params.AccessTokenExpiredAt
+	}
+	if params.AccessTokenVersion != nil {
+		update[repoColumnChannel.AccessTokenVersion] = string(*params.AccessTokenVersion)
+	}
+	if params.AccessTokenKeyID != nil {
+		update[repoColumnChannel.AccessTokenKeyID] = *params.AccessTokenKeyID
+	}
+	if params.AssertionKeyID != nil {
+		update[repoColumnChannel.AssertionKeyID] = *params.AssertionKeyID
+	}
+	if params.AssertionPrivateKey != nil {
+		assertionPrivateKey, err := r.encryptor.encrypt(ctx, *params.AssertionPrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		update[repoColumnChannel.AssertionPrivateKey] = assertionPrivateKey
+	}
+	if params.AccessTokenRefreshAttempts != nil {
+		update[repoColumnChannel.AccessTokenRefreshAttempts] = *params.AccessTokenRefreshAttempts
+	}
+	if params.AccessTokenRefreshAfter != nil {
+		update[repoColumnChannel.AccessTokenRefreshAfter] = *params.AccessTokenRefreshAfter
+	}
+	if params.HealthStatus != nil {
+		update[repoColumnChannel.HealthStatus] = string(*params.HealthStatus)
+	}
+	if params.HealthCheckedAt != nil {
+		update[repoColumnChannel.HealthCheckedAt] = *params.HealthCheckedAt
+	}
+	if params.HealthError != nil {
+		update[repoColumnChannel.HealthError] = *params.HealthError
+	}
+	if params.HealthCheckAfter != nil {
+		update[repoColumnChannel.HealthCheckAfter] = *params.HealthCheckAfter
+	}
+
+	// build SQL query
+	query, args, err := r.pgsq.Update(repoTableChannel).
+		SetMap(update).
+		Where(sq.Eq{
+			repoColumnChannel.OrganizationID: organizationID,
+			repoColumnChannel.ID:             channelID,
+		}).
+		Suffix(fmt.Sprintf("returning %s", repoColumnChannel.columns())).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	// execute SQL query
+	row := repoChannel{}
+	if err = r.db.GetContext(ctx, &row, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.NewResourceNotFoundError("channel is not found", err)
+		}
+		return nil, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	return r.toDomainChannel(ctx, row)
+}
+
+// ClaimChannelsForTokenRefresh claims channels whose access token expires before expireBefore.
+// Claimed channels are not claimable again until leaseUntil, and rows locked by other claimers are
+// skipped, so it's safe for several service instances to claim at the same time.
+func (r *PostgresRepository) ClaimChannelsForTokenRefresh(ctx context.Context, expireBefore, leaseUntil time.Time, limit int) ([]domain.Channel, domain.Error) {
+	var rows []repoChannel
+	err := r.claimRows(ctx, &rows, claimParams{
+		table:       repoTableChannel,
+		idColumn:    repoColumnChannel.ID,
+		leaseColumn: repoColumnChannel.AccessTokenRefreshAfter,
+		columns:     repoColumnChannel.columns(),
+		where:       []sq.Sqlizer{sq.Lt{repoColumnChannel.AccessTokenExpiredAt: expireBefore}},
+		orderBy:     []string{repoColumnChannel.AccessTokenExpiredAt},
+		leaseUntil:  leaseUntil,
+		limit:       limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// map the query result back to domain model
+	return r.toDomainChannels(ctx, rows)
+}
+
+// ClaimChannelsForHealthCheck claims channels which are due to be checked. Claimed channels are not
+// claimable again until leaseUntil, and rows locked by other claimers are skipped.
+func (r *PostgresRepository) ClaimChannelsForHealthCheck(ctx context.Context, leaseUntil time.Time, limit int) ([]domain.Channel, domain.Error) {
+	var rows []repoChannel
+	err := r.claimRows(ctx, &rows, claimParams{
+		table:       repoTableChannel,
+		idColumn:    repoColumnChannel.ID,
+		leaseColumn: repoColumnChannel.HealthCheckAfter,
+		columns:     repoColumnChannel.columns(),
+		orderBy:     []string{repoColumnChannel.HealthCheckAfter},
+		leaseUntil:  leaseUntil,
+		limit:       limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// map the query result back to domain model
+	return r.toDomainChannels(ctx, rows)
+}
+
+func (r *PostgresRepository) DeleteChannel(ctx context.Context, organizationID, channelID int) (err domain.Error) {
+	tx, err := r.beginTx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = r.finishTx(err, tx)
+	}()
+
+	err = r.deleteChannel(ctx, tx, organizationID, channelID)
+	return err
+}
+
+func (r *PostgresRepository) deleteChannel(ctx context.Context, db sqlContextGetter, organizationID, channelID int) domain.Error {
+	// Delete all slides belonging to the channel
+	query, args, err := r.pgsq.Delete(repoTableSlide).
+		Where(sq.Eq{
+			repoColumnSlide.OrganizationID: organizationID,
+			repoColumnSlide.ChannelID:      channelID,
+		}).
+		ToSql()
+	if err != nil {
+		return domain.NewInternalError("", err)
+	}
+	if _, err = db.ExecContext(ctx, query, args...); err != nil {
+		return domain.NewExternalError("", nil, err)
+	}
+
+	// Delete the channel itself
+	query, args, err = r.pgsq.Delete(repoTableChannel).
+		Where(sq.Eq{
+			repoColumnChannel.OrganizationID: organizationID,
+			repoColumnChannel.ID:             channelID,
+		}).
+		ToSql()
+	if err != nil {
+		return domain.NewInternalError("", err)
+	}
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return domain.NewExternalError("", nil, err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return domain.NewExternalError("", nil, err)
+	} else if affected == 0 {
+		return domain.NewResourceNotFoundError("channel is not found", sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+// ReencryptChannels encrypts the credentials of all channels with the current key provider. It
+// encrypts plaintext rows written before encryption was introduced, and re-wraps rows encrypted by
+// a previous key. It returns the number of re-encrypted channels.
+func (r *PostgresRepository) ReencryptChannels(ctx context.Context) (int, domain.Error) {
+	const batchSize = 100
+
+	count, lastID := 0, 0
+	for {
+		query, args, err := r.pgsq.Select(repoColumnChannel.ID).
+			From(repoTableChannel).
+			Where(sq.Gt{repoColumnChannel.ID: lastID}).
+			OrderBy(repoColumnChannel.ID).
+			Limit(batchSize).
+			ToSql()
+		if err != nil {
+			return count, domain.NewInternalError("", err)
+		}
+
+		var channelIDs []int
+		if err = r.db.SelectContext(ctx, &channelIDs, query, args...); err != nil {
+			return count, domain.NewExternalError("", nil, err)
+		}
+
+		for _, channelID := range channelIDs {
+			if err := r.reencryptChannel(ctx, channelID); err != nil {
+				return count, err
+			}
+			count++
+			lastID = channelID
+		}
+
+		if len(channelIDs) < batchSize {
+			return count, nil
+		}
+	}
+}
+
+func (r *PostgresRepository) reencryptChannel(ctx context.Context, channelID int) (err domain.Error) {
+	tx, err := r.beginTx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = r.finishTx(err, tx)
+	}()
+
+	// lock the row so concurrent token refreshes are not overwritten
+	query, args, sqlErr := r.pgsq.Select(repoColumnChannel.columns()).
+		From(repoTableChannel).
+		Where(sq.Eq{repoColumnChannel.ID: channelID}).
+		Suffix("for update").
+		ToSql()
+	if sqlErr != nil {
+		return domain.NewInternalError("", sqlErr)
+	}
+	row := repoChannel{}
+	if sqlErr = tx.GetContext(ctx, &row, query, args...); sqlErr != nil {
+		return domain.NewExternalError("", nil, sqlErr)
+	}
+
+	channel, err := r.toDomainChannel(ctx, row)
+	if err != nil {
+		return err
+	}
+	secret, err := r.encryptor.encrypt(ctx, channel.ExternalChannelSecret)
+	if err != nil {
+		return err
+	}
+	accessToken, err := r.encryptor.encrypt(ctx, channel.AccessToken)
+	if err != nil {
+		return err
+	}
+	assertionPrivateKey, err := r.encryptor.encrypt(ctx, channel.AssertionPrivateKey)
+	if err != nil {
+		return err
+	}
+
+	query, args, sqlErr = r.pgsq.Update(repoTableChannel).
+		Set(repoColumnChannel.ExternalChannelSecret, secret).
+		Set(repoColumnChannel.AccessToken, accessToken).
+		Set(repoColumnChannel.AssertionPrivateKey, assertionPrivateKey).
+		Where(sq.Eq{repoColumnChannel.ID: channelID}).
+		ToSql()
+	if sqlErr != nil {
+		return domain.NewInternalError("", sqlErr)
+	}
+	if _, sqlErr = tx.ExecContext(ctx, query, args...); sqlErr != nil {
+		return domain.NewExternalError("", nil, sqlErr)
+	}
+
+	return r.reencryptChannelAccessTokens(ctx, tx, channelID)
 }
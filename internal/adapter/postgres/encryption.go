@@ -0,0 +1,225 @@
+package postgres
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+// KeyProvider generates and unwraps the data keys used by envelope encryption. The plaintext data
+// key encrypts the value, and only the encrypted data key is stored along with the ciphertext.
+//
+//go:generate mockgen -destination automock/key_provider.go -package=automock . KeyProvider
+type KeyProvider interface {
+	// GenerateDataKey returns a new 256-bit data key in both plaintext and encrypted form
+	GenerateDataKey(ctx context.Context) (plaintext []byte, encrypted []byte, err domain.Error)
+	// DecryptDataKey returns the plaintext of an encrypted data key
+	DecryptDataKey(ctx context.Context, encrypted []byte) ([]byte, domain.Error)
+}
+
+const (
+	// encryptedValuePrefix marks a column value as envelope encrypted. Values without the prefix are
+	// plaintext written before encryption was introduced.
+	encryptedValuePrefix = "enc:v1:"
+	// maxCachedDataKeys bounds the number of decrypted data keys kept in memory
+	maxCachedDataKeys = 1024
+	// dataKeyMaxAge and dataKeyMaxUses bound how long and how many values a generated data key
+	// encrypts before another one is generated
+	dataKeyMaxAge  = 5 * time.Minute
+	dataKeyMaxUses = 10000
+)
+
+var errEncryptionNotConfigured = errors.New("encryption key provider is not configured")
+
+// envelopeEncryptor encrypts column values with AES-256-GCM. A generated data key is reused for a
+// while, so neither encrypting nor decrypting a batch of values calls the key provider for each of
+// them. The stored format is "enc:v1:<base64 encrypted data key>:<base64 nonce and ciphertext>".
+type envelopeEncryptor struct {
+	keyProvider KeyProvider
+
+	// dataKeys caches decrypted data keys by their encrypted form to avoid unwrapping them on every read
+	mutex    sync.RWMutex
+	dataKeys map[string][]byte
+
+	// encryptionKey is the data key encrypting values until it's too old or too used
+	encryptionMutex sync.Mutex
+	encryptionKey   encryptionDataKey
+}
+
+type encryptionDataKey struct {
+	plaintext []byte
+	encrypted []byte
+	createdAt time.Time
+	uses      int
+}
+
+func newEnvelopeEncryptor(keyProvider KeyProvider) *envelopeEncryptor {
+	return &envelopeEncryptor{
+		keyProvider: keyProvider,
+		dataKeys:    make(map[string][]byte),
+	}
+}
+
+func (e *envelopeEncryptor) encrypt(ctx context.Context, plaintext string) (string, domain.Error) {
+	if plaintext == "" {
+		// nothing to protect, e.g., the assertion key of a channel without v2.1 access tokens
+		return "", nil
+	}
+	if e.keyProvider == nil {
+		return "", domain.NewInternalError("", errEncryptionNotConfigured)
+	}
+
+	dataKey, encryptedDataKey, err := e.encryptionDataKey(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := sealAESGCM(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+
+	return encryptedValuePrefix +
+		base64.StdEncoding.EncodeToString(encryptedDataKey) + ":" +
+		base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (e *envelopeEncryptor) decrypt(ctx context.Context, value string) (string, domain.Error) {
+	if !isEncryptedValue(value) {
+		// plaintext rows are returned as they are until they get re-encrypted
+		return value, nil
+	}
+	if e.keyProvider == nil {
+		return "", domain.NewInternalError("", errEncryptionNotConfigured)
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(value, encryptedValuePrefix), ":", 2)
+	if len(parts) != 2 {
+		return "", domain.NewInternalError("", errors.New("malformed encrypted value"))
+	}
+	encryptedDataKey, decodeErr := base64.StdEncoding.DecodeString(parts[0])
+	if decodeErr != nil {
+		return "", domain.NewInternalError("", decodeErr)
+	}
+	sealed, decodeErr := base64.StdEncoding.DecodeString(parts[1])
+	if decodeErr != nil {
+		return "", domain.NewInternalError("", decodeErr)
+	}
+
+	dataKey, err := e.dataKey(ctx, encryptedDataKey)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := openAESGCM(dataKey, sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// encryptionDataKey returns the data key to encrypt a value with, which is generated again once the
+// current one is too old or too used
+func (e *envelopeEncryptor) encryptionDataKey(ctx context.Context) ([]byte, []byte, domain.Error) {
+	e.encryptionMutex.Lock()
+	defer e.encryptionMutex.Unlock()
+
+	key := &e.encryptionKey
+	if key.plaintext == nil || key.uses >= dataKeyMaxUses || time.Since(key.createdAt) >= dataKeyMaxAge {
+		plaintext, encrypted, err := e.keyProvider.GenerateDataKey(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		*key = encryptionDataKey{
+			plaintext: plaintext,
+			encrypted: encrypted,
+			createdAt: time.Now(),
+		}
+		// values encrypted here are decrypted without unwrapping the key
+		e.cacheDataKey(encrypted, plaintext)
+	}
+
+	key.uses++
+	return key.plaintext, key.encrypted, nil
+}
+
+func (e *envelopeEncryptor) dataKey(ctx context.Context, encryptedDataKey []byte) ([]byte, domain.Error) {
+	cacheKey := string(encryptedDataKey)
+
+	e.mutex.RLock()
+	dataKey, ok := e.dataKeys[cacheKey]
+	e.mutex.RUnlock()
+	if ok {
+		return dataKey, nil
+	}
+
+	dataKey, err := e.keyProvider.DecryptDataKey(ctx, encryptedDataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	e.cacheDataKey(encryptedDataKey, dataKey)
+	return dataKey, nil
+}
+
+func (e *envelopeEncryptor) cacheDataKey(encryptedDataKey, dataKey []byte) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if len(e.dataKeys) >= maxCachedDataKeys {
+		e.dataKeys = make(map[string][]byte)
+	}
+	e.dataKeys[string(encryptedDataKey)] = dataKey
+}
+
+func isEncryptedValue(value string) bool {
+	return strings.HasPrefix(value, encryptedValuePrefix)
+}
+
+// sealAESGCM encrypts plaintext with AES-GCM and prepends the random nonce to the ciphertext
+func sealAESGCM(key, plaintext []byte) ([]byte, domain.Error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// openAESGCM decrypts the output of sealAESGCM
+func openAESGCM(key, sealed []byte) ([]byte, domain.Error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, domain.NewInternalError("", errors.New("malformed ciphertext"))
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+	return plaintext, nil
+}
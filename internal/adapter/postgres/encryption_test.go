@@ -0,0 +1,216 @@
+package postgres
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+func newTestKeyProvider(t *testing.T) *StaticKeyProvider {
+	t.Helper()
+
+	masterKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(masterKey); err != nil {
+		t.Fatal(err)
+	}
+	provider, err := NewStaticKeyProvider(context.Background(), base64.StdEncoding.EncodeToString(masterKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func TestEnvelopeEncryptor_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	encryptor := newEnvelopeEncryptor(newTestKeyProvider(t))
+
+	tests := []struct {
+		name      string
+		plaintext string
+	}{
+		{name: "channel secret", plaintext: "0123456789abcdef0123456789abcdef"},
+		{name: "unicode", plaintext: "頻道密鑰 🔑"},
+		{name: "long value", plaintext: strings.Repeat("x", 4096)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encrypted, err := encryptor.encrypt(ctx, tt.plaintext)
+			if err != nil {
+				t.Fatalf("encrypt() error = %v", err)
+			}
+			if !isEncryptedValue(encrypted) {
+				t.Fatalf("encrypt() = %q, want the %q prefix", encrypted, encryptedValuePrefix)
+			}
+			if strings.Contains(encrypted, tt.plaintext) {
+				t.Fatalf("encrypt() leaks the plaintext")
+			}
+
+			decrypted, err := encryptor.decrypt(ctx, encrypted)
+			if err != nil {
+				t.Fatalf("decrypt() error = %v", err)
+			}
+			if decrypted != tt.plaintext {
+				t.Fatalf("decrypt() = %q, want %q", decrypted, tt.plaintext)
+			}
+		})
+	}
+}
+
+func TestEnvelopeEncryptor_FreshNoncePerValue(t *testing.T) {
+	ctx := context.Background()
+	encryptor := newEnvelopeEncryptor(newTestKeyProvider(t))
+
+	first, err := encryptor.encrypt(ctx, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := encryptor.encrypt(ctx, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Fatalf("encrypt() returns the same ciphertext twice")
+	}
+}
+
+// countingKeyProvider counts the calls to the key provider it wraps
+type countingKeyProvider struct {
+	KeyProvider
+	generated int
+	decrypted int
+}
+
+func (p *countingKeyProvider) GenerateDataKey(ctx context.Context) ([]byte, []byte, domain.Error) {
+	p.generated++
+	return p.KeyProvider.GenerateDataKey(ctx)
+}
+
+func (p *countingKeyProvider) DecryptDataKey(ctx context.Context, encrypted []byte) ([]byte, domain.Error) {
+	p.decrypted++
+	return p.KeyProvider.DecryptDataKey(ctx, encrypted)
+}
+
+func TestEnvelopeEncryptor_ReuseDataKey(t *testing.T) {
+	ctx := context.Background()
+	provider := &countingKeyProvider{KeyProvider: newTestKeyProvider(t)}
+	encryptor := newEnvelopeEncryptor(provider)
+
+	var values []string
+	for i := 0; i < 10; i++ {
+		encrypted, err := encryptor.encrypt(ctx, "secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, encrypted)
+	}
+	if provider.generated != 1 {
+		t.Fatalf("GenerateDataKey() is called %d times, want 1", provider.generated)
+	}
+
+	// values encrypted by the same instance don't need the data key unwrapped
+	for _, value := range values {
+		if _, err := encryptor.decrypt(ctx, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if provider.decrypted != 0 {
+		t.Fatalf("DecryptDataKey() is called %d times, want 0", provider.decrypted)
+	}
+
+	// another instance unwraps the shared data key once
+	reader := newEnvelopeEncryptor(provider)
+	for _, value := range values {
+		if _, err := reader.decrypt(ctx, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if provider.decrypted != 1 {
+		t.Fatalf("DecryptDataKey() is called %d times, want 1", provider.decrypted)
+	}
+
+	// a data key too old is replaced
+	encryptor.encryptionKey.createdAt = time.Now().Add(-dataKeyMaxAge)
+	if _, err := encryptor.encrypt(ctx, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if provider.generated != 2 {
+		t.Fatalf("GenerateDataKey() is called %d times, want 2", provider.generated)
+	}
+}
+
+func TestEnvelopeEncryptor_Decrypt(t *testing.T) {
+	ctx := context.Background()
+	encryptor := newEnvelopeEncryptor(newTestKeyProvider(t))
+
+	encrypted, err := encryptor.encrypt(ctx, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.SplitN(strings.TrimPrefix(encrypted, encryptedValuePrefix), ":", 2)
+	sealed, _ := base64.StdEncoding.DecodeString(parts[1])
+	sealed[len(sealed)-1] ^= 0xff
+	tampered := encryptedValuePrefix + parts[0] + ":" + base64.StdEncoding.EncodeToString(sealed)
+
+	tests := []struct {
+		name      string
+		encryptor *envelopeEncryptor
+		value     string
+		want      string
+		wantErr   bool
+	}{
+		{name: "legacy plaintext passes through", encryptor: encryptor, value: "plaintext-secret", want: "plaintext-secret"},
+		{name: "empty value passes through", encryptor: encryptor, value: "", want: ""},
+		{name: "legacy plaintext without key provider", encryptor: newEnvelopeEncryptor(nil), value: "plaintext-secret", want: "plaintext-secret"},
+		{name: "wrong master key", encryptor: newEnvelopeEncryptor(newTestKeyProvider(t)), value: encrypted, wantErr: true},
+		{name: "tampered ciphertext", encryptor: newEnvelopeEncryptor(encryptor.keyProvider), value: tampered, wantErr: true},
+		{name: "malformed value", encryptor: encryptor, value: encryptedValuePrefix + "no-separator", wantErr: true},
+		{name: "invalid base64", encryptor: encryptor, value: encryptedValuePrefix + "!!!:!!!", wantErr: true},
+		{name: "encrypted value without key provider", encryptor: newEnvelopeEncryptor(nil), value: encrypted, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.encryptor.decrypt(ctx, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decrypt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("decrypt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvelopeEncryptor_EncryptEmptyValue(t *testing.T) {
+	encrypted, err := newEnvelopeEncryptor(nil).encrypt(context.Background(), "")
+	if err != nil {
+		t.Fatalf("encrypt() error = %v", err)
+	}
+	if encrypted != "" {
+		t.Fatalf("encrypt() = %q, want empty", encrypted)
+	}
+}
+
+func TestNewStaticKeyProvider(t *testing.T) {
+	tests := []struct {
+		name      string
+		masterKey string
+		wantErr   bool
+	}{
+		{name: "256-bit key", masterKey: base64.StdEncoding.EncodeToString(make([]byte, 32))},
+		{name: "128-bit key", masterKey: base64.StdEncoding.EncodeToString(make([]byte, 16)), wantErr: true},
+		{name: "invalid base64", masterKey: "not base64!", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewStaticKeyProvider(context.Background(), tt.masterKey)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewStaticKeyProvider() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+type repoInboundMessage struct {
+	ID                int64     `db:"id"`
+	ChannelID         int       `db:"channel_id"`
+	ExternalMessageID string    `db:"external_message_id"`
+	ExternalMemberID  string    `db:"external_member_id"`
+	SourceType        string    `db:"source_type"`
+	ExternalGroupID   string    `db:"external_group_id"`
+	MessageType       string    `db:"message_type"`
+	Text              string    `db:"text"`
+	Raw               []byte    `db:"raw"`
+	SentAt            time.Time `db:"sent_at"`
+	CreatedAt         time.Time `db:"created_at"`
+}
+
+type repoColumnPatternInboundMessage struct {
+	ID                string
+	ChannelID         string
+	ExternalMessageID string
+	ExternalMemberID  string
+	SourceType        string
+	ExternalGroupID   string
+	MessageType       string
+	Text              string
+	Raw               string
+	SentAt            string
+	CreatedAt         string
+}
+
+const repoTableInboundMessage = "inbound_message"
+
+var repoColumnInboundMessage = repoColumnPatternInboundMessage{
+	ID:                "id",
+	ChannelID:         "channel_id",
+	ExternalMessageID: "external_message_id",
+	ExternalMemberID:  "external_member_id",
+	SourceType:        "source_type",
+	ExternalGroupID:   "external_group_id",
+	MessageType:       "message_type",
+	Text:              "text",
+	Raw:               "raw",
+	SentAt:            "sent_at",
+	CreatedAt:         "created_at",
+}
+
+// likeEscaper escapes wildcards of LIKE patterns, so they are matched literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (c *repoColumnPatternInboundMessage) columns() string {
+	return strings.Join([]string{
+		c.ID,
+		c.ChannelID,
+		c.ExternalMessageID,
+		c.ExternalMemberID,
+		c.SourceType,
+		c.ExternalGroupID,
+		c.MessageType,
+		c.Text,
+		c.Raw,
+		c.SentAt,
+		c.CreatedAt,
+	}, ", ")
+}
+
+func (row repoInboundMessage) toDomain() domain.InboundMessage {
+	return domain.InboundMessage{
+		ID:                row.ID,
+		ChannelID:         row.ChannelID,
+		ExternalMessageID: row.ExternalMessageID,
+		ExternalMemberID:  row.ExternalMemberID,
+		SourceType:        domain.LineSourceType(row.SourceType),
+		ExternalGroupID:   row.ExternalGroupID,
+		MessageType:       row.MessageType,
+		Text:              row.Text,
+		Raw:               row.Raw,
+		SentAt:            row.SentAt,
+		CreatedAt:         row.CreatedAt,
+	}
+}
+
+// CreateInboundMessage stores the message. A message stored before is ignored, so redelivered events
+// don't duplicate it.
+func (r *PostgresRepository) CreateInboundMessage(ctx context.Context, message domain.InboundMessage) domain.Error {
+	raw := []byte(message.Raw)
+	if len(raw) == 0 {
+		raw = []byte("{}")
+	}
+
+	query, args, err := r.pgsq.Insert(repoTableInboundMessage).
+		SetMap(map[string]interface{}{
+			repoColumnInboundMessage.ChannelID:         message.ChannelID,
+			repoColumnInboundMessage.ExternalMessageID: message.ExternalMessageID,
+			repoColumnInboundMessage.ExternalMemberID:  message.ExternalMemberID,
+			repoColumnInboundMessage.SourceType:        message.SourceType,
+			repoColumnInboundMessage.ExternalGroupID:   message.ExternalGroupID,
+			repoColumnInboundMessage.MessageType:       message.MessageType,
+			repoColumnInboundMessage.Text:              message.Text,
+			repoColumnInboundMessage.Raw:               string(raw),
+			repoColumnInboundMessage.SentAt:            message.SentAt,
+		}).
+		Suffix(fmt.Sprintf("on conflict (%s, %s) do nothing",
+			repoColumnInboundMessage.ChannelID, repoColumnInboundMessage.ExternalMessageID)).
+		ToSql()
+	if err != nil {
+		return domain.NewInternalError("", err)
+	}
+	if _, err = r.db.ExecContext(ctx, query, args...); err != nil {
+		return domain.NewExternalError("", nil, err)
+	}
+	return nil
+}
+
+// ListInboundMessages returns messages of the channel matching the filter in the given page, latest
+// first, and the total number of them.
+func (r *PostgresRepository) ListInboundMessages(ctx context.Context, channelID int, filter domain.InboundMessageFilter, pagination domain.Pagination) ([]domain.InboundMessage, int, domain.Error) {
+	where := sq.And{sq.Eq{repoColumnInboundMessage.ChannelID: channelID}}
+	if filter.ExternalMemberID != "" {
+		where = append(where, sq.Eq{repoColumnInboundMessage.ExternalMemberID: filter.ExternalMemberID})
+	}
+	if filter.SentFrom != nil {
+		where = append(where, sq.GtOrEq{repoColumnInboundMessage.SentAt: *filter.SentFrom})
+	}
+	if filter.SentTo != nil {
+		where = append(where, sq.Lt{repoColumnInboundMessage.SentAt: *filter.SentTo})
+	}
+	// every term must be in the text, which is served by the trigram index
+	for _, term := range strings.Fields(filter.Query) {
+		where = append(where, sq.ILike{repoColumnInboundMessage.Text: "%" + likeEscaper.Replace(term) + "%"})
+	}
+
+	// count all matched messages for pagination
+	query, args, err := r.pgsq.Select("count(*)").
+		From(repoTableInboundMessage).
+		Where(where).
+		ToSql()
+	if err != nil {
+		return nil, 0, domain.NewInternalError("", err)
+	}
+
+	var total int
+	if err = r.db.GetContext(ctx, &total, query, args...); err != nil {
+		return nil, 0, domain.NewExternalError("", nil, err)
+	}
+
+	// get messages of the requested page
+	query, args, err = r.pgsq.Select(repoColumnInboundMessage.columns()).
+		From(repoTableInboundMessage).
+		Where(where).
+		OrderBy(fmt.Sprintf("%s desc", repoColumnInboundMessage.SentAt), fmt.Sprintf("%s desc", repoColumnInboundMessage.ID)).
+		Limit(uint64(pagination.Limit)).
+		Offset(uint64(pagination.Offset)).
+		ToSql()
+	if err != nil {
+		return nil, 0, domain.NewInternalError("", err)
+	}
+
+	var rows []repoInboundMessage
+	if err = r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, 0, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	messages := make([]domain.InboundMessage, 0, len(rows))
+	for _, row := range rows {
+		messages = append(messages, row.toDomain())
+	}
+	return messages, total, nil
+}
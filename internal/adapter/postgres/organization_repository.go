@@ -0,0 +1,172 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+type repoOrganization struct {
+	ID        int       `db:"id"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+type repoColumnPatternOrganization struct {
+	ID        string
+	Name      string
+	CreatedAt string
+	UpdatedAt string
+}
+
+const repoTableOrganization = "organization"
+
+var repoColumnOrganization = repoColumnPatternOrganization{
+	ID:        "id",
+	Name:      "name",
+	CreatedAt: "created_at",
+	UpdatedAt: "updated_at",
+}
+
+func (c *repoColumnPatternOrganization) columns() string {
+	return strings.Join([]string{
+		c.ID,
+		c.Name,
+		c.CreatedAt,
+		c.UpdatedAt,
+	}, ", ")
+}
+
+func (row repoOrganization) toDomain() domain.Organization {
+	return domain.Organization{
+		ID:        row.ID,
+		Name:      row.Name,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+	}
+}
+
+func (r *PostgresRepository) CreateOrganization(ctx context.Context, organization domain.Organization) (*domain.Organization, domain.Error) {
+	// build SQL query
+	query, args, err := r.pgsq.Insert(repoTableOrganization).
+		SetMap(map[string]interface{}{
+			repoColumnOrganization.Name: organization.Name,
+		}).
+		Suffix(fmt.Sprintf("returning %s", repoColumnOrganization.columns())).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	// execute SQL query
+	row := repoOrganization{}
+	if err = r.db.GetContext(ctx, &row, query, args...); err != nil {
+		return nil, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	created := row.toDomain()
+	return &created, nil
+}
+
+func (r *PostgresRepository) GetOrganizationByID(ctx context.Context, organizationID int) (*domain.Organization, domain.Error) {
+	query, args, err := r.pgsq.Select(repoColumnOrganization.columns()).
+		From(repoTableOrganization).
+		Where(sq.Eq{repoColumnOrganization.ID: organizationID}).
+		Limit(1).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	// get one row from result
+	row := repoOrganization{}
+	if err = r.db.GetContext(ctx, &row, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.NewResourceNotFoundError("organization is not found", err)
+		}
+		return nil, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	organization := row.toDomain()
+	return &organization, nil
+}
+
+func (r *PostgresRepository) ListOrganizations(ctx context.Context, pagination domain.Pagination) ([]domain.Organization, int, domain.Error) {
+	// count all organizations for pagination
+	query, args, err := r.pgsq.Select("count(*)").
+		From(repoTableOrganization).
+		ToSql()
+	if err != nil {
+		return nil, 0, domain.NewInternalError("", err)
+	}
+
+	var total int
+	if err = r.db.GetContext(ctx, &total, query, args...); err != nil {
+		return nil, 0, domain.NewExternalError("", nil, err)
+	}
+
+	// get organizations of the requested page
+	query, args, err = r.pgsq.Select(repoColumnOrganization.columns()).
+		From(repoTableOrganization).
+		OrderBy(repoColumnOrganization.ID).
+		Limit(uint64(pagination.Limit)).
+		Offset(uint64(pagination.Offset)).
+		ToSql()
+	if err != nil {
+		return nil, 0, domain.NewInternalError("", err)
+	}
+
+	var rows []repoOrganization
+	if err = r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, 0, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	organizations := make([]domain.Organization, 0, len(rows))
+	for _, row := range rows {
+		organizations = append(organizations, row.toDomain())
+	}
+	return organizations, total, nil
+}
+
+func (r *PostgresRepository) UpdateOrganization(ctx context.Context, organizationID int, params domain.UpdateOrganizationParams) (*domain.Organization, domain.Error) {
+	update := map[string]interface{}{
+		repoColumnOrganization.UpdatedAt: time.Now(),
+	}
+	if params.Name != nil {
+		update[repoColumnOrganization.Name] = *params.Name
+	}
+
+	// build SQL query
+	query, args, err := r.pgsq.Update(repoTableOrganization).
+		SetMap(update).
+		Where(sq.Eq{repoColumnOrganization.ID: organizationID}).
+		Suffix(fmt.Sprintf("returning %s", repoColumnOrganization.columns())).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	// execute SQL query
+	row := repoOrganization{}
+	if err = r.db.GetContext(ctx, &row, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.NewResourceNotFoundError("organization is not found", err)
+		}
+		return nil, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	organization := row.toDomain()
+	return &organization, nil
+}
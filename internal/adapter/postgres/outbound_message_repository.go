@@ -0,0 +1,353 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+type repoOutboundMessage struct {
+	ID            int64     `db:"id"`
+	ChannelID     int       `db:"channel_id"`
+	DeliveryID    string    `db:"delivery_id"`
+	Recipient     string    `db:"recipient"`
+	Mode          string    `db:"mode"`
+	Payload       []byte    `db:"payload"`
+	LineRequestID string    `db:"line_request_id"`
+	HTTPStatus    int       `db:"http_status"`
+	Status        string    `db:"status"`
+	Error         string    `db:"error"`
+	CreatedAt     time.Time `db:"created_at"`
+	// RetryClaimedAt is set while the attempt is being retried
+	RetryClaimedAt *time.Time `db:"retry_claimed_at"`
+}
+
+type repoColumnPatternOutboundMessage struct {
+	ID             string
+	ChannelID      string
+	DeliveryID     string
+	Recipient      string
+	Mode           string
+	Payload        string
+	LineRequestID  string
+	HTTPStatus     string
+	Status         string
+	Error          string
+	CreatedAt      string
+	RetryClaimedAt string
+}
+
+const repoTableOutboundMessage = "outbound_message"
+
+var repoColumnOutboundMessage = repoColumnPatternOutboundMessage{
+	ID:             "id",
+	ChannelID:      "channel_id",
+	DeliveryID:     "delivery_id",
+	Recipient:      "recipient",
+	Mode:           "mode",
+	Payload:        "payload",
+	LineRequestID:  "line_request_id",
+	HTTPStatus:     "http_status",
+	Status:         "status",
+	Error:          "error",
+	CreatedAt:      "created_at",
+	RetryClaimedAt: "retry_claimed_at",
+}
+
+func (c *repoColumnPatternOutboundMessage) columns() string {
+	return strings.Join([]string{
+		c.ID,
+		c.ChannelID,
+		c.DeliveryID,
+		c.Recipient,
+		c.Mode,
+		c.Payload,
+		c.LineRequestID,
+		c.HTTPStatus,
+		c.Status,
+		c.Error,
+		c.CreatedAt,
+		c.RetryClaimedAt,
+	}, ", ")
+}
+
+func (row repoOutboundMessage) toDomain() domain.OutboundMessage {
+	return domain.OutboundMessage{
+		ID:            row.ID,
+		ChannelID:     row.ChannelID,
+		DeliveryID:    row.DeliveryID,
+		Recipient:     row.Recipient,
+		Mode:          domain.LineSendMode(row.Mode),
+		Payload:       row.Payload,
+		LineRequestID: row.LineRequestID,
+		HTTPStatus:    row.HTTPStatus,
+		Status:        domain.OutboundMessageStatus(row.Status),
+		Error:         row.Error,
+		CreatedAt:     row.CreatedAt,
+	}
+}
+
+// CreateOutboundMessages stores the attempts in one statement and returns them in the same order
+func (r *PostgresRepository) CreateOutboundMessages(ctx context.Context, messages []domain.OutboundMessage) ([]domain.OutboundMessage, domain.Error) {
+	return r.createOutboundMessages(ctx, r.db, messages)
+}
+
+func (r *PostgresRepository) createOutboundMessages(ctx context.Context, db sqlContextGetter, messages []domain.OutboundMessage) ([]domain.OutboundMessage, domain.Error) {
+	if len(messages) == 0 {
+		return nil, nil
+	}
+
+	c := repoColumnOutboundMessage
+	insert := r.pgsq.Insert(repoTableOutboundMessage).
+		Columns(c.ChannelID, c.DeliveryID, c.Recipient, c.Mode, c.Payload, c.LineRequestID, c.HTTPStatus, c.Status, c.Error)
+	for _, m := range messages {
+		payload := []byte(m.Payload)
+		if len(payload) == 0 {
+			payload = []byte("[]")
+		}
+		insert = insert.Values(m.ChannelID, m.DeliveryID, m.Recipient, m.Mode, string(payload), m.LineRequestID, m.HTTPStatus, m.Status, m.Error)
+	}
+
+	query, args, err := insert.Suffix(fmt.Sprintf("returning %s", c.columns())).ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	var rows []repoOutboundMessage
+	if err = db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	created := make([]domain.OutboundMessage, 0, len(rows))
+	for _, row := range rows {
+		created = append(created, row.toDomain())
+	}
+	return created, nil
+}
+
+func (r *PostgresRepository) GetOutboundMessage(ctx context.Context, channelID int, messageID int64) (*domain.OutboundMessage, domain.Error) {
+	query, args, err := r.pgsq.Select(repoColumnOutboundMessage.columns()).
+		From(repoTableOutboundMessage).
+		Where(sq.Eq{
+			repoColumnOutboundMessage.ChannelID: channelID,
+			repoColumnOutboundMessage.ID:        messageID,
+		}).
+		Limit(1).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	// get one row from result
+	row := repoOutboundMessage{}
+	if err = r.db.GetContext(ctx, &row, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.NewResourceNotFoundError("outbound message is not found", err)
+		}
+		return nil, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	message := row.toDomain()
+	return &message, nil
+}
+
+// ClaimOutboundMessageForRetry marks the failed attempt as being retried and returns it. Attempts of
+// the delivery are locked first, so concurrent retries of one delivery are serialized, and the claim
+// fails with a ParameterError once any attempt of the delivery has succeeded or is being retried.
+// Claims made before staleBefore are left by retries which never finished, and are taken over.
+func (r *PostgresRepository) ClaimOutboundMessageForRetry(ctx context.Context, channelID int, messageID int64, staleBefore time.Time) (message *domain.OutboundMessage, err domain.Error) {
+	tx, err := r.beginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = r.finishTx(err, tx)
+	}()
+
+	c := repoColumnOutboundMessage
+	query, args, sqlErr := r.pgsq.Select(c.DeliveryID).
+		From(repoTableOutboundMessage).
+		Where(sq.Eq{c.ChannelID: channelID, c.ID: messageID}).
+		ToSql()
+	if sqlErr != nil {
+		return nil, domain.NewInternalError("", sqlErr)
+	}
+	var deliveryID string
+	if sqlErr = tx.GetContext(ctx, &deliveryID, query, args...); sqlErr != nil {
+		if errors.Is(sqlErr, sql.ErrNoRows) {
+			return nil, domain.NewResourceNotFoundError("outbound message is not found", sqlErr)
+		}
+		return nil, domain.NewExternalError("", nil, sqlErr)
+	}
+
+	// lock attempts of the delivery, which a finishing retry updates as well
+	query, args, sqlErr = r.pgsq.Select(c.ID).
+		From(repoTableOutboundMessage).
+		Where(sq.Eq{c.DeliveryID: deliveryID}).
+		Suffix("for update").
+		ToSql()
+	if sqlErr != nil {
+		return nil, domain.NewInternalError("", sqlErr)
+	}
+	var ids []int64
+	if sqlErr = tx.SelectContext(ctx, &ids, query, args...); sqlErr != nil {
+		return nil, domain.NewExternalError("", nil, sqlErr)
+	}
+
+	// read the attempts again in a new statement, which sees the ones committed while waiting for the
+	// lock, e.g. the attempt of a retry finished meanwhile
+	query, args, sqlErr = r.pgsq.Select(c.columns()).
+		From(repoTableOutboundMessage).
+		Where(sq.Eq{c.DeliveryID: deliveryID}).
+		ToSql()
+	if sqlErr != nil {
+		return nil, domain.NewInternalError("", sqlErr)
+	}
+	var rows []repoOutboundMessage
+	if sqlErr = tx.SelectContext(ctx, &rows, query, args...); sqlErr != nil {
+		return nil, domain.NewExternalError("", nil, sqlErr)
+	}
+
+	var claimed *repoOutboundMessage
+	for i, row := range rows {
+		switch {
+		case row.Status == string(domain.OutboundMessageStatusSucceeded):
+			msg := "messages have been delivered by another attempt"
+			return nil, domain.NewParameterError(msg, errors.New(msg))
+		case row.Status == string(domain.OutboundMessageStatusRetrying) && row.RetryClaimedAt != nil && row.RetryClaimedAt.After(staleBefore):
+			msg := "messages are being retried"
+			return nil, domain.NewParameterError(msg, errors.New(msg))
+		}
+		if row.ID == messageID {
+			claimed = &rows[i]
+		}
+	}
+	if claimed == nil || claimed.Status == string(domain.OutboundMessageStatusSucceeded) {
+		msg := "only failed outbound messages could be retried"
+		return nil, domain.NewParameterError(msg, errors.New(msg))
+	}
+
+	// release stale claims on other attempts, and claim the requested one
+	query, args, sqlErr = r.pgsq.Update(repoTableOutboundMessage).
+		Set(c.Status, domain.OutboundMessageStatusFailed).
+		Set(c.RetryClaimedAt, nil).
+		Where(sq.Eq{c.DeliveryID: deliveryID, c.Status: domain.OutboundMessageStatusRetrying}).
+		ToSql()
+	if sqlErr != nil {
+		return nil, domain.NewInternalError("", sqlErr)
+	}
+	if _, sqlErr = tx.ExecContext(ctx, query, args...); sqlErr != nil {
+		return nil, domain.NewExternalError("", nil, sqlErr)
+	}
+
+	query, args, sqlErr = r.pgsq.Update(repoTableOutboundMessage).
+		Set(c.Status, domain.OutboundMessageStatusRetrying).
+		Set(c.RetryClaimedAt, sq.Expr("now()")).
+		Where(sq.Eq{c.ID: messageID}).
+		Suffix(fmt.Sprintf("returning %s", c.columns())).
+		ToSql()
+	if sqlErr != nil {
+		return nil, domain.NewInternalError("", sqlErr)
+	}
+	row := repoOutboundMessage{}
+	if sqlErr = tx.GetContext(ctx, &row, query, args...); sqlErr != nil {
+		return nil, domain.NewExternalError("", nil, sqlErr)
+	}
+
+	// map the query result back to domain model
+	m := row.toDomain()
+	return &m, nil
+}
+
+// FinishOutboundMessageRetry stores the attempt of the retry and releases the claim on the retried
+// attempt in one transaction, so a retry claimed afterwards sees whether this one has succeeded
+func (r *PostgresRepository) FinishOutboundMessageRetry(ctx context.Context, messageID int64, attempt domain.OutboundMessage) (message *domain.OutboundMessage, err domain.Error) {
+	tx, err := r.beginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = r.finishTx(err, tx)
+	}()
+
+	c := repoColumnOutboundMessage
+	query, args, sqlErr := r.pgsq.Update(repoTableOutboundMessage).
+		Set(c.Status, domain.OutboundMessageStatusFailed).
+		Set(c.RetryClaimedAt, nil).
+		Where(sq.Eq{c.ID: messageID, c.Status: domain.OutboundMessageStatusRetrying}).
+		ToSql()
+	if sqlErr != nil {
+		return nil, domain.NewInternalError("", sqlErr)
+	}
+	if _, sqlErr = tx.ExecContext(ctx, query, args...); sqlErr != nil {
+		return nil, domain.NewExternalError("", nil, sqlErr)
+	}
+
+	created, err := r.createOutboundMessages(ctx, tx, []domain.OutboundMessage{attempt})
+	if err != nil {
+		return nil, err
+	}
+	return &created[0], nil
+}
+
+// ListOutboundMessages returns attempts of the channel matching the filter in the given page, latest
+// first, and the total number of them.
+func (r *PostgresRepository) ListOutboundMessages(ctx context.Context, channelID int, filter domain.OutboundMessageFilter, pagination domain.Pagination) ([]domain.OutboundMessage, int, domain.Error) {
+	where := sq.Eq{repoColumnOutboundMessage.ChannelID: channelID}
+	if filter.Recipient != "" {
+		where[repoColumnOutboundMessage.Recipient] = filter.Recipient
+	}
+	if filter.DeliveryID != "" {
+		where[repoColumnOutboundMessage.DeliveryID] = filter.DeliveryID
+	}
+	if filter.Status != "" {
+		where[repoColumnOutboundMessage.Status] = filter.Status
+	}
+
+	// count all matched attempts for pagination
+	query, args, err := r.pgsq.Select("count(*)").
+		From(repoTableOutboundMessage).
+		Where(where).
+		ToSql()
+	if err != nil {
+		return nil, 0, domain.NewInternalError("", err)
+	}
+
+	var total int
+	if err = r.db.GetContext(ctx, &total, query, args...); err != nil {
+		return nil, 0, domain.NewExternalError("", nil, err)
+	}
+
+	// get attempts of the requested page
+	query, args, err = r.pgsq.Select(repoColumnOutboundMessage.columns()).
+		From(repoTableOutboundMessage).
+		Where(where).
+		OrderBy(fmt.Sprintf("%s desc", repoColumnOutboundMessage.ID)).
+		Limit(uint64(pagination.Limit)).
+		Offset(uint64(pagination.Offset)).
+		ToSql()
+	if err != nil {
+		return nil, 0, domain.NewInternalError("", err)
+	}
+
+	var rows []repoOutboundMessage
+	if err = r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, 0, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	messages := make([]domain.OutboundMessage, 0, len(rows))
+	for _, row := range rows {
+		messages = append(messages, row.toDomain())
+	}
+	return messages, total, nil
+}
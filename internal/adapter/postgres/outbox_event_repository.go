@@ -0,0 +1,198 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+type repoOutboxEvent struct {
+	ID            int64        `db:"id"`
+	Payload       string       `db:"payload"`
+	Attempts      int          `db:"attempts"`
+	LastError     string       `db:"last_error"`
+	NextAttemptAt time.Time    `db:"next_attempt_at"`
+	SentAt        sql.NullTime `db:"sent_at"`
+	DeadAt        sql.NullTime `db:"dead_at"`
+	CreatedAt     time.Time    `db:"created_at"`
+}
+
+type repoColumnPatternOutboxEvent struct {
+	ID            string
+	Payload       string
+	Attempts      string
+	LastError     string
+	NextAttemptAt string
+	SentAt        string
+	DeadAt        string
+	CreatedAt     string
+}
+
+const repoTableOutboxEvent = "event_outbox"
+
+var repoColumnOutboxEvent = repoColumnPatternOutboxEvent{
+	ID:            "id",
+	Payload:       "payload",
+	Attempts:      "attempts",
+	LastError:     "last_error",
+	NextAttemptAt: "next_attempt_at",
+	SentAt:        "sent_at",
+	DeadAt:        "dead_at",
+	CreatedAt:     "created_at",
+}
+
+func (c *repoColumnPatternOutboxEvent) columns() string {
+	return strings.Join([]string{
+		c.ID,
+		c.Payload,
+		c.Attempts,
+		c.LastError,
+		c.NextAttemptAt,
+		c.SentAt,
+		c.DeadAt,
+		c.CreatedAt,
+	}, ", ")
+}
+
+// toDomainOutboxEvent maps the row back to domain model with the decrypted payload
+func (r *PostgresRepository) toDomainOutboxEvent(ctx context.Context, row repoOutboxEvent) (*domain.OutboxEvent, domain.Error) {
+	payload, err := r.encryptor.decrypt(ctx, row.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.OutboxEvent{
+		ID:            row.ID,
+		Payload:       payload,
+		Attempts:      row.Attempts,
+		LastError:     row.LastError,
+		NextAttemptAt: row.NextAttemptAt,
+		SentAt:        nullTimeToPtr(row.SentAt),
+		DeadAt:        nullTimeToPtr(row.DeadAt),
+		CreatedAt:     row.CreatedAt,
+	}, nil
+}
+
+// CreateOutboxEvents stores the payloads in the outbox in one statement, so either all or none of
+// them are stored.
+func (r *PostgresRepository) CreateOutboxEvents(ctx context.Context, payloads []string) domain.Error {
+	return r.createOutboxEvents(ctx, r.db, payloads)
+}
+
+func (r *PostgresRepository) createOutboxEvents(ctx context.Context, db sqlContextGetter, payloads []string) domain.Error {
+	if len(payloads) == 0 {
+		return nil
+	}
+
+	insert := r.pgsq.Insert(repoTableOutboxEvent).Columns(repoColumnOutboxEvent.Payload)
+	for _, payload := range payloads {
+		// encrypt the payload before it's stored
+		encrypted, err := r.encryptor.encrypt(ctx, payload)
+		if err != nil {
+			return err
+		}
+		insert = insert.Values(encrypted)
+	}
+
+	query, args, err := insert.ToSql()
+	if err != nil {
+		return domain.NewInternalError("", err)
+	}
+	if _, err = db.ExecContext(ctx, query, args...); err != nil {
+		return domain.NewExternalError("", nil, err)
+	}
+	return nil
+}
+
+// ClaimOutboxEvents claims unsent events due to be published, oldest first. Dead events are skipped.
+// Claimed events are not claimable again until leaseUntil, and rows locked by other claimers are
+// skipped, so several relays could run at once.
+func (r *PostgresRepository) ClaimOutboxEvents(ctx context.Context, leaseUntil time.Time, limit int) ([]domain.OutboxEvent, domain.Error) {
+	var rows []repoOutboxEvent
+	err := r.claimRows(ctx, &rows, claimParams{
+		table:       repoTableOutboxEvent,
+		idColumn:    repoColumnOutboxEvent.ID,
+		leaseColumn: repoColumnOutboxEvent.NextAttemptAt,
+		columns:     repoColumnOutboxEvent.columns(),
+		where: []sq.Sqlizer{sq.Eq{
+			repoColumnOutboxEvent.SentAt: nil,
+			repoColumnOutboxEvent.DeadAt: nil,
+		}},
+		orderBy:    []string{repoColumnOutboxEvent.NextAttemptAt, repoColumnOutboxEvent.ID},
+		leaseUntil: leaseUntil,
+		limit:      limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// map the query result back to domain model
+	events := make([]domain.OutboxEvent, 0, len(rows))
+	for _, row := range rows {
+		event, err := r.toDomainOutboxEvent(ctx, row)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *event)
+	}
+	return events, nil
+}
+
+func (r *PostgresRepository) UpdateOutboxEvent(ctx context.Context, eventID int64, params domain.UpdateOutboxEventParams) domain.Error {
+	update := map[string]interface{}{}
+	if params.Attempts != nil {
+		update[repoColumnOutboxEvent.Attempts] = *params.Attempts
+	}
+	if params.LastError != nil {
+		update[repoColumnOutboxEvent.LastError] = *params.LastError
+	}
+	if params.NextAttemptAt != nil {
+		update[repoColumnOutboxEvent.NextAttemptAt] = *params.NextAttemptAt
+	}
+	if params.SentAt != nil {
+		update[repoColumnOutboxEvent.SentAt] = *params.SentAt
+	}
+	if params.DeadAt != nil {
+		update[repoColumnOutboxEvent.DeadAt] = *params.DeadAt
+	}
+	if len(update) == 0 {
+		return nil
+	}
+
+	query, args, err := r.pgsq.Update(repoTableOutboxEvent).
+		SetMap(update).
+		Where(sq.Eq{repoColumnOutboxEvent.ID: eventID}).
+		ToSql()
+	if err != nil {
+		return domain.NewInternalError("", err)
+	}
+	if _, err = r.db.ExecContext(ctx, query, args...); err != nil {
+		return domain.NewExternalError("", nil, err)
+	}
+	return nil
+}
+
+// DeleteSentOutboxEvents deletes events sent before the given time, and returns how many are deleted
+func (r *PostgresRepository) DeleteSentOutboxEvents(ctx context.Context, sentBefore time.Time) (int, domain.Error) {
+	query, args, err := r.pgsq.Delete(repoTableOutboxEvent).
+		Where(sq.Lt{repoColumnOutboxEvent.SentAt: sentBefore}).
+		ToSql()
+	if err != nil {
+		return 0, domain.NewInternalError("", err)
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, domain.NewExternalError("", nil, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, domain.NewExternalError("", nil, err)
+	}
+	return int(affected), nil
+}
@@ -3,6 +3,8 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/hashicorp/go-multierror"
@@ -12,15 +14,19 @@ import (
 )
 
 type PostgresRepository struct {
-	db   *sqlx.DB
-	pgsq sq.StatementBuilderType
+	db        *sqlx.DB
+	pgsq      sq.StatementBuilderType
+	encryptor *envelopeEncryptor
 }
 
-func NewPostgresRepository(ctx context.Context, db *sqlx.DB) *PostgresRepository {
+// NewPostgresRepository creates the repository. The keyProvider encrypts channel credentials at
+// rest; without it, reading or writing channel credentials fails.
+func NewPostgresRepository(ctx context.Context, db *sqlx.DB, keyProvider KeyProvider) *PostgresRepository {
 	return &PostgresRepository{
 		db: db,
 		// set the default placeholder as $ instead of ? because postgres uses $
-		pgsq: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+		pgsq:      sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+		encryptor: newEnvelopeEncryptor(keyProvider),
 	}
 }
 
@@ -58,3 +64,49 @@ func (r *PostgresRepository) finishTx(err domain.Error, tx *sqlx.Tx) domain.Erro
 		return nil
 	}
 }
+
+// claimParams describes which rows of the table to claim. A row is claimable once its lease column
+// is due, and claiming moves the lease column to leaseUntil.
+type claimParams struct {
+	table       string
+	idColumn    string
+	leaseColumn string
+	// columns are returned for the claimed rows
+	columns string
+	// where narrows down the claimable rows
+	where      []sq.Sqlizer
+	orderBy    []string
+	leaseUntil time.Time
+	limit      int
+}
+
+// claimRows claims rows and scans them into dest. Claimed rows are not claimable again until
+// leaseUntil, and rows locked by other claimers are skipped, so it's safe for several service
+// instances to claim at the same time.
+func (r *PostgresRepository) claimRows(ctx context.Context, dest interface{}, params claimParams) domain.Error {
+	// the sub-query must use the default placeholder since it is nested into the outer query
+	claimable := sq.Select(params.idColumn).
+		From(params.table).
+		Where(sq.Expr(fmt.Sprintf("%s <= now()", params.leaseColumn)))
+	for _, where := range params.where {
+		claimable = claimable.Where(where)
+	}
+	claimable = claimable.
+		OrderBy(params.orderBy...).
+		Limit(uint64(params.limit)).
+		Suffix("for update skip locked")
+
+	query, args, err := r.pgsq.Update(params.table).
+		Set(params.leaseColumn, params.leaseUntil).
+		Where(sq.Expr(fmt.Sprintf("%s in (?)", params.idColumn), claimable)).
+		Suffix(fmt.Sprintf("returning %s", params.columns)).
+		ToSql()
+	if err != nil {
+		return domain.NewInternalError("", err)
+	}
+
+	if err = r.db.SelectContext(ctx, dest, query, args...); err != nil {
+		return domain.NewExternalError("", nil, err)
+	}
+	return nil
+}
@@ -11,34 +11,38 @@ import (
 )
 
 type repoSlide struct {
-	ID        int    `db:"id"`
-	ChannelID int    `db:"channel_id"`
-	URL       string `db:"url"`
-	Page      int    `db:"page"`
-	Current   bool   `db:"current"`
+	ID             int    `db:"id"`
+	OrganizationID int    `db:"organization_id"`
+	ChannelID      int    `db:"channel_id"`
+	URL            string `db:"url"`
+	Page           int    `db:"page"`
+	Current        bool   `db:"current"`
 }
 
 type repoColumnPatternSlide struct {
-	ID        string
-	ChannelID string
-	URL       string
-	Page      string
-	Current   string
+	ID             string
+	OrganizationID string
+	ChannelID      string
+	URL            string
+	Page           string
+	Current        string
 }
 
 const repoTableSlide = "slide"
 
 var repoColumnSlide = repoColumnPatternSlide{
-	ID:        "id",
-	ChannelID: "channel_id",
-	URL:       "url",
-	Page:      "page",
-	Current:   "current",
+	ID:             "id",
+	OrganizationID: "organization_id",
+	ChannelID:      "channel_id",
+	URL:            "url",
+	Page:           "page",
+	Current:        "current",
 }
 
 func (c *repoColumnPatternSlide) columns() string {
 	return strings.Join([]string{
 		c.ID,
+		c.OrganizationID,
 		c.ChannelID,
 		c.URL,
 		c.Page,
@@ -46,8 +50,8 @@ func (c *repoColumnPatternSlide) columns() string {
 	}, ", ")
 }
 
-func (r *PostgresRepository) GetSlideURLByPage(ctx context.Context, channelID, page int) (url string, p int, err domain.Error) {
-	slide, err := r.getSlideByPage(ctx, channelID, page)
+func (r *PostgresRepository) GetSlideURLByPage(ctx context.Context, organizationID, channelID, page int) (url string, p int, err domain.Error) {
+	slide, err := r.getSlideByPage(ctx, organizationID, channelID, page)
 	if err != nil {
 		return "", 0, err
 	}
@@ -55,12 +59,13 @@ func (r *PostgresRepository) GetSlideURLByPage(ctx context.Context, channelID, p
 	return slide.URL, slide.Page, nil
 }
 
-func (r *PostgresRepository) getSlideByPage(ctx context.Context, channelID, page int) (*repoSlide, domain.Error) {
+func (r *PostgresRepository) getSlideByPage(ctx context.Context, organizationID, channelID, page int) (*repoSlide, domain.Error) {
 	query, args, err := r.pgsq.Select(repoColumnSlide.columns()).
 		From(repoTableSlide).
 		Where(sq.Eq{
-			repoColumnSlide.ChannelID: channelID,
-			repoColumnSlide.Page:      page,
+			repoColumnSlide.OrganizationID: organizationID,
+			repoColumnSlide.ChannelID:      channelID,
+			repoColumnSlide.Page:           page,
 		}).
 		Limit(1).
 		ToSql()
@@ -76,11 +81,12 @@ func (r *PostgresRepository) getSlideByPage(ctx context.Context, channelID, page
 	return &row, nil
 }
 
-func (r *PostgresRepository) GetLastPageNumber(ctx context.Context, channelID int) (int, domain.Error) {
+func (r *PostgresRepository) GetLastPageNumber(ctx context.Context, organizationID, channelID int) (int, domain.Error) {
 	query, args, err := r.pgsq.Select(repoColumnSlide.Page).
 		From(repoTableSlide).
 		Where(sq.Eq{
-			repoColumnSlide.ChannelID: channelID,
+			repoColumnSlide.OrganizationID: organizationID,
+			repoColumnSlide.ChannelID:      channelID,
 		}).
 		OrderBy(fmt.Sprintf("%v desc", repoColumnSlide.Page)).
 		Limit(1).
@@ -96,7 +102,7 @@ func (r *PostgresRepository) GetLastPageNumber(ctx context.Context, channelID in
 	return last, nil
 }
 
-func (r *PostgresRepository) UpdateCurrentPage(ctx context.Context, channelID, page int) domain.Error {
+func (r *PostgresRepository) UpdateCurrentPage(ctx context.Context, organizationID, channelID, page int) domain.Error {
 	tx, err := r.beginTx()
 	if err != nil {
 		return err
@@ -105,17 +111,18 @@ func (r *PostgresRepository) UpdateCurrentPage(ctx context.Context, channelID, p
 		err = r.finishTx(err, tx)
 	}()
 
-	err = r.updateCurrentPage(ctx, tx, channelID, page)
+	err = r.updateCurrentPage(ctx, tx, organizationID, channelID, page)
 	return err
 }
 
-func (r *PostgresRepository) updateCurrentPage(ctx context.Context, db sqlContextGetter, channelID, page int) domain.Error {
+func (r *PostgresRepository) updateCurrentPage(ctx context.Context, db sqlContextGetter, organizationID, channelID, page int) domain.Error {
 	// Set the current page to be enabled
 	query, args, err := r.pgsq.Update(repoTableSlide).
 		Set(repoColumnSlide.Current, true).
 		Where(sq.Eq{
-			repoColumnSlide.ChannelID: channelID,
-			repoColumnSlide.Page:      page,
+			repoColumnSlide.OrganizationID: organizationID,
+			repoColumnSlide.ChannelID:      channelID,
+			repoColumnSlide.Page:           page,
 		}).
 		ToSql()
 	if err != nil {
@@ -128,7 +135,10 @@ func (r *PostgresRepository) updateCurrentPage(ctx context.Context, db sqlContex
 	// Set all other pages are disabled
 	query, args, err = r.pgsq.Update(repoTableSlide).
 		Set(repoColumnSlide.Current, false).
-		Where(sq.Eq{repoColumnSlide.ChannelID: channelID}).
+		Where(sq.Eq{
+			repoColumnSlide.OrganizationID: organizationID,
+			repoColumnSlide.ChannelID:      channelID,
+		}).
 		Where(sq.NotEq{repoColumnSlide.Page: page}).
 		ToSql()
 	if err != nil {
@@ -141,12 +151,13 @@ func (r *PostgresRepository) updateCurrentPage(ctx context.Context, db sqlContex
 	return nil
 }
 
-func (r *PostgresRepository) GetEnabledSlideURL(ctx context.Context, channelID int) (string, domain.Error) {
+func (r *PostgresRepository) GetEnabledSlideURL(ctx context.Context, organizationID, channelID int) (string, domain.Error) {
 	query, args, err := r.pgsq.Select(repoColumnSlide.columns()).
 		From(repoTableSlide).
 		Where(sq.Eq{
-			repoColumnSlide.ChannelID: channelID,
-			repoColumnSlide.Current:   true,
+			repoColumnSlide.OrganizationID: organizationID,
+			repoColumnSlide.ChannelID:      channelID,
+			repoColumnSlide.Current:        true,
 		}).
 		Limit(1).
 		ToSql()
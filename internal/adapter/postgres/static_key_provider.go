@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+const dataKeySize = 32
+
+// StaticKeyProvider wraps data keys with a static master key held in memory. It is meant for local
+// development and tests; production should use a KMS backed provider.
+type StaticKeyProvider struct {
+	masterKey []byte
+}
+
+// NewStaticKeyProvider creates a StaticKeyProvider with a base64 encoded 256-bit master key
+func NewStaticKeyProvider(_ context.Context, base64MasterKey string) (*StaticKeyProvider, error) {
+	masterKey, err := base64.StdEncoding.DecodeString(base64MasterKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(masterKey) != dataKeySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", dataKeySize, len(masterKey))
+	}
+	return &StaticKeyProvider{
+		masterKey: masterKey,
+	}, nil
+}
+
+func (p *StaticKeyProvider) GenerateDataKey(_ context.Context) ([]byte, []byte, domain.Error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, domain.NewInternalError("", err)
+	}
+
+	encrypted, err := sealAESGCM(p.masterKey, dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return dataKey, encrypted, nil
+}
+
+func (p *StaticKeyProvider) DecryptDataKey(_ context.Context, encrypted []byte) ([]byte, domain.Error) {
+	return openAESGCM(p.masterKey, encrypted)
+}
@@ -0,0 +1,183 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+type repoColumnPatternTagChannelMember struct {
+	TagID           string
+	ChannelMemberID string
+	CreatedAt       string
+}
+
+const repoTableTagChannelMember = "tag_channel_member"
+
+var repoColumnTagChannelMember = repoColumnPatternTagChannelMember{
+	TagID:           "tag_id",
+	ChannelMemberID: "channel_member_id",
+	CreatedAt:       "created_at",
+}
+
+// channelMemberIDsQuery selects IDs of the channel's members by their external member IDs
+func (r *PostgresRepository) channelMemberIDsQuery(channelID int, externalMemberIDs []string) sq.SelectBuilder {
+	return sq.Select(repoColumnChannelMember.ID).
+		From(repoTableChannelMember).
+		Where(sq.Eq{
+			repoColumnChannelMember.ChannelID:        channelID,
+			repoColumnChannelMember.ExternalMemberID: externalMemberIDs,
+		})
+}
+
+// AttachTag attaches the tag to the channel's members. Unknown members and members who already have
+// the tag are skipped, and the number of newly attached members is returned.
+func (r *PostgresRepository) AttachTag(ctx context.Context, channelID, tagID int, externalMemberIDs []string) (int, domain.Error) {
+	members := sq.Select(fmt.Sprintf("%d", tagID), repoColumnChannelMember.ID).
+		From(repoTableChannelMember).
+		Where(sq.Eq{
+			repoColumnChannelMember.ChannelID:        channelID,
+			repoColumnChannelMember.ExternalMemberID: externalMemberIDs,
+		})
+
+	query, args, err := r.pgsq.Insert(repoTableTagChannelMember).
+		Columns(repoColumnTagChannelMember.TagID, repoColumnTagChannelMember.ChannelMemberID).
+		Select(members).
+		Suffix("on conflict do nothing").
+		ToSql()
+	if err != nil {
+		return 0, domain.NewInternalError("", err)
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, domain.NewExternalError("", nil, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, domain.NewExternalError("", nil, err)
+	}
+	return int(affected), nil
+}
+
+// DetachTag detaches the tag from the channel's members, and returns the number of detached members
+func (r *PostgresRepository) DetachTag(ctx context.Context, channelID, tagID int, externalMemberIDs []string) (int, domain.Error) {
+	query, args, err := r.pgsq.Delete(repoTableTagChannelMember).
+		Where(sq.Eq{repoColumnTagChannelMember.TagID: tagID}).
+		Where(sq.Expr(fmt.Sprintf("%s in (?)", repoColumnTagChannelMember.ChannelMemberID), r.channelMemberIDsQuery(channelID, externalMemberIDs))).
+		ToSql()
+	if err != nil {
+		return 0, domain.NewInternalError("", err)
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, domain.NewExternalError("", nil, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, domain.NewExternalError("", nil, err)
+	}
+	return int(affected), nil
+}
+
+// ListMemberTags returns tags attached to the channel's member ordered by name
+func (r *PostgresRepository) ListMemberTags(ctx context.Context, channelID int, externalMemberID string) ([]domain.Tag, domain.Error) {
+	attached := sq.Select(repoColumnTagChannelMember.TagID).
+		From(repoTableTagChannelMember).
+		Where(sq.Expr(fmt.Sprintf("%s in (?)", repoColumnTagChannelMember.ChannelMemberID), r.channelMemberIDsQuery(channelID, []string{externalMemberID})))
+
+	query, args, err := r.pgsq.Select(repoColumnTag.columns()).
+		From(repoTableTag).
+		Where(sq.Eq{repoColumnTag.ChannelID: channelID}).
+		Where(sq.Expr(fmt.Sprintf("%s in (?)", repoColumnTag.ID), attached)).
+		OrderBy(repoColumnTag.Name).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	var rows []repoTag
+	if err = r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	tags := make([]domain.Tag, 0, len(rows))
+	for _, row := range rows {
+		tags = append(tags, row.toDomain())
+	}
+	return tags, nil
+}
+
+// tagExpressionCondition builds the condition channel members match the tag expression with
+func tagExpressionCondition(expression domain.TagExpression) sq.Sqlizer {
+	var operands []sq.Sqlizer
+	for _, tagID := range expression.TagIDs {
+		operands = append(operands, sq.Expr(fmt.Sprintf(
+			"exists (select 1 from %s where %s.%s = ? and %s.%s = %s.%s)",
+			repoTableTagChannelMember,
+			repoTableTagChannelMember, repoColumnTagChannelMember.TagID,
+			repoTableTagChannelMember, repoColumnTagChannelMember.ChannelMemberID,
+			repoTableChannelMember, repoColumnChannelMember.ID,
+		), tagID))
+	}
+	for _, sub := range expression.Expressions {
+		operands = append(operands, tagExpressionCondition(sub))
+	}
+
+	if expression.Operator == domain.TagOperatorOr {
+		return sq.Or(operands)
+	}
+	return sq.And(operands)
+}
+
+// ListChannelMembersByTags returns the channel's members matching the tag expression in the given
+// page, most recently seen first, and the total number of them.
+func (r *PostgresRepository) ListChannelMembersByTags(ctx context.Context, channelID int, expression domain.TagExpression, pagination domain.Pagination) ([]domain.ChannelMember, int, domain.Error) {
+	where := sq.And{
+		sq.Eq{repoColumnChannelMember.ChannelID: channelID},
+		tagExpressionCondition(expression),
+	}
+
+	// count all matched members for pagination
+	query, args, err := r.pgsq.Select("count(*)").
+		From(repoTableChannelMember).
+		Where(where).
+		ToSql()
+	if err != nil {
+		return nil, 0, domain.NewInternalError("", err)
+	}
+
+	var total int
+	if err = r.db.GetContext(ctx, &total, query, args...); err != nil {
+		return nil, 0, domain.NewExternalError("", nil, err)
+	}
+
+	// get members of the requested page
+	query, args, err = r.pgsq.Select(repoColumnChannelMember.columns()).
+		From(repoTableChannelMember).
+		Where(where).
+		OrderBy(fmt.Sprintf("%s desc", repoColumnChannelMember.LastSeenAt), repoColumnChannelMember.ID).
+		Limit(uint64(pagination.Limit)).
+		Offset(uint64(pagination.Offset)).
+		ToSql()
+	if err != nil {
+		return nil, 0, domain.NewInternalError("", err)
+	}
+
+	var rows []repoChannelMember
+	if err = r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, 0, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	members := make([]domain.ChannelMember, 0, len(rows))
+	for _, row := range rows {
+		members = append(members, row.toDomain())
+	}
+	return members, total, nil
+}
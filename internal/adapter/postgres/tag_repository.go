@@ -0,0 +1,204 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+type repoTag struct {
+	ID        int       `db:"id"`
+	ChannelID int       `db:"channel_id"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+type repoColumnPatternTag struct {
+	ID        string
+	ChannelID string
+	Name      string
+	CreatedAt string
+	UpdatedAt string
+}
+
+const repoTableTag = "tag"
+
+var repoColumnTag = repoColumnPatternTag{
+	ID:        "id",
+	ChannelID: "channel_id",
+	Name:      "name",
+	CreatedAt: "created_at",
+	UpdatedAt: "updated_at",
+}
+
+func (c *repoColumnPatternTag) columns() string {
+	return strings.Join([]string{
+		c.ID,
+		c.ChannelID,
+		c.Name,
+		c.CreatedAt,
+		c.UpdatedAt,
+	}, ", ")
+}
+
+func (row repoTag) toDomain() domain.Tag {
+	return domain.Tag{
+		ID:        row.ID,
+		ChannelID: row.ChannelID,
+		Name:      row.Name,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+	}
+}
+
+// pqUniqueViolation is the error code of unique constraint violations
+const pqUniqueViolation = pq.ErrorCode("23505")
+
+// newTagWriteError reports duplicated tag names as parameter errors
+func newTagWriteError(err error) domain.Error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation {
+		return domain.NewParameterError("tag name is already used in the channel", err)
+	}
+	return domain.NewExternalError("", nil, err)
+}
+
+func (r *PostgresRepository) CreateTag(ctx context.Context, tag domain.Tag) (*domain.Tag, domain.Error) {
+	// build SQL query
+	query, args, err := r.pgsq.Insert(repoTableTag).
+		SetMap(map[string]interface{}{
+			repoColumnTag.ChannelID: tag.ChannelID,
+			repoColumnTag.Name:      tag.Name,
+		}).
+		Suffix(fmt.Sprintf("returning %s", repoColumnTag.columns())).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	// execute SQL query
+	row := repoTag{}
+	if err = r.db.GetContext(ctx, &row, query, args...); err != nil {
+		return nil, newTagWriteError(err)
+	}
+
+	// map the query result back to domain model
+	created := row.toDomain()
+	return &created, nil
+}
+
+func (r *PostgresRepository) GetTagByID(ctx context.Context, channelID, tagID int) (*domain.Tag, domain.Error) {
+	query, args, err := r.pgsq.Select(repoColumnTag.columns()).
+		From(repoTableTag).
+		Where(sq.Eq{
+			repoColumnTag.ChannelID: channelID,
+			repoColumnTag.ID:        tagID,
+		}).
+		Limit(1).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	// get one row from result
+	row := repoTag{}
+	if err = r.db.GetContext(ctx, &row, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.NewResourceNotFoundError("tag is not found", err)
+		}
+		return nil, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	tag := row.toDomain()
+	return &tag, nil
+}
+
+// ListTags returns all tags of the channel ordered by name
+func (r *PostgresRepository) ListTags(ctx context.Context, channelID int) ([]domain.Tag, domain.Error) {
+	query, args, err := r.pgsq.Select(repoColumnTag.columns()).
+		From(repoTableTag).
+		Where(sq.Eq{repoColumnTag.ChannelID: channelID}).
+		OrderBy(repoColumnTag.Name).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	var rows []repoTag
+	if err = r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	tags := make([]domain.Tag, 0, len(rows))
+	for _, row := range rows {
+		tags = append(tags, row.toDomain())
+	}
+	return tags, nil
+}
+
+func (r *PostgresRepository) UpdateTag(ctx context.Context, channelID, tagID int, params domain.UpdateTagParams) (*domain.Tag, domain.Error) {
+	update := map[string]interface{}{
+		repoColumnTag.UpdatedAt: time.Now(),
+	}
+	if params.Name != nil {
+		update[repoColumnTag.Name] = *params.Name
+	}
+
+	query, args, err := r.pgsq.Update(repoTableTag).
+		SetMap(update).
+		Where(sq.Eq{
+			repoColumnTag.ChannelID: channelID,
+			repoColumnTag.ID:        tagID,
+		}).
+		Suffix(fmt.Sprintf("returning %s", repoColumnTag.columns())).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	row := repoTag{}
+	if err = r.db.GetContext(ctx, &row, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.NewResourceNotFoundError("tag is not found", err)
+		}
+		return nil, newTagWriteError(err)
+	}
+
+	// map the query result back to domain model
+	tag := row.toDomain()
+	return &tag, nil
+}
+
+// DeleteTag deletes the tag, which is detached from all members as well
+func (r *PostgresRepository) DeleteTag(ctx context.Context, channelID, tagID int) domain.Error {
+	query, args, err := r.pgsq.Delete(repoTableTag).
+		Where(sq.Eq{
+			repoColumnTag.ChannelID: channelID,
+			repoColumnTag.ID:        tagID,
+		}).
+		ToSql()
+	if err != nil {
+		return domain.NewInternalError("", err)
+	}
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return domain.NewExternalError("", nil, err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return domain.NewExternalError("", nil, err)
+	} else if affected == 0 {
+		return domain.NewResourceNotFoundError("tag is not found", sql.ErrNoRows)
+	}
+	return nil
+}
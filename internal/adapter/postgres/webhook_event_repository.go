@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+type repoColumnPatternWebhookEvent struct {
+	WebhookEventID string
+	ExpireAt       string
+	CreatedAt      string
+}
+
+const repoTableWebhookEvent = "webhook_event"
+
+var repoColumnWebhookEvent = repoColumnPatternWebhookEvent{
+	WebhookEventID: "webhook_event_id",
+	ExpireAt:       "expire_at",
+	CreatedAt:      "created_at",
+}
+
+// StoreWebhookEvents marks the webhook events received until expireAt and stores them in the outbox in
+// one transaction, so an event is never marked without being stored. Events whose marks haven't
+// expired are duplicates, which are not stored, and their webhook event IDs are returned.
+func (r *PostgresRepository) StoreWebhookEvents(ctx context.Context, events []domain.WebhookOutboxEvent, expireAt time.Time) (dropped []string, err domain.Error) {
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	tx, err := r.beginTx()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = r.finishTx(err, tx)
+	}()
+
+	ids := make([]string, 0, len(events))
+	for _, e := range events {
+		if e.WebhookEventID != "" {
+			ids = append(ids, e.WebhookEventID)
+		}
+	}
+	marked, err := r.markWebhookEvents(ctx, tx, ids, expireAt)
+	if err != nil {
+		return nil, err
+	}
+
+	payloads := make([]string, 0, len(events))
+	for _, e := range events {
+		if e.WebhookEventID != "" {
+			if !marked[e.WebhookEventID] {
+				dropped = append(dropped, e.WebhookEventID)
+				continue
+			}
+			// an event repeated in the same webhook is stored once
+			delete(marked, e.WebhookEventID)
+		}
+		payloads = append(payloads, e.Payload)
+	}
+
+	if err = r.createOutboxEvents(ctx, tx, payloads); err != nil {
+		return nil, err
+	}
+	return dropped, nil
+}
+
+// markWebhookEvents marks the webhook events received until expireAt, and returns the IDs marked by
+// this call. Events marked before whose marks haven't expired are left out.
+func (r *PostgresRepository) markWebhookEvents(ctx context.Context, db sqlContextGetter, webhookEventIDs []string, expireAt time.Time) (map[string]bool, domain.Error) {
+	marked := make(map[string]bool, len(webhookEventIDs))
+	if len(webhookEventIDs) == 0 {
+		return marked, nil
+	}
+
+	insert := r.pgsq.Insert(repoTableWebhookEvent).
+		Columns(repoColumnWebhookEvent.WebhookEventID, repoColumnWebhookEvent.ExpireAt)
+	seen := make(map[string]bool, len(webhookEventIDs))
+	for _, id := range webhookEventIDs {
+		// a row could not be upserted twice in one statement
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		insert = insert.Values(id, expireAt)
+	}
+
+	query, args, err := insert.
+		// an expired mark is taken over, while a live one makes nothing returned
+		Suffix(fmt.Sprintf("on conflict (%s) do update set %s = excluded.%s, %s = now() where %s.%s <= now() returning %s",
+			repoColumnWebhookEvent.WebhookEventID,
+			repoColumnWebhookEvent.ExpireAt, repoColumnWebhookEvent.ExpireAt,
+			repoColumnWebhookEvent.CreatedAt,
+			repoTableWebhookEvent, repoColumnWebhookEvent.ExpireAt,
+			repoColumnWebhookEvent.WebhookEventID)).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	var ids []string
+	if err = db.SelectContext(ctx, &ids, query, args...); err != nil {
+		return nil, domain.NewExternalError("", nil, err)
+	}
+	for _, id := range ids {
+		marked[id] = true
+	}
+	return marked, nil
+}
+
+// DeleteExpiredWebhookEvents deletes marks expired before the given time, and returns how many are
+// deleted.
+func (r *PostgresRepository) DeleteExpiredWebhookEvents(ctx context.Context, expiredBefore time.Time) (int, domain.Error) {
+	query, args, err := r.pgsq.Delete(repoTableWebhookEvent).
+		Where(sq.Lt{repoColumnWebhookEvent.ExpireAt: expiredBefore}).
+		ToSql()
+	if err != nil {
+		return 0, domain.NewInternalError("", err)
+	}
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, domain.NewExternalError("", nil, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, domain.NewExternalError("", nil, err)
+	}
+	return int(affected), nil
+}
@@ -0,0 +1,259 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+type repoWorkerTask struct {
+	ID             int          `db:"id"`
+	OrganizationID int          `db:"organization_id"`
+	ChannelID      int          `db:"channel_id"`
+	EventID        string       `db:"event_id"`
+	EventType      string       `db:"event_type"`
+	Payload        []byte       `db:"payload"`
+	Status         string       `db:"status"`
+	Attempts       int          `db:"attempts"`
+	LastError      string       `db:"last_error"`
+	StartedAt      sql.NullTime `db:"started_at"`
+	FinishedAt     sql.NullTime `db:"finished_at"`
+	CreatedAt      time.Time    `db:"created_at"`
+	UpdatedAt      time.Time    `db:"updated_at"`
+}
+
+type repoColumnPatternWorkerTask struct {
+	ID             string
+	OrganizationID string
+	ChannelID      string
+	EventID        string
+	EventType      string
+	Payload        string
+	Status         string
+	Attempts       string
+	LastError      string
+	StartedAt      string
+	FinishedAt     string
+	CreatedAt      string
+	UpdatedAt      string
+}
+
+const repoTableWorkerTask = "worker_task"
+
+var repoColumnWorkerTask = repoColumnPatternWorkerTask{
+	ID:             "id",
+	OrganizationID: "organization_id",
+	ChannelID:      "channel_id",
+	EventID:        "event_id",
+	EventType:      "event_type",
+	Payload:        "payload",
+	Status:         "status",
+	Attempts:       "attempts",
+	LastError:      "last_error",
+	StartedAt:      "started_at",
+	FinishedAt:     "finished_at",
+	CreatedAt:      "created_at",
+	UpdatedAt:      "updated_at",
+}
+
+func (c *repoColumnPatternWorkerTask) columns() string {
+	return strings.Join([]string{
+		c.ID,
+		c.OrganizationID,
+		c.ChannelID,
+		c.EventID,
+		c.EventType,
+		c.Payload,
+		c.Status,
+		c.Attempts,
+		c.LastError,
+		c.StartedAt,
+		c.FinishedAt,
+		c.CreatedAt,
+		c.UpdatedAt,
+	}, ", ")
+}
+
+func (row repoWorkerTask) toDomain() domain.WorkerTask {
+	return domain.WorkerTask{
+		ID:             row.ID,
+		OrganizationID: row.OrganizationID,
+		ChannelID:      row.ChannelID,
+		EventID:        row.EventID,
+		EventType:      row.EventType,
+		Payload:        row.Payload,
+		Status:         domain.WorkerTaskStatus(row.Status),
+		Attempts:       row.Attempts,
+		LastError:      row.LastError,
+		StartedAt:      nullTimeToPtr(row.StartedAt),
+		FinishedAt:     nullTimeToPtr(row.FinishedAt),
+		CreatedAt:      row.CreatedAt,
+		UpdatedAt:      row.UpdatedAt,
+	}
+}
+
+// CreateWorkerTask creates a pending task of the event. If the event has been received before, its
+// existing task is returned instead.
+func (r *PostgresRepository) CreateWorkerTask(ctx context.Context, task domain.WorkerTask) (*domain.WorkerTask, domain.Error) {
+	payload := []byte(task.Payload)
+	if len(payload) == 0 {
+		payload = []byte("{}")
+	}
+
+	// build SQL query
+	query, args, err := r.pgsq.Insert(repoTableWorkerTask).
+		SetMap(map[string]interface{}{
+			repoColumnWorkerTask.OrganizationID: task.OrganizationID,
+			repoColumnWorkerTask.ChannelID:      task.ChannelID,
+			repoColumnWorkerTask.EventID:        task.EventID,
+			repoColumnWorkerTask.EventType:      task.EventType,
+			repoColumnWorkerTask.Payload:        string(payload),
+			repoColumnWorkerTask.Status:         domain.WorkerTaskStatusPending,
+		}).
+		// the no-op update makes the existing row returned
+		Suffix(fmt.Sprintf("on conflict (%s) do update set %s = now() returning %s",
+			repoColumnWorkerTask.EventID, repoColumnWorkerTask.UpdatedAt, repoColumnWorkerTask.columns())).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	// execute SQL query
+	row := repoWorkerTask{}
+	if err = r.db.GetContext(ctx, &row, query, args...); err != nil {
+		return nil, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	created := row.toDomain()
+	return &created, nil
+}
+
+// StartWorkerTask marks the task running and counts the attempt
+func (r *PostgresRepository) StartWorkerTask(ctx context.Context, taskID int, startedAt time.Time) (*domain.WorkerTask, domain.Error) {
+	query, args, err := r.pgsq.Update(repoTableWorkerTask).
+		Set(repoColumnWorkerTask.Status, domain.WorkerTaskStatusRunning).
+		Set(repoColumnWorkerTask.Attempts, sq.Expr(fmt.Sprintf("%s + 1", repoColumnWorkerTask.Attempts))).
+		Set(repoColumnWorkerTask.StartedAt, startedAt).
+		Set(repoColumnWorkerTask.FinishedAt, nil).
+		Set(repoColumnWorkerTask.UpdatedAt, time.Now()).
+		Where(sq.Eq{repoColumnWorkerTask.ID: taskID}).
+		Suffix(fmt.Sprintf("returning %s", repoColumnWorkerTask.columns())).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	row := repoWorkerTask{}
+	if err = r.db.GetContext(ctx, &row, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.NewResourceNotFoundError("worker task is not found", err)
+		}
+		return nil, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	task := row.toDomain()
+	return &task, nil
+}
+
+// FinishWorkerTask records the result of the task's last attempt
+func (r *PostgresRepository) FinishWorkerTask(ctx context.Context, taskID int, status domain.WorkerTaskStatus, lastError string, finishedAt time.Time) domain.Error {
+	query, args, err := r.pgsq.Update(repoTableWorkerTask).
+		SetMap(map[string]interface{}{
+			repoColumnWorkerTask.Status:     status,
+			repoColumnWorkerTask.LastError:  lastError,
+			repoColumnWorkerTask.FinishedAt: finishedAt,
+			repoColumnWorkerTask.UpdatedAt:  time.Now(),
+		}).
+		Where(sq.Eq{repoColumnWorkerTask.ID: taskID}).
+		ToSql()
+	if err != nil {
+		return domain.NewInternalError("", err)
+	}
+	if _, err = r.db.ExecContext(ctx, query, args...); err != nil {
+		return domain.NewExternalError("", nil, err)
+	}
+	return nil
+}
+
+func (r *PostgresRepository) GetWorkerTaskByID(ctx context.Context, organizationID, taskID int) (*domain.WorkerTask, domain.Error) {
+	query, args, err := r.pgsq.Select(repoColumnWorkerTask.columns()).
+		From(repoTableWorkerTask).
+		Where(sq.Eq{
+			repoColumnWorkerTask.OrganizationID: organizationID,
+			repoColumnWorkerTask.ID:             taskID,
+		}).
+		Limit(1).
+		ToSql()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	// get one row from result
+	row := repoWorkerTask{}
+	if err = r.db.GetContext(ctx, &row, query, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.NewResourceNotFoundError("worker task is not found", err)
+		}
+		return nil, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	task := row.toDomain()
+	return &task, nil
+}
+
+// ListWorkerTasks returns tasks of the organization in the given page, latest first, and the total
+// number of tasks matching the filter.
+func (r *PostgresRepository) ListWorkerTasks(ctx context.Context, organizationID int, filter domain.WorkerTaskFilter, pagination domain.Pagination) ([]domain.WorkerTask, int, domain.Error) {
+	where := sq.Eq{repoColumnWorkerTask.OrganizationID: organizationID}
+	if filter.Status != "" {
+		where[repoColumnWorkerTask.Status] = filter.Status
+	}
+
+	// count all matched tasks for pagination
+	query, args, err := r.pgsq.Select("count(*)").
+		From(repoTableWorkerTask).
+		Where(where).
+		ToSql()
+	if err != nil {
+		return nil, 0, domain.NewInternalError("", err)
+	}
+
+	var total int
+	if err = r.db.GetContext(ctx, &total, query, args...); err != nil {
+		return nil, 0, domain.NewExternalError("", nil, err)
+	}
+
+	// get tasks of the requested page
+	query, args, err = r.pgsq.Select(repoColumnWorkerTask.columns()).
+		From(repoTableWorkerTask).
+		Where(where).
+		OrderBy(fmt.Sprintf("%s desc", repoColumnWorkerTask.ID)).
+		Limit(uint64(pagination.Limit)).
+		Offset(uint64(pagination.Offset)).
+		ToSql()
+	if err != nil {
+		return nil, 0, domain.NewInternalError("", err)
+	}
+
+	var rows []repoWorkerTask
+	if err = r.db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, 0, domain.NewExternalError("", nil, err)
+	}
+
+	// map the query result back to domain model
+	tasks := make([]domain.WorkerTask, 0, len(rows))
+	for _, row := range rows {
+		tasks = append(tasks, row.toDomain())
+	}
+	return tasks, total, nil
+}
@@ -0,0 +1,100 @@
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/rs/zerolog"
+
+	"github.com/david7482/aws-serverless-service/internal/adapter/batch"
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+const (
+	// maxBatchEntries and maxBatchSize are the limits of a SendMessageBatch request
+	// Reference: https://docs.aws.amazon.com/AWSSimpleQueueService/latest/APIReference/API_SendMessageBatch.html
+	maxBatchEntries = 10
+	maxBatchSize    = 256 * 1024
+	// maxSendAttempts is how many times failed entries are sent before they are reported as failed
+	maxSendAttempts = 3
+	// retryBackoff is the delay before failed entries are sent again, doubled on each attempt
+	retryBackoff = 100 * time.Millisecond
+)
+
+// SQS publishes events as messages of the queue
+type SQS struct {
+	client   *sqs.SQS
+	queueURL string
+	sender   *batch.Sender
+}
+
+func NewSQS(ctx context.Context, s *session.Session, queueURL string) *SQS {
+	q := &SQS{
+		client:   sqs.New(s),
+		queueURL: queueURL,
+	}
+	q.sender = batch.NewSender(ctx, batch.SenderParam{
+		Name:         "sqs",
+		MaxEntries:   maxBatchEntries,
+		MaxSize:      maxBatchSize,
+		MaxAttempts:  maxSendAttempts,
+		RetryBackoff: retryBackoff,
+		Send:         q.sendBatch,
+	})
+	return q
+}
+
+// PutEvents sends the events in as few requests as the limits of SQS allow. Entries failed by SQS are
+// sent again unless the failure is caused by the entry itself, and the returned errors tell the result
+// of each event in the same order, which is nil if the event is sent.
+func (q *SQS) PutEvents(ctx context.Context, data []string) []domain.Error {
+	return q.sender.Send(ctx, data)
+}
+
+// sendBatch sends the batch of events in one request
+func (q *SQS) sendBatch(ctx context.Context, data []string) []domain.Error {
+	// entries are identified by their indexes in data
+	entries := make([]*sqs.SendMessageBatchRequestEntry, 0, len(data))
+	for i, d := range data {
+		entries = append(entries, &sqs.SendMessageBatchRequestEntry{
+			Id:          aws.String(strconv.Itoa(i)),
+			MessageBody: aws.String(d),
+		})
+	}
+
+	results := make([]domain.Error, len(data))
+	output, err := q.client.SendMessageBatchWithContext(ctx, &sqs.SendMessageBatchInput{
+		QueueUrl: aws.String(q.queueURL),
+		Entries:  entries,
+	})
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Msg("fail to send messages to sqs")
+		for i := range results {
+			results[i] = domain.NewExternalError("", nil, err)
+		}
+		return results
+	}
+
+	for _, entry := range output.Failed {
+		i, err := strconv.Atoi(aws.StringValue(entry.Id))
+		if err != nil || i < 0 || i >= len(data) {
+			continue
+		}
+		err = fmt.Errorf("%s: %s", aws.StringValue(entry.Code), aws.StringValue(entry.Message))
+		if aws.BoolValue(entry.SenderFault) {
+			// the entry fails again if it's the cause
+			results[i] = domain.NewParameterError("", err)
+		} else {
+			results[i] = domain.NewExternalError("", nil, err)
+		}
+	}
+	if len(output.Failed) > 0 {
+		zerolog.Ctx(ctx).Error().Int("failedEntryCount", len(output.Failed)).Msg("sqs fails some entries")
+	}
+	return results
+}
@@ -2,45 +2,106 @@ package app
 
 import (
 	"context"
+	"crypto/rand"
+	"fmt"
 	"log"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
+	"github.com/rs/zerolog"
 
 	"github.com/david7482/aws-serverless-service/internal/adapter/eventbridge"
+	"github.com/david7482/aws-serverless-service/internal/adapter/kms"
 	"github.com/david7482/aws-serverless-service/internal/adapter/line"
+	"github.com/david7482/aws-serverless-service/internal/adapter/memory"
 	"github.com/david7482/aws-serverless-service/internal/adapter/postgres"
+	"github.com/david7482/aws-serverless-service/internal/adapter/sqs"
+	"github.com/david7482/aws-serverless-service/internal/app/service/auth"
 	"github.com/david7482/aws-serverless-service/internal/app/service/channel"
+	"github.com/david7482/aws-serverless-service/internal/app/service/history"
+	"github.com/david7482/aws-serverless-service/internal/app/service/member"
 	"github.com/david7482/aws-serverless-service/internal/app/service/message"
+	"github.com/david7482/aws-serverless-service/internal/app/service/organization"
+	"github.com/david7482/aws-serverless-service/internal/app/service/outbound"
 	"github.com/david7482/aws-serverless-service/internal/app/service/slide"
+	"github.com/david7482/aws-serverless-service/internal/app/service/tag"
+	"github.com/david7482/aws-serverless-service/internal/app/service/workertask"
+	"github.com/david7482/aws-serverless-service/internal/worker"
 )
 
 type Application struct {
-	Params         ApplicationParams
-	MsgService     *message.MessageService
-	ChannelService *channel.ChannelService
-	SlideService   *slide.SlideService
-
-	//AccountService          *auth.AccountService
-	//TokenService            *auth.TokenService
-	//OrgService              *organization.OrgService
+	Params          ApplicationParams
+	MsgService      *message.MessageService
+	ChannelService  *channel.ChannelService
+	SlideService    *slide.SlideService
+	MemberService   *member.MemberService
+	HistoryService  *history.HistoryService
+	OutboundService *outbound.OutboundService
+	OrgService      *organization.OrgService
+	RoleService     *organization.RoleService
+	AccountService  *auth.AccountService
+	TokenService    *auth.TokenService
+
+	TagService              *tag.TagService
+	TagChannelMemberService *tag.TagChannelMemberService
+	WorkerTaskService       *workertask.WorkerTaskService
+
+	// Worker and LocalEventBus are only set in local mode, where the worker consumes events published
+	// to the in-memory event bus.
+	Worker        *worker.Worker
+	LocalEventBus *memory.EventBus
+
+	// ChannelListener invalidates ChannelCache on changes of channels made by any instance
+	ChannelCache    *memory.ChannelCache
+	ChannelListener *postgres.ChannelListener
+
 	//UserService             *organization.UserService
-	//RoleService             *organization.RoleService
-	//WorkerTaskService       *workertask.WorkerTaskService
 	//ChannelService          *organization.ChannelService
-	//TagService              *tag.TagService
-	//TagChannelMemberService *tag.TagChannelMemberService
 }
 
 type ApplicationParams struct {
 	// Database parameters
 	DatabaseDSN string
 
+	// Encryption parameters
+	EncryptionKeyProvider string
+	EncryptionStaticKey   string
+
+	// Event bus parameters
+	EventBus string
+	// Local runs the worker in the service with the in-memory event bus, and EventBus is ignored
+	Local bool
+
 	// AWS parameters
 	AWSRegion          string
 	AWSEventBridgeName string
+	AWSSQSQueueURL     string
+	AWSKMSKeyID        string
+
+	// Auth parameters
+	AdminAPIKey string
+	TokenSecret string
+	TokenTTL    time.Duration
+
+	// Channel parameters
+	TokenRefreshWindow   time.Duration
+	TokenRevocationGrace time.Duration
+	PublicBaseURL        string
+	HealthCheckInterval  time.Duration
+
+	// Member parameters
+	MemberProfileTTL time.Duration
+
+	// Webhook parameters
+	WebhookDedupeStore string
+	WebhookDedupeTTL   time.Duration
+	// ChannelCacheTTL and ChannelCacheNegativeTTL are how long known and unknown channels are
+	// cached for webhooks
+	ChannelCacheTTL         time.Duration
+	ChannelCacheNegativeTTL time.Duration
 }
 
 func MustNewApplication(ctx context.Context, params ApplicationParams) *Application {
@@ -52,13 +113,6 @@ func MustNewApplication(ctx context.Context, params ApplicationParams) *Applicat
 }
 
 func NewApplication(ctx context.Context, params ApplicationParams) (*Application, error) {
-	// Create repositories
-	db := sqlx.MustOpen("postgres", params.DatabaseDSN)
-	if err := db.Ping(); err != nil {
-		return nil, err
-	}
-	postgresRepo := postgres.NewPostgresRepository(ctx, db)
-
 	// Create AWS resources
 	ses, err := session.NewSessionWithOptions(session.Options{
 		Config: aws.Config{
@@ -68,23 +122,195 @@ func NewApplication(ctx context.Context, params ApplicationParams) (*Application
 	if err != nil {
 		return nil, err
 	}
-	eventBridge := eventbridge.NewEventBridge(ctx, ses, params.AWSEventBridgeName)
+
+	// Create repositories
+	keyProvider, err := newKeyProvider(ctx, ses, params)
+	if err != nil {
+		return nil, err
+	}
+	db := sqlx.MustOpen("postgres", params.DatabaseDSN)
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+	postgresRepo := postgres.NewPostgresRepository(ctx, db, keyProvider)
+
+	var eventBus message.EventBus
+	var localEventBus *memory.EventBus
+	if params.Local {
+		localEventBus = memory.NewEventBus(ctx, memoryEventBusSize)
+		eventBus = localEventBus
+	} else {
+		eventBus, err = newEventBus(ctx, ses, params)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	eventStore, err := newWebhookEventStore(ctx, postgresRepo, params)
+	if err != nil {
+		return nil, err
+	}
 
 	lineService := line.NewLineService(ctx)
 
+	channelCache := memory.NewChannelCache(ctx, memory.ChannelCacheParam{
+		Repo:        postgresRepo,
+		TTL:         params.ChannelCacheTTL,
+		NegativeTTL: params.ChannelCacheNegativeTTL,
+	})
+	channelListener, err := postgres.NewChannelListener(ctx, params.DatabaseDSN)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenSecret, err := newTokenSecret(ctx, params.TokenSecret)
+	if err != nil {
+		return nil, err
+	}
+
 	app := &Application{
-		Params: params,
+		Params:          params,
+		ChannelCache:    channelCache,
+		ChannelListener: channelListener,
 		MsgService: message.NewMessageService(ctx, message.MessageServiceParam{
-			ChannelRepo: postgresRepo,
+			ChannelRepo: channelCache,
+			MemberRepo:  postgresRepo,
+			GroupRepo:   postgresRepo,
+			InboundRepo: postgresRepo,
+			OutboxRepo:  postgresRepo,
+			EventStore:  eventStore,
 			LineService: lineService,
-			EventBridge: eventBridge,
+			EventBus:    eventBus,
+			ProfileTTL:  params.MemberProfileTTL,
+			DedupeTTL:   params.WebhookDedupeTTL,
 		}),
 		ChannelService: channel.NewChannelService(ctx, channel.ChannelServiceParam{
-			ChannelRepo: postgresRepo,
-			LineService: lineService,
+			ChannelRepo:          postgresRepo,
+			ChannelCache:         channelCache,
+			AccessTokenRepo:      postgresRepo,
+			RevocationRepo:       postgresRepo,
+			AuditLogRepo:         postgresRepo,
+			LineService:          lineService,
+			TokenRefreshWindow:   params.TokenRefreshWindow,
+			TokenRevocationGrace: params.TokenRevocationGrace,
+			PublicBaseURL:        params.PublicBaseURL,
+			HealthCheckInterval:  params.HealthCheckInterval,
 		}),
 		SlideService: slide.NewSlideService(ctx, postgresRepo),
+		MemberService: member.NewMemberService(ctx, member.MemberServiceParam{
+			ChannelRepo: postgresRepo,
+			MemberRepo:  postgresRepo,
+			GroupRepo:   postgresRepo,
+		}),
+		HistoryService: history.NewHistoryService(ctx, history.HistoryServiceParam{
+			ChannelRepo: postgresRepo,
+			InboundRepo: postgresRepo,
+		}),
+		OutboundService: outbound.NewOutboundService(ctx, outbound.OutboundServiceParam{
+			ChannelRepo:  postgresRepo,
+			OutboundRepo: postgresRepo,
+			LineService:  lineService,
+		}),
+		OrgService: organization.NewOrgService(ctx, organization.OrgServiceParam{
+			OrgRepo: postgresRepo,
+		}),
+		RoleService: organization.NewRoleService(ctx, organization.RoleServiceParam{}),
+		TagService: tag.NewTagService(ctx, tag.TagServiceParam{
+			ChannelRepo: postgresRepo,
+			TagRepo:     postgresRepo,
+		}),
+		TagChannelMemberService: tag.NewTagChannelMemberService(ctx, tag.TagChannelMemberServiceParam{
+			ChannelRepo:   postgresRepo,
+			TagRepo:       postgresRepo,
+			TagMemberRepo: postgresRepo,
+		}),
+		WorkerTaskService: workertask.NewWorkerTaskService(ctx, workertask.WorkerTaskServiceParam{
+			TaskRepo: postgresRepo,
+		}),
+		AccountService: auth.NewAccountService(ctx, auth.AccountServiceParam{
+			APIKeyRepo:  postgresRepo,
+			AdminAPIKey: params.AdminAPIKey,
+		}),
+		TokenService: auth.NewTokenService(ctx, auth.TokenServiceParam{
+			APIKeyRepo: postgresRepo,
+			Secret:     tokenSecret,
+			TTL:        params.TokenTTL,
+		}),
+	}
+
+	if params.Local {
+		app.LocalEventBus = localEventBus
+		app.Worker = worker.NewWorker(ctx, worker.WorkerParam{
+			SlideRepo:       postgresRepo,
+			TaskService:     app.WorkerTaskService,
+			LineService:     lineService,
+			OutboundService: app.OutboundService,
+		})
 	}
 
 	return app, nil
 }
+
+func newKeyProvider(ctx context.Context, ses *session.Session, params ApplicationParams) (postgres.KeyProvider, error) {
+	switch params.EncryptionKeyProvider {
+	case "static":
+		return postgres.NewStaticKeyProvider(ctx, params.EncryptionStaticKey)
+	case "kms":
+		if params.AWSKMSKeyID == "" {
+			return nil, fmt.Errorf("AWS KMS key ID is required by kms key provider")
+		}
+		return kms.NewKeyProvider(ctx, ses, params.AWSKMSKeyID), nil
+	default:
+		return nil, fmt.Errorf("unknown encryption key provider: %s", params.EncryptionKeyProvider)
+	}
+}
+
+// memoryEventBusSize is how many events the in-memory event bus buffers
+const memoryEventBusSize = 1000
+
+// newEventBus returns the event bus which webhook events are published to. The in-memory event bus
+// is only used in local mode, since nothing consumes it without the local worker.
+func newEventBus(ctx context.Context, ses *session.Session, params ApplicationParams) (message.EventBus, error) {
+	switch params.EventBus {
+	case "eventbridge":
+		if params.AWSEventBridgeName == "" {
+			return nil, fmt.Errorf("AWS EventBridge name is required by eventbridge event bus")
+		}
+		return eventbridge.NewEventBridge(ctx, ses, params.AWSEventBridgeName), nil
+	case "sqs":
+		if params.AWSSQSQueueURL == "" {
+			return nil, fmt.Errorf("AWS SQS queue URL is required by sqs event bus")
+		}
+		return sqs.NewSQS(ctx, ses, params.AWSSQSQueueURL), nil
+	default:
+		return nil, fmt.Errorf("unknown event bus: %s", params.EventBus)
+	}
+}
+
+// newWebhookEventStore returns the store remembering received webhook events. The memory store only
+// drops duplicates received by the same instance.
+func newWebhookEventStore(ctx context.Context, postgresRepo *postgres.PostgresRepository, params ApplicationParams) (message.WebhookEventStore, error) {
+	switch params.WebhookDedupeStore {
+	case "postgres":
+		return postgresRepo, nil
+	case "memory":
+		return memory.NewWebhookEventStore(ctx, postgresRepo), nil
+	default:
+		return nil, fmt.Errorf("unknown webhook dedupe store: %s", params.WebhookDedupeStore)
+	}
+}
+
+// newTokenSecret returns the secret to sign bearer tokens. A random one is generated if it's not
+// given, and then tokens are only valid on this instance until it restarts.
+func newTokenSecret(ctx context.Context, secret string) ([]byte, error) {
+	if secret != "" {
+		return []byte(secret), nil
+	}
+
+	zerolog.Ctx(ctx).Warn().Msg("token secret is not given, and a random one is used")
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	return random, nil
+}
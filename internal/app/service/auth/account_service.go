@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+const (
+	// APIKeyPrefix starts every API key, which tells API keys apart from bearer tokens
+	APIKeyPrefix = "cbk_"
+	// apiKeyDisplayPrefixLength is the length of the key prefix kept for display
+	apiKeyDisplayPrefixLength = 12
+	// apiKeyLastUsedPrecision limits how often the last used time of a key is written
+	apiKeyLastUsedPrecision = time.Minute
+)
+
+var errInvalidAPIKey = errors.New("invalid API key")
+
+type AccountService struct {
+	apiKeyRepo      APIKeyRepository
+	adminAPIKeyHash string
+}
+
+type AccountServiceParam struct {
+	APIKeyRepo APIKeyRepository
+	// AdminAPIKey authenticates the admin, who could access all organizations. Admin access is
+	// disabled if it's empty.
+	AdminAPIKey string
+}
+
+func NewAccountService(_ context.Context, param AccountServiceParam) *AccountService {
+	s := &AccountService{
+		apiKeyRepo: param.APIKeyRepo,
+	}
+	if param.AdminAPIKey != "" {
+		s.adminAPIKeyHash = hashAPIKey(param.AdminAPIKey)
+	}
+	return s
+}
+
+// logger wrap the execution context with component info
+func (s *AccountService) logger(ctx context.Context) *zerolog.Logger {
+	l := zerolog.Ctx(ctx).With().Str("service", "account").Logger()
+	return &l
+}
+
+type IssueAPIKeyParam struct {
+	OrganizationID int
+	Name           string
+	Role           domain.Role
+	// ExpiresIn is the lifetime of the key. The key never expires if it's zero.
+	ExpiresIn time.Duration
+	CreatedBy domain.Actor
+}
+
+// IssueAPIKey issues a new API key of the organization. The returned key is the only chance to see
+// it since only its hash is stored.
+func (s *AccountService) IssueAPIKey(ctx context.Context, param IssueAPIKeyParam) (*domain.APIKey, string, domain.Error) {
+	if !param.Role.Valid() {
+		msg := "unknown role"
+		return nil, "", domain.NewParameterError(msg, errors.New(msg))
+	}
+
+	plaintext, err := generateAPIKey()
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Msg("failed to generate API key")
+		return nil, "", err
+	}
+
+	key := domain.APIKey{
+		OrganizationID: param.OrganizationID,
+		Name:           param.Name,
+		Role:           param.Role,
+		Prefix:         plaintext[:apiKeyDisplayPrefixLength],
+		Hash:           hashAPIKey(plaintext),
+		CreatedBy:      param.CreatedBy.ID,
+	}
+	if param.ExpiresIn > 0 {
+		expiredAt := time.Now().Add(param.ExpiresIn)
+		key.ExpiredAt = &expiredAt
+	}
+
+	created, err := s.apiKeyRepo.CreateAPIKey(ctx, key)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("organizationID", param.OrganizationID).Msg("failed to create API key")
+		return nil, "", err
+	}
+
+	s.logger(ctx).Info().
+		Int("organizationID", param.OrganizationID).
+		Int("apiKeyID", created.ID).
+		Str("role", string(created.Role)).
+		Str("actorID", param.CreatedBy.ID).
+		Msg("API key is issued")
+	return created, plaintext, nil
+}
+
+func (s *AccountService) ListAPIKeys(ctx context.Context, organizationID int) ([]domain.APIKey, domain.Error) {
+	keys, err := s.apiKeyRepo.ListAPIKeys(ctx, organizationID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("organizationID", organizationID).Msg("failed to list API keys")
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes the API key. Bearer tokens exchanged with the key stop working as well.
+func (s *AccountService) RevokeAPIKey(ctx context.Context, organizationID, keyID int, actor domain.Actor) domain.Error {
+	err := s.apiKeyRepo.RevokeAPIKey(ctx, organizationID, keyID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("apiKeyID", keyID).Msg("failed to revoke API key")
+		return err
+	}
+
+	s.logger(ctx).Info().
+		Int("organizationID", organizationID).
+		Int("apiKeyID", keyID).
+		Str("actorID", actor.ID).
+		Msg("API key is revoked")
+	return nil
+}
+
+// UpdateAPIKeyRole changes the role of the API key. It takes effect on bearer tokens exchanged with
+// the key as well.
+func (s *AccountService) UpdateAPIKeyRole(ctx context.Context, organizationID, keyID int, role domain.Role, actor domain.Actor) (*domain.APIKey, domain.Error) {
+	if !role.Valid() {
+		msg := "unknown role"
+		return nil, domain.NewParameterError(msg, errors.New(msg))
+	}
+
+	key, err := s.apiKeyRepo.UpdateAPIKeyRole(ctx, organizationID, keyID, role)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("apiKeyID", keyID).Msg("failed to update role of API key")
+		return nil, err
+	}
+
+	s.logger(ctx).Info().
+		Int("organizationID", organizationID).
+		Int("apiKeyID", keyID).
+		Str("role", string(role)).
+		Str("actorID", actor.ID).
+		Msg("role of API key is updated")
+	return key, nil
+}
+
+// AuthenticateAPIKey returns the principal the API key acts as
+func (s *AccountService) AuthenticateAPIKey(ctx context.Context, plaintext string) (*domain.Principal, domain.Error) {
+	if !strings.HasPrefix(plaintext, APIKeyPrefix) {
+		return nil, domain.NewUnauthorizedError("invalid API key", errInvalidAPIKey)
+	}
+	hash := hashAPIKey(plaintext)
+
+	if s.adminAPIKeyHash != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(s.adminAPIKeyHash)) == 1 {
+		return &domain.Principal{Type: domain.PrincipalTypeAdmin}, nil
+	}
+
+	key, err := s.apiKeyRepo.GetAPIKeyByHash(ctx, hash)
+	if err != nil {
+		var notFound domain.ResourceNotFoundError
+		if errors.As(err, &notFound) {
+			return nil, domain.NewUnauthorizedError("invalid API key", errInvalidAPIKey)
+		}
+		s.logger(ctx).Error().Err(err).Msg("failed to get API key")
+		return nil, err
+	}
+
+	now := time.Now()
+	if !key.Active(now) {
+		return nil, domain.NewUnauthorizedError("API key is revoked or expired", errInvalidAPIKey)
+	}
+
+	// Record the last used time at a coarse precision to avoid a write per request
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyLastUsedPrecision {
+		if err := s.apiKeyRepo.UpdateAPIKeyLastUsedAt(ctx, key.ID, now); err != nil {
+			s.logger(ctx).Warn().Err(err).Int("apiKeyID", key.ID).Msg("failed to update last used time of API key")
+		}
+	}
+
+	return &domain.Principal{
+		Type:           domain.PrincipalTypeAPIKey,
+		APIKeyID:       key.ID,
+		OrganizationID: key.OrganizationID,
+		Role:           key.Role,
+	}, nil
+}
+
+// generateAPIKey returns a new random API key
+func generateAPIKey() (string, domain.Error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", domain.NewInternalError("", err)
+	}
+	return APIKeyPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashAPIKey hashes the API key for storage. API keys are random enough, so a fast hash is fine.
+func hashAPIKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
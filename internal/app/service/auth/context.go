@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+type principalContextKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the authenticated principal
+func WithPrincipal(ctx context.Context, principal domain.Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns the authenticated principal carried by ctx
+func PrincipalFromContext(ctx context.Context) (domain.Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(domain.Principal)
+	return principal, ok
+}
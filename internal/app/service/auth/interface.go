@@ -0,0 +1,19 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+//go:generate mockgen -destination automock/api_key_repository.go -package=automock . APIKeyRepository
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key domain.APIKey) (*domain.APIKey, domain.Error)
+	GetAPIKeyByID(ctx context.Context, keyID int) (*domain.APIKey, domain.Error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (*domain.APIKey, domain.Error)
+	ListAPIKeys(ctx context.Context, organizationID int) ([]domain.APIKey, domain.Error)
+	RevokeAPIKey(ctx context.Context, organizationID, keyID int) domain.Error
+	UpdateAPIKeyRole(ctx context.Context, organizationID, keyID int, role domain.Role) (*domain.APIKey, domain.Error)
+	UpdateAPIKeyLastUsedAt(ctx context.Context, keyID int, lastUsedAt time.Time) domain.Error
+}
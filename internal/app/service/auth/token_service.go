@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+const tokenIssuer = "chatbot"
+
+var errInvalidToken = errors.New("invalid bearer token")
+
+// tokenClaims are the JWT claims of bearer tokens
+type tokenClaims struct {
+	Issuer         string               `json:"iss"`
+	Subject        string               `json:"sub"`
+	IssuedAt       int64                `json:"iat"`
+	ExpiredAt      int64                `json:"exp"`
+	Type           domain.PrincipalType `json:"typ"`
+	APIKeyID       int                  `json:"key,omitempty"`
+	OrganizationID int                  `json:"org,omitempty"`
+}
+
+// TokenService issues short-lived bearer tokens in exchange for API keys, so clients don't have to
+// send their API keys on every request. Tokens are HS256 signed JWTs.
+type TokenService struct {
+	apiKeyRepo APIKeyRepository
+	secret     []byte
+	ttl        time.Duration
+}
+
+type TokenServiceParam struct {
+	APIKeyRepo APIKeyRepository
+	// Secret signs the tokens, so it must be shared by all service instances
+	Secret []byte
+	// TTL is the lifetime of issued tokens
+	TTL time.Duration
+}
+
+func NewTokenService(_ context.Context, param TokenServiceParam) *TokenService {
+	return &TokenService{
+		apiKeyRepo: param.APIKeyRepo,
+		secret:     param.Secret,
+		ttl:        param.TTL,
+	}
+}
+
+// logger wrap the execution context with component info
+func (s *TokenService) logger(ctx context.Context) *zerolog.Logger {
+	l := zerolog.Ctx(ctx).With().Str("service", "token").Logger()
+	return &l
+}
+
+// IssueToken issues a bearer token acting as the principal
+func (s *TokenService) IssueToken(ctx context.Context, principal domain.Principal) (string, time.Time, domain.Error) {
+	now := time.Now()
+	expiredAt := now.Add(s.ttl)
+
+	payload, err := json.Marshal(tokenClaims{
+		Issuer:         tokenIssuer,
+		Subject:        principal.ID(),
+		IssuedAt:       now.Unix(),
+		ExpiredAt:      expiredAt.Unix(),
+		Type:           principal.Type,
+		APIKeyID:       principal.APIKeyID,
+		OrganizationID: principal.OrganizationID,
+	})
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Msg("failed to marshal token claims")
+		return "", time.Time{}, domain.NewInternalError("", err)
+	}
+
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + s.sign(signingInput), expiredAt, nil
+}
+
+// VerifyToken returns the principal the bearer token acts as. Tokens exchanged with API keys which
+// are revoked afterwards are rejected.
+func (s *TokenService) VerifyToken(ctx context.Context, token string) (*domain.Principal, domain.Error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, domain.NewUnauthorizedError("invalid bearer token", errInvalidToken)
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+	}
+	if err := decodeTokenSegment(parts[0], &header); err != nil || header.Algorithm != "HS256" {
+		return nil, domain.NewUnauthorizedError("invalid bearer token", errInvalidToken)
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(s.sign(parts[0]+"."+parts[1]))) {
+		return nil, domain.NewUnauthorizedError("invalid bearer token", errInvalidToken)
+	}
+
+	var claims tokenClaims
+	if err := decodeTokenSegment(parts[1], &claims); err != nil || claims.Issuer != tokenIssuer {
+		return nil, domain.NewUnauthorizedError("invalid bearer token", errInvalidToken)
+	}
+	if time.Now().Unix() >= claims.ExpiredAt {
+		return nil, domain.NewUnauthorizedError("bearer token is expired", errInvalidToken)
+	}
+
+	principal := domain.Principal{
+		Type:           claims.Type,
+		APIKeyID:       claims.APIKeyID,
+		OrganizationID: claims.OrganizationID,
+	}
+	switch principal.Type {
+	case domain.PrincipalTypeAdmin:
+		return &principal, nil
+	case domain.PrincipalTypeAPIKey:
+		key, err := s.apiKeyRepo.GetAPIKeyByID(ctx, principal.APIKeyID)
+		if err != nil {
+			var notFound domain.ResourceNotFoundError
+			if errors.As(err, &notFound) {
+				return nil, domain.NewUnauthorizedError("invalid bearer token", errInvalidToken)
+			}
+			s.logger(ctx).Error().Err(err).Int("apiKeyID", principal.APIKeyID).Msg("failed to get API key")
+			return nil, err
+		}
+		if !key.Active(time.Now()) {
+			return nil, domain.NewUnauthorizedError("API key is revoked or expired", errInvalidToken)
+		}
+		// The role is not part of the token, so role changes take effect immediately
+		principal.Role = key.Role
+		return &principal, nil
+	default:
+		return nil, domain.NewUnauthorizedError("invalid bearer token", errInvalidToken)
+	}
+}
+
+func (s *TokenService) sign(signingInput string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(signingInput))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func decodeTokenSegment(segment string, v interface{}) error {
+	decoded, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(decoded, v)
+}
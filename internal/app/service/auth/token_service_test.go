@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+// fakeAPIKeyRepository serves API keys from memory. Only GetAPIKeyByID is used by VerifyToken.
+type fakeAPIKeyRepository struct {
+	APIKeyRepository
+	keys map[int]domain.APIKey
+}
+
+func (r *fakeAPIKeyRepository) GetAPIKeyByID(_ context.Context, keyID int) (*domain.APIKey, domain.Error) {
+	key, ok := r.keys[keyID]
+	if !ok {
+		return nil, domain.NewResourceNotFoundError("API key is not found", errors.New("no rows"))
+	}
+	return &key, nil
+}
+
+func newTestTokenService(ttl time.Duration) *TokenService {
+	revokedAt := time.Now().Add(-time.Minute)
+	expiredAt := time.Now().Add(-time.Minute)
+	return NewTokenService(context.Background(), TokenServiceParam{
+		APIKeyRepo: &fakeAPIKeyRepository{keys: map[int]domain.APIKey{
+			1: {ID: 1, OrganizationID: 10, Role: domain.RoleEditor},
+			2: {ID: 2, OrganizationID: 10, Role: domain.RoleEditor, RevokedAt: &revokedAt},
+			3: {ID: 3, OrganizationID: 10, Role: domain.RoleEditor, ExpiredAt: &expiredAt},
+		}},
+		Secret: []byte("test-secret"),
+		TTL:    ttl,
+	})
+}
+
+func issueTestToken(t *testing.T, s *TokenService, principal domain.Principal) string {
+	t.Helper()
+
+	token, _, err := s.IssueToken(context.Background(), principal)
+	if err != nil {
+		t.Fatalf("IssueToken() error = %v", err)
+	}
+	return token
+}
+
+// replaceTokenSegment replaces a segment of the token and signs it again with the secret
+func replaceTokenSegment(s *TokenService, token string, index int, segment string) string {
+	parts := strings.Split(token, ".")
+	parts[index] = base64.RawURLEncoding.EncodeToString([]byte(segment))
+	return parts[0] + "." + parts[1] + "." + s.sign(parts[0]+"."+parts[1])
+}
+
+func TestTokenService_VerifyToken(t *testing.T) {
+	s := newTestTokenService(time.Hour)
+	apiKeyToken := issueTestToken(t, s, domain.Principal{Type: domain.PrincipalTypeAPIKey, APIKeyID: 1, OrganizationID: 10})
+	adminToken := issueTestToken(t, s, domain.Principal{Type: domain.PrincipalTypeAdmin})
+
+	parts := strings.Split(apiKeyToken, ".")
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	signature[0] ^= 0xff
+	tamperedSignature := parts[0] + "." + parts[1] + "." + base64.RawURLEncoding.EncodeToString(signature)
+
+	otherSecret := NewTokenService(context.Background(), TokenServiceParam{Secret: []byte("other-secret"), TTL: time.Hour})
+	exp := time.Now().Add(time.Hour).Unix()
+
+	tests := []struct {
+		name     string
+		token    string
+		wantType domain.PrincipalType
+		wantRole domain.Role
+		wantErr  bool
+	}{
+		{name: "API key token", token: apiKeyToken, wantType: domain.PrincipalTypeAPIKey, wantRole: domain.RoleEditor},
+		{name: "admin token", token: adminToken, wantType: domain.PrincipalTypeAdmin},
+		{name: "tampered signature", token: tamperedSignature, wantErr: true},
+		{
+			name:    "tampered claims",
+			token:   parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"chatbot","typ":"admin","exp":9999999999}`)) + "." + parts[2],
+			wantErr: true,
+		},
+		{name: "signed with another secret", token: issueTestToken(t, otherSecret, domain.Principal{Type: domain.PrincipalTypeAdmin}), wantErr: true},
+		{name: "alg none", token: replaceTokenSegment(s, adminToken, 0, `{"alg":"none","typ":"JWT"}`), wantErr: true},
+		{name: "alg HS512", token: replaceTokenSegment(s, adminToken, 0, `{"alg":"HS512","typ":"JWT"}`), wantErr: true},
+		{name: "alg RS256", token: replaceTokenSegment(s, adminToken, 0, `{"alg":"RS256","typ":"JWT"}`), wantErr: true},
+		{name: "unsigned alg none", token: base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + parts[1] + ".", wantErr: true},
+		{name: "another issuer", token: replaceTokenSegment(s, adminToken, 1, `{"iss":"someone","typ":"admin","exp":`+strconv.FormatInt(exp, 10)+`}`), wantErr: true},
+		{name: "unknown principal type", token: replaceTokenSegment(s, adminToken, 1, `{"iss":"chatbot","typ":"root","exp":`+strconv.FormatInt(exp, 10)+`}`), wantErr: true},
+		{name: "revoked API key", token: issueTestToken(t, s, domain.Principal{Type: domain.PrincipalTypeAPIKey, APIKeyID: 2, OrganizationID: 10}), wantErr: true},
+		{name: "expired API key", token: issueTestToken(t, s, domain.Principal{Type: domain.PrincipalTypeAPIKey, APIKeyID: 3, OrganizationID: 10}), wantErr: true},
+		{name: "deleted API key", token: issueTestToken(t, s, domain.Principal{Type: domain.PrincipalTypeAPIKey, APIKeyID: 4, OrganizationID: 10}), wantErr: true},
+		{name: "expired token", token: issueTestToken(t, newTestTokenService(-time.Second), domain.Principal{Type: domain.PrincipalTypeAdmin}), wantErr: true},
+		{name: "not a JWT", token: "not-a-token", wantErr: true},
+		{name: "empty", token: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := s.VerifyToken(context.Background(), tt.token)
+			if tt.wantErr {
+				var unauthorized domain.UnauthorizedError
+				if !errors.As(err, &unauthorized) {
+					t.Fatalf("VerifyToken() error = %v, want an UnauthorizedError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyToken() error = %v", err)
+			}
+			if principal.Type != tt.wantType || principal.Role != tt.wantRole {
+				t.Fatalf("VerifyToken() = %+v, want type %s and role %s", principal, tt.wantType, tt.wantRole)
+			}
+		})
+	}
+}
+
+func TestTokenService_VerifyToken_RevokedAfterIssued(t *testing.T) {
+	s := newTestTokenService(time.Hour)
+	token := issueTestToken(t, s, domain.Principal{Type: domain.PrincipalTypeAPIKey, APIKeyID: 1, OrganizationID: 10})
+	if _, err := s.VerifyToken(context.Background(), token); err != nil {
+		t.Fatalf("VerifyToken() error = %v", err)
+	}
+
+	repo := s.apiKeyRepo.(*fakeAPIKeyRepository)
+	key := repo.keys[1]
+	revokedAt := time.Now()
+	key.RevokedAt = &revokedAt
+	repo.keys[1] = key
+
+	if _, err := s.VerifyToken(context.Background(), token); err == nil {
+		t.Fatalf("VerifyToken() accepts a token of a revoked API key")
+	}
+}
@@ -0,0 +1,179 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+const (
+	// tokenRefreshBatchSize is the number of channels claimed at once for token refresh
+	tokenRefreshBatchSize = 10
+	// tokenRefreshLease is how long a claimed channel is held before others could claim it again
+	tokenRefreshLease = 5 * time.Minute
+)
+
+// retryBackoff delays token refreshes and revocations after failures
+var retryBackoff = domain.Backoff{Min: time.Minute, Max: 6 * time.Hour}
+
+// issueAccessToken issues a new access token with the strategy of the channel's access token version
+func (s *ChannelService) issueAccessToken(ctx context.Context, channel domain.Channel) (*domain.AccessToken, domain.Error) {
+	switch channel.AccessTokenVersion {
+	case domain.AccessTokenVersionV21:
+		if channel.AssertionKeyID == "" || channel.AssertionPrivateKey == "" {
+			msg := "assertion key ID and private key are required by v2.1 access tokens"
+			return nil, domain.NewParameterError(msg, errors.New(msg))
+		}
+		return s.lineService.IssueAccessTokenV21(ctx, channel.ExternalChannelID, channel.AssertionKeyID, channel.AssertionPrivateKey)
+	case domain.AccessTokenVersionV2, "":
+		accessToken, expiredAt, err := s.lineService.IssueAccessToken(ctx, channel.ExternalChannelID, channel.ExternalChannelSecret)
+		if err != nil {
+			return nil, err
+		}
+		return &domain.AccessToken{Token: accessToken, ExpiredAt: expiredAt}, nil
+	default:
+		msg := "unknown access token version"
+		return nil, domain.NewParameterError(msg, errors.New(msg))
+	}
+}
+
+// trackAccessToken records v2.1 access tokens, which stay valid until they expire unless they are
+// revoked explicitly. v2 access tokens are not tracked.
+func (s *ChannelService) trackAccessToken(ctx context.Context, channelID int, token domain.AccessToken) {
+	if token.KeyID == "" {
+		return
+	}
+
+	_, err := s.accessTokenRepo.CreateChannelAccessToken(ctx, domain.ChannelAccessToken{
+		ChannelID:   channelID,
+		KeyID:       token.KeyID,
+		AccessToken: token.Token,
+		ExpiredAt:   token.ExpiredAt,
+	})
+	if err != nil {
+		// the token is already in use, so we only log the failure here
+		s.logger(ctx).Error().Err(err).
+			Int("channelID", channelID).
+			Str("keyID", token.KeyID).
+			Msg("failed to track access token")
+	}
+}
+
+// ListChannelAccessTokens returns the unexpired v2.1 access tokens issued for the channel
+func (s *ChannelService) ListChannelAccessTokens(ctx context.Context, organizationID, channelID int) ([]domain.ChannelAccessToken, domain.Error) {
+	// Make sure the channel belongs to the organization
+	if _, err := s.channelRepo.GetChannelByID(ctx, organizationID, channelID); err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to get channel")
+		return nil, err
+	}
+
+	tokens, err := s.accessTokenRepo.ListChannelAccessTokens(ctx, channelID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to list access tokens")
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// RevokeChannelAccessToken revokes a v2.1 access token of the channel at LINE. The access token
+// currently used by the channel cannot be revoked.
+func (s *ChannelService) RevokeChannelAccessToken(ctx context.Context, organizationID, channelID, tokenID int) domain.Error {
+	channel, err := s.channelRepo.GetChannelByID(ctx, organizationID, channelID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to get channel")
+		return err
+	}
+
+	token, err := s.accessTokenRepo.GetChannelAccessTokenByID(ctx, channelID, tokenID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("tokenID", tokenID).Msg("failed to get access token")
+		return err
+	}
+	if token.RevokedAt != nil {
+		return nil
+	}
+	if token.AccessToken == channel.AccessToken {
+		msg := "the access token is in use by the channel"
+		return domain.NewParameterError(msg, errors.New(msg))
+	}
+
+	err = s.lineService.RevokeAccessTokenV21(ctx, channel.ExternalChannelID, channel.ExternalChannelSecret, token.AccessToken)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("tokenID", tokenID).Msg("failed to revoke access token")
+		return err
+	}
+
+	err = s.accessTokenRepo.MarkChannelAccessTokenRevoked(ctx, token.KeyID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("tokenID", tokenID).Msg("failed to mark access token revoked")
+		return err
+	}
+	return nil
+}
+
+// RefreshExpiringAccessTokens issues new access tokens for channels whose access token is going to
+// expire within the refresh window. Failed channels are retried later with exponential backoff.
+func (s *ChannelService) RefreshExpiringAccessTokens(ctx context.Context) domain.Error {
+	for {
+		now := time.Now()
+		channels, err := s.channelRepo.ClaimChannelsForTokenRefresh(ctx, now.Add(s.tokenRefreshWindow), now.Add(tokenRefreshLease), tokenRefreshBatchSize)
+		if err != nil {
+			s.logger(ctx).Error().Err(err).Msg("failed to claim channels for token refresh")
+			return err
+		}
+
+		for _, channel := range channels {
+			s.refreshAccessToken(ctx, channel)
+		}
+
+		if len(channels) < tokenRefreshBatchSize || ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+func (s *ChannelService) refreshAccessToken(ctx context.Context, channel domain.Channel) {
+	token, err := s.issueAccessToken(ctx, channel)
+	if err != nil {
+		attempts := channel.AccessTokenRefreshAttempts + 1
+		retryAt := time.Now().Add(retryBackoff.Delay(attempts))
+		s.logger(ctx).Error().Err(err).
+			Int("channelID", channel.ID).
+			Int("attempts", attempts).
+			Time("retryAt", retryAt).
+			Msg("failed to refresh access token")
+
+		_, err = s.channelRepo.UpdateChannel(ctx, channel.OrganizationID, channel.ID, domain.UpdateChannelParams{
+			AccessTokenRefreshAttempts: &attempts,
+			AccessTokenRefreshAfter:    &retryAt,
+		})
+		if err != nil {
+			s.logger(ctx).Error().Err(err).Int("channelID", channel.ID).Msg("failed to record token refresh failure")
+		}
+		return
+	}
+
+	// Replace the token and reset the retry state in one update
+	attempts, refreshAfter := 0, time.Now()
+	_, err = s.channelRepo.UpdateChannel(ctx, channel.OrganizationID, channel.ID, domain.UpdateChannelParams{
+		AccessToken:                &token.Token,
+		AccessTokenExpiredAt:       &token.ExpiredAt,
+		AccessTokenKeyID:           &token.KeyID,
+		AccessTokenRefreshAttempts: &attempts,
+		AccessTokenRefreshAfter:    &refreshAfter,
+	})
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channel.ID).Msg("failed to save refreshed access token")
+		return
+	}
+	s.invalidateChannel(ctx, channel.ExternalChannelID)
+	s.trackAccessToken(ctx, channel.ID, *token)
+	s.revokeReplacedAccessToken(ctx, channel, channel.ExternalChannelSecret)
+
+	s.logger(ctx).Info().
+		Int("channelID", channel.ID).
+		Time("expiredAt", token.ExpiredAt).
+		Msg("access token is refreshed")
+}
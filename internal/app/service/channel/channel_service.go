@@ -2,6 +2,9 @@ package channel
 
 import (
 	"context"
+	"errors"
+	"strconv"
+	"time"
 
 	"github.com/rs/zerolog"
 
@@ -9,19 +12,49 @@ import (
 )
 
 type ChannelService struct {
-	channelRepo ChannelRepository
-	lineService LineService
+	channelRepo          ChannelRepository
+	channelCache         ChannelCache
+	accessTokenRepo      ChannelAccessTokenRepository
+	revocationRepo       AccessTokenRevocationRepository
+	auditLogRepo         AuditLogRepository
+	lineService          LineService
+	tokenRefreshWindow   time.Duration
+	tokenRevocationGrace time.Duration
+	publicBaseURL        string
+	healthCheckInterval  time.Duration
 }
 
 type ChannelServiceParam struct {
-	ChannelRepo ChannelRepository
-	LineService LineService
+	ChannelRepo     ChannelRepository
+	ChannelCache    ChannelCache
+	AccessTokenRepo ChannelAccessTokenRepository
+	RevocationRepo  AccessTokenRevocationRepository
+	AuditLogRepo    AuditLogRepository
+	LineService     LineService
+	// TokenRefreshWindow is how long before expiry an access token gets refreshed
+	TokenRefreshWindow time.Duration
+	// TokenRevocationGrace is how long a replaced access token stays valid before it is revoked. It
+	// should cover how long published events carrying the old access token might be retried.
+	TokenRevocationGrace time.Duration
+	// PublicBaseURL is the base URL LINE could reach this service with, which is used to configure
+	// webhook endpoints of channels
+	PublicBaseURL string
+	// HealthCheckInterval is how often each channel gets its health checked
+	HealthCheckInterval time.Duration
 }
 
 func NewChannelService(_ context.Context, param ChannelServiceParam) *ChannelService {
 	return &ChannelService{
-		channelRepo: param.ChannelRepo,
-		lineService: param.LineService,
+		channelRepo:          param.ChannelRepo,
+		channelCache:         param.ChannelCache,
+		accessTokenRepo:      param.AccessTokenRepo,
+		revocationRepo:       param.RevocationRepo,
+		auditLogRepo:         param.AuditLogRepo,
+		lineService:          param.LineService,
+		tokenRefreshWindow:   param.TokenRefreshWindow,
+		tokenRevocationGrace: param.TokenRevocationGrace,
+		publicBaseURL:        param.PublicBaseURL,
+		healthCheckInterval:  param.HealthCheckInterval,
 	}
 }
 
@@ -31,31 +64,251 @@ func (s *ChannelService) logger(ctx context.Context) *zerolog.Logger {
 	return &l
 }
 
-func (s *ChannelService) CreateChannel(ctx context.Context, externalChannelID, externalChannelSecret string) (*domain.Channel, domain.Error) {
-	accessToken, expiredAt, err := s.lineService.IssueAccessToken(ctx, externalChannelID, externalChannelSecret)
+// invalidateChannel drops the cached channel after it is changed, so webhooks of this instance see the
+// change right away. Other instances are notified by the database.
+func (s *ChannelService) invalidateChannel(ctx context.Context, externalChannelID string) {
+	s.channelCache.InvalidateChannel(ctx, externalChannelID)
+}
+
+type CreateChannelParam struct {
+	OrganizationID        int
+	ExternalChannelID     string
+	ExternalChannelSecret string
+	// AssertionKeyID and AssertionPrivateKey are optional. If they are given, the channel would use
+	// v2.1 access tokens instead of v2 ones.
+	AssertionKeyID      string
+	AssertionPrivateKey string
+	// ConfigureWebhook sets the webhook endpoint of the channel at LINE and tests it
+	ConfigureWebhook bool
+}
+
+// CreateChannel creates the channel with a newly issued access token. If the webhook is requested to
+// be configured, its status is returned as well.
+func (s *ChannelService) CreateChannel(ctx context.Context, param CreateChannelParam) (*domain.Channel, *domain.LineWebhookStatus, domain.Error) {
+	if param.ConfigureWebhook && s.publicBaseURL == "" {
+		msg := "public base URL is not configured to set the webhook endpoint"
+		return nil, nil, domain.NewParameterError(msg, errors.New(msg))
+	}
+
+	channel := &domain.Channel{
+		OrganizationID:        param.OrganizationID,
+		ExternalChannelID:     param.ExternalChannelID,
+		ExternalChannelSecret: param.ExternalChannelSecret,
+		AccessTokenVersion:    domain.AccessTokenVersionV2,
+		AssertionKeyID:        param.AssertionKeyID,
+		AssertionPrivateKey:   param.AssertionPrivateKey,
+	}
+	if param.AssertionKeyID != "" || param.AssertionPrivateKey != "" {
+		channel.AccessTokenVersion = domain.AccessTokenVersionV21
+	}
+
+	token, err := s.issueAccessToken(ctx, *channel)
 	if err != nil {
 		s.logger(ctx).Error().Err(err).Msg("failed to issue access token from Line")
-		return nil, err
+		return nil, nil, err
 	}
 
-	info, err := s.lineService.GetChannelInfo(ctx, accessToken)
+	info, err := s.lineService.GetChannelInfo(ctx, token.Token)
 	if err != nil {
 		s.logger(ctx).Error().Err(err).Msg("failed to get channel info")
-		return nil, err
+		return nil, nil, err
 	}
 
-	channel := &domain.Channel{
-		Name:                  info.DisplayName,
-		ExternalChannelID:     externalChannelID,
-		ExternalChannelSecret: externalChannelSecret,
-		AccessToken:           accessToken,
-		AccessTokenExpiredAt:  expiredAt,
-	}
+	channel.Name = info.DisplayName
+	channel.AccessToken = token.Token
+	channel.AccessTokenExpiredAt = token.ExpiredAt
+	channel.AccessTokenKeyID = token.KeyID
 
 	channel, err = s.channelRepo.CreateChannel(ctx, *channel)
 	if err != nil {
 		s.logger(ctx).Error().Err(err).Msg("failed to create channel")
+		return nil, nil, err
+	}
+	// The channel might have been cached as unknown by webhooks sent before it is created
+	s.invalidateChannel(ctx, channel.ExternalChannelID)
+
+	s.trackAccessToken(ctx, channel.ID, *token)
+
+	var webhook *domain.LineWebhookStatus
+	if param.ConfigureWebhook {
+		webhook = s.configureWebhook(ctx, *channel)
+	}
+	return channel, webhook, nil
+}
+
+func (s *ChannelService) GetChannel(ctx context.Context, organizationID, channelID int) (*domain.Channel, domain.Error) {
+	channel, err := s.channelRepo.GetChannelByID(ctx, organizationID, channelID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to get channel")
 		return nil, err
 	}
 	return channel, nil
 }
+
+// ListChannels returns channels of the organization in the given page and the total number of them.
+func (s *ChannelService) ListChannels(ctx context.Context, organizationID int, pagination domain.Pagination) ([]domain.Channel, int, domain.Error) {
+	channels, total, err := s.channelRepo.ListChannels(ctx, organizationID, pagination)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Msg("failed to list channels")
+		return nil, 0, err
+	}
+	return channels, total, nil
+}
+
+// RevealChannelSecret returns the plaintext channel secret. Every reveal is recorded in the audit
+// log first, and the secret is not returned if it cannot be recorded.
+func (s *ChannelService) RevealChannelSecret(ctx context.Context, organizationID, channelID int, actor domain.Actor) (string, domain.Error) {
+	channel, err := s.channelRepo.GetChannelByID(ctx, organizationID, channelID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to get channel")
+		return "", err
+	}
+
+	_, err = s.auditLogRepo.CreateAuditLog(ctx, domain.AuditLog{
+		Actor:        actor,
+		Action:       domain.AuditActionRevealChannelSecret,
+		ResourceType: domain.AuditResourceTypeChannel,
+		ResourceID:   strconv.Itoa(channel.ID),
+	})
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to create audit log")
+		return "", err
+	}
+
+	s.logger(ctx).Info().
+		Int("channelID", channelID).
+		Str("actorID", actor.ID).
+		Msg("channel secret is revealed")
+	return channel.ExternalChannelSecret, nil
+}
+
+type UpdateChannelParam struct {
+	Name                  *string
+	ExternalChannelSecret *string
+	AccessTokenVersion    *domain.AccessTokenVersion
+	AssertionKeyID        *string
+	AssertionPrivateKey   *string
+}
+
+// UpdateChannel renames the channel, rotates its secret or changes how its access tokens are issued.
+// Changing credentials would issue a new access token with them, so invalid credentials are
+// rejected before they are stored. The replaced access token is revoked afterwards.
+func (s *ChannelService) UpdateChannel(ctx context.Context, organizationID, channelID int, param UpdateChannelParam) (*domain.Channel, domain.Error) {
+	params := domain.UpdateChannelParams{
+		Name: param.Name,
+	}
+
+	var token *domain.AccessToken
+	var replaced domain.Channel
+	if param.ExternalChannelSecret != nil || param.AccessTokenVersion != nil || param.AssertionKeyID != nil || param.AssertionPrivateKey != nil {
+		channel, err := s.channelRepo.GetChannelByID(ctx, organizationID, channelID)
+		if err != nil {
+			s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to get channel")
+			return nil, err
+		}
+
+		replaced = *channel
+
+		// Apply new credentials to the current channel to issue the new access token
+		if param.ExternalChannelSecret != nil {
+			channel.ExternalChannelSecret = *param.ExternalChannelSecret
+		}
+		if param.AssertionKeyID != nil || param.AssertionPrivateKey != nil {
+			// A new assertion key implies v2.1 access tokens unless the version is given explicitly
+			channel.AccessTokenVersion = domain.AccessTokenVersionV21
+		}
+		if param.AssertionKeyID != nil {
+			channel.AssertionKeyID = *param.AssertionKeyID
+		}
+		if param.AssertionPrivateKey != nil {
+			channel.AssertionPrivateKey = *param.AssertionPrivateKey
+		}
+		if param.AccessTokenVersion != nil {
+			channel.AccessTokenVersion = *param.AccessTokenVersion
+		}
+
+		token, err = s.issueAccessToken(ctx, *channel)
+		if err != nil {
+			s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to issue access token from Line")
+			return nil, err
+		}
+
+		params.ExternalChannelSecret = param.ExternalChannelSecret
+		params.AccessTokenVersion = &channel.AccessTokenVersion
+		params.AssertionKeyID = param.AssertionKeyID
+		params.AssertionPrivateKey = param.AssertionPrivateKey
+		params.AccessToken = &token.Token
+		params.AccessTokenExpiredAt = &token.ExpiredAt
+		params.AccessTokenKeyID = &token.KeyID
+
+		// Check the new credentials in the next round of health check
+		checkAfter := time.Now()
+		params.HealthCheckAfter = &checkAfter
+	}
+
+	channel, err := s.channelRepo.UpdateChannel(ctx, organizationID, channelID, params)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to update channel")
+		return nil, err
+	}
+	s.invalidateChannel(ctx, channel.ExternalChannelID)
+
+	if token != nil {
+		s.trackAccessToken(ctx, channel.ID, *token)
+		s.revokeReplacedAccessToken(ctx, replaced, channel.ExternalChannelSecret)
+	}
+	return channel, nil
+}
+
+// DeleteChannel deletes the channel and revokes its access tokens, including v2.1 access tokens
+// which are not in use but still valid.
+func (s *ChannelService) DeleteChannel(ctx context.Context, organizationID, channelID int) domain.Error {
+	channel, err := s.channelRepo.GetChannelByID(ctx, organizationID, channelID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to get channel")
+		return err
+	}
+
+	// Tracked access tokens are deleted with the channel, so list them beforehand
+	tokens, err := s.accessTokenRepo.ListChannelAccessTokens(ctx, channelID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to list access tokens")
+		return err
+	}
+
+	err = s.channelRepo.DeleteChannel(ctx, organizationID, channelID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to delete channel")
+		return err
+	}
+	s.invalidateChannel(ctx, channel.ExternalChannelID)
+
+	s.revokeAccessToken(ctx, *channel, channel.ExternalChannelSecret)
+	for _, token := range tokens {
+		if token.RevokedAt != nil || token.AccessToken == channel.AccessToken {
+			continue
+		}
+		s.revoke(ctx, domain.AccessTokenRevocation{
+			ExternalChannelID:     channel.ExternalChannelID,
+			ExternalChannelSecret: channel.ExternalChannelSecret,
+			AccessToken:           token.AccessToken,
+			AccessTokenVersion:    domain.AccessTokenVersionV21,
+			AccessTokenKeyID:      token.KeyID,
+			AccessTokenExpiredAt:  token.ExpiredAt,
+		})
+	}
+	return nil
+}
+
+// ReencryptChannels encrypts credentials of all channels with the current encryption key. It is used
+// to migrate plaintext rows and to rotate the key.
+func (s *ChannelService) ReencryptChannels(ctx context.Context) (int, domain.Error) {
+	count, err := s.channelRepo.ReencryptChannels(ctx)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("count", count).Msg("failed to re-encrypt channels")
+		return count, err
+	}
+
+	s.logger(ctx).Info().Int("count", count).Msg("channels are re-encrypted")
+	return count, nil
+}
@@ -0,0 +1,56 @@
+package channel
+
+import (
+	"context"
+	"time"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+const (
+	// healthCheckBatchSize is the number of channels claimed at once for health check
+	healthCheckBatchSize = 10
+	// healthCheckLease is how long a claimed channel is held before others could claim it again
+	healthCheckLease = 5 * time.Minute
+)
+
+// CheckChannelsHealth verifies channels which are due to be checked by getting their bot info from
+// LINE, and stores the result on the channels. Each channel is checked again after the health check
+// interval.
+func (s *ChannelService) CheckChannelsHealth(ctx context.Context) domain.Error {
+	for {
+		channels, err := s.channelRepo.ClaimChannelsForHealthCheck(ctx, time.Now().Add(healthCheckLease), healthCheckBatchSize)
+		if err != nil {
+			s.logger(ctx).Error().Err(err).Msg("failed to claim channels for health check")
+			return err
+		}
+
+		for _, channel := range channels {
+			s.checkChannelHealth(ctx, channel)
+		}
+
+		if len(channels) < healthCheckBatchSize || ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+func (s *ChannelService) checkChannelHealth(ctx context.Context, channel domain.Channel) {
+	status, healthError := domain.ChannelHealthStatusHealthy, ""
+	if _, err := s.lineService.GetChannelInfo(ctx, channel.AccessToken); err != nil {
+		status, healthError = domain.ChannelHealthStatusUnhealthy, err.Error()
+		s.logger(ctx).Warn().Err(err).Int("channelID", channel.ID).Msg("channel is unhealthy")
+	}
+
+	checkedAt := time.Now()
+	checkAfter := checkedAt.Add(s.healthCheckInterval)
+	_, err := s.channelRepo.UpdateChannel(ctx, channel.OrganizationID, channel.ID, domain.UpdateChannelParams{
+		HealthStatus:     &status,
+		HealthCheckedAt:  &checkedAt,
+		HealthError:      &healthError,
+		HealthCheckAfter: &checkAfter,
+	})
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channel.ID).Msg("failed to save channel health")
+	}
+}
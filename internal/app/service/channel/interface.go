@@ -12,10 +12,49 @@ import (
 //go:generate mockgen -destination automock/channel_repository.go -package=automock . ChannelRepository
 type ChannelRepository interface {
 	CreateChannel(ctx context.Context, channel domain.Channel) (*domain.Channel, domain.Error)
+	GetChannelByID(ctx context.Context, organizationID, channelID int) (*domain.Channel, domain.Error)
+	ListChannels(ctx context.Context, organizationID int, pagination domain.Pagination) ([]domain.Channel, int, domain.Error)
+	UpdateChannel(ctx context.Context, organizationID, channelID int, params domain.UpdateChannelParams) (*domain.Channel, domain.Error)
+	DeleteChannel(ctx context.Context, organizationID, channelID int) domain.Error
+	ReencryptChannels(ctx context.Context) (int, domain.Error)
+	ClaimChannelsForTokenRefresh(ctx context.Context, expireBefore, leaseUntil time.Time, limit int) ([]domain.Channel, domain.Error)
+	ClaimChannelsForHealthCheck(ctx context.Context, leaseUntil time.Time, limit int) ([]domain.Channel, domain.Error)
+}
+
+// ChannelCache caches channels for the webhook, which has to be told when a channel changes
+//
+//go:generate mockgen -destination automock/channel_cache.go -package=automock . ChannelCache
+type ChannelCache interface {
+	InvalidateChannel(ctx context.Context, externalChannelID string)
+}
+
+//go:generate mockgen -destination automock/channel_access_token_repository.go -package=automock . ChannelAccessTokenRepository
+type ChannelAccessTokenRepository interface {
+	CreateChannelAccessToken(ctx context.Context, token domain.ChannelAccessToken) (*domain.ChannelAccessToken, domain.Error)
+	ListChannelAccessTokens(ctx context.Context, channelID int) ([]domain.ChannelAccessToken, domain.Error)
+	GetChannelAccessTokenByID(ctx context.Context, channelID, tokenID int) (*domain.ChannelAccessToken, domain.Error)
+	MarkChannelAccessTokenRevoked(ctx context.Context, keyID string) domain.Error
+}
+
+//go:generate mockgen -destination automock/access_token_revocation_repository.go -package=automock . AccessTokenRevocationRepository
+type AccessTokenRevocationRepository interface {
+	CreateAccessTokenRevocation(ctx context.Context, revocation domain.AccessTokenRevocation) (*domain.AccessTokenRevocation, domain.Error)
+	ClaimAccessTokenRevocations(ctx context.Context, leaseUntil time.Time, limit int) ([]domain.AccessTokenRevocation, domain.Error)
+	UpdateAccessTokenRevocation(ctx context.Context, revocationID int, params domain.UpdateAccessTokenRevocationParams) domain.Error
+}
+
+//go:generate mockgen -destination automock/audit_log_repository.go -package=automock . AuditLogRepository
+type AuditLogRepository interface {
+	CreateAuditLog(ctx context.Context, log domain.AuditLog) (*domain.AuditLog, domain.Error)
 }
 
 //go:generate mockgen -destination automock/line_service.go -package=automock . LineService
 type LineService interface {
 	IssueAccessToken(ctx context.Context, ExternalChannelID string, ExternalChannelSecret string) (string, time.Time, domain.Error)
+	IssueAccessTokenV21(ctx context.Context, externalChannelID, assertionKeyID, assertionPrivateKey string) (*domain.AccessToken, domain.Error)
+	RevokeAccessToken(ctx context.Context, accessToken string) domain.Error
+	RevokeAccessTokenV21(ctx context.Context, externalChannelID, externalChannelSecret, accessToken string) domain.Error
 	GetChannelInfo(ctx context.Context, accessToken string) (*linebot.BotInfoResponse, domain.Error)
+	SetWebhookEndpoint(ctx context.Context, accessToken, endpoint string) domain.Error
+	TestWebhookEndpoint(ctx context.Context, accessToken, endpoint string) (*domain.LineWebhookTestResult, domain.Error)
 }
@@ -0,0 +1,180 @@
+package channel
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+const (
+	// revocationRetryBatchSize is the number of revocations claimed at once for retry
+	revocationRetryBatchSize = 10
+	// revocationRetryLease is how long a claimed revocation is held before others could claim it again
+	revocationRetryLease = 5 * time.Minute
+)
+
+// newAccessTokenRevocation builds the revocation of the access token of the channel. v2.1 access
+// tokens are revoked with the given channel secret, since the old one might have been rotated already.
+func newAccessTokenRevocation(channel domain.Channel, externalChannelSecret string) domain.AccessTokenRevocation {
+	return domain.AccessTokenRevocation{
+		ExternalChannelID:     channel.ExternalChannelID,
+		ExternalChannelSecret: externalChannelSecret,
+		AccessToken:           channel.AccessToken,
+		AccessTokenVersion:    channel.AccessTokenVersion,
+		AccessTokenKeyID:      channel.AccessTokenKeyID,
+		AccessTokenExpiredAt:  channel.AccessTokenExpiredAt,
+	}
+}
+
+// revokeAccessToken revokes the access token of the deleted channel right away. If LINE fails to
+// revoke it, the revocation is recorded and retried by RetryAccessTokenRevocations.
+func (s *ChannelService) revokeAccessToken(ctx context.Context, channel domain.Channel, externalChannelSecret string) {
+	s.revoke(ctx, newAccessTokenRevocation(channel, externalChannelSecret))
+}
+
+// revokeReplacedAccessToken schedules the revocation of the access token replaced by a new one.
+// Events published before the replacement still carry the old access token, so it is revoked by
+// RetryAccessTokenRevocations after the grace period instead of right away.
+func (s *ChannelService) revokeReplacedAccessToken(ctx context.Context, channel domain.Channel, externalChannelSecret string) {
+	revocation := newAccessTokenRevocation(channel, externalChannelSecret)
+	revocation.NextAttemptAt = time.Now().Add(s.tokenRevocationGrace)
+	if revocation.AccessToken == "" || !revocation.AccessTokenExpiredAt.After(revocation.NextAttemptAt) {
+		// the access token expires within the grace period anyway
+		return
+	}
+
+	_, err := s.revocationRepo.CreateAccessTokenRevocation(ctx, revocation)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).
+			Str("externalChannelID", revocation.ExternalChannelID).
+			Msg("failed to schedule access token revocation")
+		return
+	}
+	s.logger(ctx).Info().
+		Str("externalChannelID", revocation.ExternalChannelID).
+		Time("revokeAt", revocation.NextAttemptAt).
+		Msg("replaced access token would be revoked after the grace period")
+}
+
+func (s *ChannelService) revoke(ctx context.Context, revocation domain.AccessTokenRevocation) {
+	if revocation.AccessToken == "" || !revocation.AccessTokenExpiredAt.After(time.Now()) {
+		return
+	}
+
+	err := s.revokeAtLine(ctx, revocation)
+	if err == nil {
+		return
+	}
+	if !isRetryableRevocationError(err) {
+		s.logger(ctx).Warn().Err(err).
+			Str("externalChannelID", revocation.ExternalChannelID).
+			Msg("access token is rejected by LINE and would not be revoked again")
+		return
+	}
+
+	revocation.Attempts = 1
+	revocation.LastError = err.Error()
+	revocation.NextAttemptAt = time.Now().Add(retryBackoff.Delay(revocation.Attempts))
+	_, err = s.revocationRepo.CreateAccessTokenRevocation(ctx, revocation)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).
+			Str("externalChannelID", revocation.ExternalChannelID).
+			Msg("failed to record access token revocation")
+		return
+	}
+	s.logger(ctx).Warn().
+		Str("externalChannelID", revocation.ExternalChannelID).
+		Time("retryAt", revocation.NextAttemptAt).
+		Msg("failed to revoke access token, and it would be retried")
+}
+
+// revokeAtLine revokes the access token with the endpoint of its version
+func (s *ChannelService) revokeAtLine(ctx context.Context, revocation domain.AccessTokenRevocation) domain.Error {
+	var err domain.Error
+	switch revocation.AccessTokenVersion {
+	case domain.AccessTokenVersionV21:
+		err = s.lineService.RevokeAccessTokenV21(ctx, revocation.ExternalChannelID, revocation.ExternalChannelSecret, revocation.AccessToken)
+	default:
+		err = s.lineService.RevokeAccessToken(ctx, revocation.AccessToken)
+	}
+	if err != nil {
+		return err
+	}
+
+	if revocation.AccessTokenKeyID != "" {
+		if err := s.accessTokenRepo.MarkChannelAccessTokenRevoked(ctx, revocation.AccessTokenKeyID); err != nil {
+			// the token is revoked at LINE anyway, so we only log the failure here
+			s.logger(ctx).Error().Err(err).
+				Str("keyID", revocation.AccessTokenKeyID).
+				Msg("failed to mark access token revoked")
+		}
+	}
+	return nil
+}
+
+// isRetryableRevocationError tells whether the revocation might succeed later. Client errors other
+// than rate limiting mean LINE doesn't accept the token or the credentials, so retrying is useless.
+func isRetryableRevocationError(err domain.Error) bool {
+	extErr, ok := err.(domain.ExternalError)
+	if !ok {
+		return true
+	}
+	code := extErr.StatusCode()
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// RetryAccessTokenRevocations retries the recorded revocations which are due. Revocations are
+// retried with exponential backoff until they succeed or the access token expires.
+func (s *ChannelService) RetryAccessTokenRevocations(ctx context.Context) domain.Error {
+	for {
+		revocations, err := s.revocationRepo.ClaimAccessTokenRevocations(ctx, time.Now().Add(revocationRetryLease), revocationRetryBatchSize)
+		if err != nil {
+			s.logger(ctx).Error().Err(err).Msg("failed to claim access token revocations")
+			return err
+		}
+
+		for _, revocation := range revocations {
+			s.retryRevocation(ctx, revocation)
+		}
+
+		if len(revocations) < revocationRetryBatchSize || ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+func (s *ChannelService) retryRevocation(ctx context.Context, revocation domain.AccessTokenRevocation) {
+	var params domain.UpdateAccessTokenRevocationParams
+
+	err := s.revokeAtLine(ctx, revocation)
+	switch {
+	case err == nil:
+		now := time.Now()
+		params.RevokedAt = &now
+		s.logger(ctx).Info().Int("revocationID", revocation.ID).Msg("access token is revoked")
+	case !isRetryableRevocationError(err):
+		// Stop retrying by leaving the next attempt after the token expires
+		attempts, lastError := revocation.Attempts+1, err.Error()
+		params.Attempts = &attempts
+		params.LastError = &lastError
+		params.NextAttemptAt = &revocation.AccessTokenExpiredAt
+		s.logger(ctx).Warn().Err(err).Int("revocationID", revocation.ID).Msg("access token is rejected by LINE and would not be revoked again")
+	default:
+		attempts, lastError := revocation.Attempts+1, err.Error()
+		retryAt := time.Now().Add(retryBackoff.Delay(attempts))
+		params.Attempts = &attempts
+		params.LastError = &lastError
+		params.NextAttemptAt = &retryAt
+		s.logger(ctx).Error().Err(err).
+			Int("revocationID", revocation.ID).
+			Int("attempts", attempts).
+			Time("retryAt", retryAt).
+			Msg("failed to revoke access token")
+	}
+
+	if err := s.revocationRepo.UpdateAccessTokenRevocation(ctx, revocation.ID, params); err != nil {
+		s.logger(ctx).Error().Err(err).Int("revocationID", revocation.ID).Msg("failed to update access token revocation")
+	}
+}
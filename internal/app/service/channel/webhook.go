@@ -0,0 +1,52 @@
+package channel
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+// webhookEndpoint returns the URL where LINE delivers webhook events of the channel
+func (s *ChannelService) webhookEndpoint(externalChannelID string) string {
+	return fmt.Sprintf("%s/api/v1/webhook/line/%s/events", strings.TrimRight(s.publicBaseURL, "/"), url.PathEscape(externalChannelID))
+}
+
+// configureWebhook sets the webhook endpoint of the channel at LINE and asks LINE to test it. The
+// channel works without the webhook being configured, so failures are reported in the status
+// instead of being returned as errors.
+func (s *ChannelService) configureWebhook(ctx context.Context, channel domain.Channel) *domain.LineWebhookStatus {
+	status := &domain.LineWebhookStatus{
+		Endpoint: s.webhookEndpoint(channel.ExternalChannelID),
+	}
+
+	err := s.lineService.SetWebhookEndpoint(ctx, channel.AccessToken, status.Endpoint)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channel.ID).Msg("failed to set webhook endpoint")
+		status.Reason = "failed to set webhook endpoint"
+		status.Detail = err.Error()
+		return status
+	}
+	status.Configured = true
+
+	result, err := s.lineService.TestWebhookEndpoint(ctx, channel.AccessToken, status.Endpoint)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channel.ID).Msg("failed to test webhook endpoint")
+		status.Reason = "failed to test webhook endpoint"
+		status.Detail = err.Error()
+		return status
+	}
+	status.Reachable = result.Success
+	status.StatusCode = result.StatusCode
+	status.Reason = result.Reason
+	status.Detail = result.Detail
+
+	s.logger(ctx).Info().
+		Int("channelID", channel.ID).
+		Str("endpoint", status.Endpoint).
+		Bool("reachable", status.Reachable).
+		Msg("webhook endpoint is configured")
+	return status
+}
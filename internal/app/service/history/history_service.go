@@ -0,0 +1,57 @@
+package history
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rs/zerolog"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+// HistoryService searches messages sent to channels. Messages are recorded from webhook events by
+// MessageService.
+type HistoryService struct {
+	channelRepo ChannelRepository
+	inboundRepo InboundMessageRepository
+}
+
+type HistoryServiceParam struct {
+	ChannelRepo ChannelRepository
+	InboundRepo InboundMessageRepository
+}
+
+func NewHistoryService(_ context.Context, param HistoryServiceParam) *HistoryService {
+	return &HistoryService{
+		channelRepo: param.ChannelRepo,
+		inboundRepo: param.InboundRepo,
+	}
+}
+
+// logger wrap the execution context with component info
+func (s *HistoryService) logger(ctx context.Context) *zerolog.Logger {
+	l := zerolog.Ctx(ctx).With().Str("service", "history").Logger()
+	return &l
+}
+
+// SearchInboundMessages returns messages of the channel matching the filter in the given page, latest
+// first, and the total number of them
+func (s *HistoryService) SearchInboundMessages(ctx context.Context, organizationID, channelID int, filter domain.InboundMessageFilter, pagination domain.Pagination) ([]domain.InboundMessage, int, domain.Error) {
+	if filter.SentFrom != nil && filter.SentTo != nil && !filter.SentFrom.Before(*filter.SentTo) {
+		msg := "from must be before to"
+		return nil, 0, domain.NewParameterError(msg, errors.New(msg))
+	}
+
+	// Make sure the channel belongs to the organization
+	if _, err := s.channelRepo.GetChannelByID(ctx, organizationID, channelID); err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to get channel")
+		return nil, 0, err
+	}
+
+	messages, total, err := s.inboundRepo.ListInboundMessages(ctx, channelID, filter, pagination)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to search inbound messages")
+		return nil, 0, err
+	}
+	return messages, total, nil
+}
@@ -0,0 +1,17 @@
+package history
+
+import (
+	"context"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+//go:generate mockgen -destination automock/channel_repository.go -package=automock . ChannelRepository
+type ChannelRepository interface {
+	GetChannelByID(ctx context.Context, organizationID, channelID int) (*domain.Channel, domain.Error)
+}
+
+//go:generate mockgen -destination automock/inbound_message_repository.go -package=automock . InboundMessageRepository
+type InboundMessageRepository interface {
+	ListInboundMessages(ctx context.Context, channelID int, filter domain.InboundMessageFilter, pagination domain.Pagination) ([]domain.InboundMessage, int, domain.Error)
+}
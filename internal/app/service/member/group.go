@@ -0,0 +1,42 @@
+package member
+
+import (
+	"context"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+// ListChannelGroups returns groups and rooms of the channel in the given page and the total number of
+// them
+func (s *MemberService) ListChannelGroups(ctx context.Context, organizationID, channelID int, pagination domain.Pagination) ([]domain.ChannelGroup, int, domain.Error) {
+	// Make sure the channel belongs to the organization
+	if _, err := s.channelRepo.GetChannelByID(ctx, organizationID, channelID); err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to get channel")
+		return nil, 0, err
+	}
+
+	groups, total, err := s.groupRepo.ListChannelGroups(ctx, channelID, pagination)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to list channel groups")
+		return nil, 0, err
+	}
+	return groups, total, nil
+}
+
+func (s *MemberService) GetChannelGroup(ctx context.Context, organizationID, channelID int, externalGroupID string) (*domain.ChannelGroup, domain.Error) {
+	// Make sure the channel belongs to the organization
+	if _, err := s.channelRepo.GetChannelByID(ctx, organizationID, channelID); err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to get channel")
+		return nil, err
+	}
+
+	group, err := s.groupRepo.GetChannelGroup(ctx, channelID, externalGroupID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).
+			Int("channelID", channelID).
+			Str("externalGroupID", externalGroupID).
+			Msg("failed to get channel group")
+		return nil, err
+	}
+	return group, nil
+}
@@ -0,0 +1,24 @@
+package member
+
+import (
+	"context"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+//go:generate mockgen -destination automock/channel_repository.go -package=automock . ChannelRepository
+type ChannelRepository interface {
+	GetChannelByID(ctx context.Context, organizationID, channelID int) (*domain.Channel, domain.Error)
+}
+
+//go:generate mockgen -destination automock/channel_group_repository.go -package=automock . ChannelGroupRepository
+type ChannelGroupRepository interface {
+	GetChannelGroup(ctx context.Context, channelID int, externalGroupID string) (*domain.ChannelGroup, domain.Error)
+	ListChannelGroups(ctx context.Context, channelID int, pagination domain.Pagination) ([]domain.ChannelGroup, int, domain.Error)
+}
+
+//go:generate mockgen -destination automock/channel_member_repository.go -package=automock . ChannelMemberRepository
+type ChannelMemberRepository interface {
+	GetChannelMember(ctx context.Context, channelID int, externalMemberID string) (*domain.ChannelMember, domain.Error)
+	ListChannelMembers(ctx context.Context, channelID int, filter domain.ChannelMemberFilter, pagination domain.Pagination) ([]domain.ChannelMember, int, domain.Error)
+}
@@ -0,0 +1,71 @@
+package member
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+// MemberService queries members of channels, and groups and rooms channels are in. They are
+// recorded from webhook events by MessageService.
+type MemberService struct {
+	channelRepo ChannelRepository
+	memberRepo  ChannelMemberRepository
+	groupRepo   ChannelGroupRepository
+}
+
+type MemberServiceParam struct {
+	ChannelRepo ChannelRepository
+	MemberRepo  ChannelMemberRepository
+	GroupRepo   ChannelGroupRepository
+}
+
+func NewMemberService(_ context.Context, param MemberServiceParam) *MemberService {
+	return &MemberService{
+		channelRepo: param.ChannelRepo,
+		memberRepo:  param.MemberRepo,
+		groupRepo:   param.GroupRepo,
+	}
+}
+
+// logger wrap the execution context with component info
+func (s *MemberService) logger(ctx context.Context) *zerolog.Logger {
+	l := zerolog.Ctx(ctx).With().Str("service", "member").Logger()
+	return &l
+}
+
+// ListChannelMembers returns members of the channel in the given page and the total number of them
+func (s *MemberService) ListChannelMembers(ctx context.Context, organizationID, channelID int, filter domain.ChannelMemberFilter, pagination domain.Pagination) ([]domain.ChannelMember, int, domain.Error) {
+	// Make sure the channel belongs to the organization
+	if _, err := s.channelRepo.GetChannelByID(ctx, organizationID, channelID); err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to get channel")
+		return nil, 0, err
+	}
+
+	members, total, err := s.memberRepo.ListChannelMembers(ctx, channelID, filter, pagination)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to list channel members")
+		return nil, 0, err
+	}
+	return members, total, nil
+}
+
+func (s *MemberService) GetChannelMember(ctx context.Context, organizationID, channelID int, externalMemberID string) (*domain.ChannelMember, domain.Error) {
+	// Make sure the channel belongs to the organization
+	if _, err := s.channelRepo.GetChannelByID(ctx, organizationID, channelID); err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to get channel")
+		return nil, err
+	}
+
+	member, err := s.memberRepo.GetChannelMember(ctx, channelID, externalMemberID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).
+			Int("channelID", channelID).
+			Str("externalMemberID", externalMemberID).
+			Msg("failed to get channel member")
+		return nil, err
+	}
+	return member, nil
+}
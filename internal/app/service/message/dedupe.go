@@ -0,0 +1,48 @@
+package message
+
+import (
+	"context"
+	"time"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+// storeWebhookEvents stores the events in the outbox, except those received before. Events without a
+// webhook event ID are always stored.
+func (s *MessageService) storeWebhookEvents(ctx context.Context, events []domain.LineEvent, outboxEvents []domain.WebhookOutboxEvent) domain.Error {
+	dropped, err := s.eventStore.StoreWebhookEvents(ctx, outboxEvents, time.Now().Add(s.dedupeTTL))
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("count", len(outboxEvents)).Msg("failed to store events in outbox")
+		return err
+	}
+
+	if len(dropped) == 0 {
+		return nil
+	}
+	duplicates := make(map[string]bool, len(dropped))
+	for _, id := range dropped {
+		duplicates[id] = true
+	}
+	for _, e := range events {
+		if duplicates[e.WebhookEventID] {
+			s.logger(ctx).Info().
+				Str("webhookEventID", e.WebhookEventID).
+				Bool("isRedelivery", e.IsRedelivery).
+				Msg("drop duplicate webhook event")
+		}
+	}
+	return nil
+}
+
+// PurgeExpiredWebhookEvents deletes marks of webhook events which have expired
+func (s *MessageService) PurgeExpiredWebhookEvents(ctx context.Context) domain.Error {
+	count, err := s.eventStore.DeleteExpiredWebhookEvents(ctx, time.Now())
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Msg("failed to purge expired webhook events")
+		return err
+	}
+	if count > 0 {
+		s.logger(ctx).Info().Int("count", count).Msg("expired webhook events are purged")
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package message
+
+import (
+	"context"
+	"time"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+const (
+	// groupSummaryRefreshBatchSize is the number of groups claimed at once for summary refresh
+	groupSummaryRefreshBatchSize = 20
+	// groupSummaryRefreshLease is how long a claimed group is held before others could claim it again
+	groupSummaryRefreshLease = 5 * time.Minute
+)
+
+// recordChannelGroup keeps the group or the room of the event's source up to date. Join and leave
+// events change whether the channel is in it, and every event marks it as seen. Summaries are fetched
+// by RefreshGroupSummaries in the background, which is brought forward when the channel joins or the
+// members change.
+func (s *MessageService) recordChannelGroup(ctx context.Context, channel domain.Channel, e domain.LineEvent) {
+	params := domain.UpsertChannelGroupParams{
+		ChannelID:       channel.ID,
+		ExternalGroupID: e.SourceGroupID(),
+		Type:            e.SourceType,
+	}
+	if params.ExternalGroupID == "" {
+		return
+	}
+
+	at := e.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	params.SeenAt = at
+	switch e.EventType {
+	case domain.LineEventTypeJoin:
+		params.JoinedAt = &at
+		params.RefreshSummary = true
+	case domain.LineEventTypeLeave:
+		params.LeftAt = &at
+	case domain.LineEventTypeMemberJoined, domain.LineEventTypeMemberLeft:
+		params.RefreshSummary = true
+	}
+
+	if _, err := s.groupRepo.UpsertChannelGroup(ctx, params); err != nil {
+		// the event is still forwarded, so we only log the failure here
+		s.logger(ctx).Error().Err(err).
+			Int("channelID", channel.ID).
+			Str("externalGroupID", params.ExternalGroupID).
+			Msg("failed to record channel group")
+	}
+}
+
+// RefreshGroupSummaries fetches summaries of groups which are new, expired or whose members have
+// changed. Groups failed to be fetched are retried later with exponential backoff, and the stale
+// summary is kept meanwhile.
+func (s *MessageService) RefreshGroupSummaries(ctx context.Context) domain.Error {
+	for {
+		groups, err := s.groupRepo.ClaimChannelGroupsForSummaryRefresh(ctx, time.Now().Add(groupSummaryRefreshLease), groupSummaryRefreshBatchSize)
+		if err != nil {
+			s.logger(ctx).Error().Err(err).Msg("failed to claim channel groups for summary refresh")
+			return err
+		}
+
+		channelIDs := make([]int, 0, len(groups))
+		for _, group := range groups {
+			channelIDs = append(channelIDs, group.ChannelID)
+		}
+		channels, err := s.getChannels(ctx, channelIDs)
+		if err != nil {
+			return err
+		}
+
+		for _, group := range groups {
+			// groups of deleted channels are deleted along with them
+			if channel, ok := channels[group.ChannelID]; ok {
+				s.refreshGroupSummary(ctx, channel, group)
+			}
+		}
+
+		if len(groups) < groupSummaryRefreshBatchSize || ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// refreshGroupSummary fetches the group's summary from LINE, and schedules the next refresh
+func (s *MessageService) refreshGroupSummary(ctx context.Context, channel domain.Channel, group domain.ChannelGroup) {
+	var params domain.UpdateChannelGroupSummaryParams
+
+	summary, err := s.lineService.GetGroupSummary(ctx, channel.AccessToken, group.Type, group.ExternalGroupID)
+	if err == nil {
+		now := time.Now()
+		attempts, refreshAfter := 0, now.Add(s.profileTTL)
+		params.Summary = summary
+		params.SummaryFetchedAt = &now
+		params.SummaryRefreshAttempts = &attempts
+		params.SummaryRefreshAfter = &refreshAfter
+	} else {
+		attempts := group.SummaryRefreshAttempts + 1
+		retryAt := time.Now().Add(profileBackoff.Delay(attempts))
+		params.SummaryRefreshAttempts = &attempts
+		params.SummaryRefreshAfter = &retryAt
+		s.logger(ctx).Warn().Err(err).
+			Int("channelID", channel.ID).
+			Str("externalGroupID", group.ExternalGroupID).
+			Int("attempts", attempts).
+			Time("retryAt", retryAt).
+			Msg("failed to get group summary")
+	}
+
+	if err := s.groupRepo.UpdateChannelGroupSummary(ctx, group.ID, params); err != nil {
+		// the group would be claimed again after the lease
+		s.logger(ctx).Error().Err(err).
+			Int("channelID", channel.ID).
+			Str("externalGroupID", group.ExternalGroupID).
+			Msg("failed to update group summary")
+	}
+}
@@ -0,0 +1,44 @@
+package message
+
+import (
+	"context"
+	"time"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+// recordInboundMessage stores the message of the event in the history. Events other than messages are
+// not stored.
+func (s *MessageService) recordInboundMessage(ctx context.Context, channel domain.Channel, e domain.LineEvent) {
+	if e.EventType != domain.LineEventTypeMessage || e.Message == nil {
+		return
+	}
+
+	sentAt := e.Timestamp
+	if sentAt.IsZero() {
+		sentAt = time.Now()
+	}
+	externalGroupID := e.ExternalGroupID
+	if e.SourceType == domain.LineSourceTypeRoom {
+		externalGroupID = e.ExternalRoomID
+	}
+
+	err := s.inboundRepo.CreateInboundMessage(ctx, domain.InboundMessage{
+		ChannelID:         channel.ID,
+		ExternalMessageID: e.Message.ID,
+		ExternalMemberID:  e.ExternalMemberID,
+		SourceType:        e.SourceType,
+		ExternalGroupID:   externalGroupID,
+		MessageType:       e.Message.Type,
+		Text:              e.Message.Text,
+		Raw:               e.EventContent,
+		SentAt:            sentAt,
+	})
+	if err != nil {
+		// the event is still forwarded, so we only log the failure here
+		s.logger(ctx).Error().Err(err).
+			Int("channelID", channel.ID).
+			Str("externalMessageID", e.Message.ID).
+			Msg("failed to record inbound message")
+	}
+}
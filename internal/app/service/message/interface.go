@@ -2,6 +2,7 @@ package message
 
 import (
 	"context"
+	"time"
 
 	"github.com/david7482/aws-serverless-service/internal/domain"
 )
@@ -9,16 +10,58 @@ import (
 //go:generate mockgen -destination automock/channel_repository.go -package=automock . ChannelRepository
 type ChannelRepository interface {
 	GetChannelByExternalID(ctx context.Context, externalChannelID string) (*domain.Channel, domain.Error)
-	GetChannelByID(ctx context.Context, channelID int) (*domain.Channel, domain.Error)
+	GetChannelByID(ctx context.Context, organizationID, channelID int) (*domain.Channel, domain.Error)
+	GetChannelsByIDs(ctx context.Context, channelIDs []int) ([]domain.Channel, domain.Error)
+}
+
+//go:generate mockgen -destination automock/channel_member_repository.go -package=automock . ChannelMemberRepository
+type ChannelMemberRepository interface {
+	UpsertChannelMember(ctx context.Context, params domain.UpsertChannelMemberParams) (*domain.ChannelMember, domain.Error)
+	UpdateChannelMemberProfile(ctx context.Context, memberID int, params domain.UpdateChannelMemberProfileParams) domain.Error
+	ClaimChannelMembersForProfileRefresh(ctx context.Context, leaseUntil time.Time, limit int) ([]domain.ChannelMember, domain.Error)
+}
+
+//go:generate mockgen -destination automock/channel_group_repository.go -package=automock . ChannelGroupRepository
+type ChannelGroupRepository interface {
+	UpsertChannelGroup(ctx context.Context, params domain.UpsertChannelGroupParams) (*domain.ChannelGroup, domain.Error)
+	UpdateChannelGroupSummary(ctx context.Context, groupID int, params domain.UpdateChannelGroupSummaryParams) domain.Error
+	ClaimChannelGroupsForSummaryRefresh(ctx context.Context, leaseUntil time.Time, limit int) ([]domain.ChannelGroup, domain.Error)
+}
+
+//go:generate mockgen -destination automock/inbound_message_repository.go -package=automock . InboundMessageRepository
+type InboundMessageRepository interface {
+	CreateInboundMessage(ctx context.Context, message domain.InboundMessage) domain.Error
+}
+
+//go:generate mockgen -destination automock/outbox_event_repository.go -package=automock . OutboxEventRepository
+type OutboxEventRepository interface {
+	ClaimOutboxEvents(ctx context.Context, leaseUntil time.Time, limit int) ([]domain.OutboxEvent, domain.Error)
+	UpdateOutboxEvent(ctx context.Context, eventID int64, params domain.UpdateOutboxEventParams) domain.Error
+	DeleteSentOutboxEvents(ctx context.Context, sentBefore time.Time) (int, domain.Error)
+}
+
+// WebhookEventStore stores webhook events in the outbox and remembers them, so redeliveries are
+// dropped. An event is either both stored and remembered or neither.
+//
+//go:generate mockgen -destination automock/webhook_event_store.go -package=automock . WebhookEventStore
+type WebhookEventStore interface {
+	StoreWebhookEvents(ctx context.Context, events []domain.WebhookOutboxEvent, expireAt time.Time) ([]string, domain.Error)
+	DeleteExpiredWebhookEvents(ctx context.Context, expiredBefore time.Time) (int, domain.Error)
 }
 
 //go:generate mockgen -destination automock/line_service.go -package=automock . LineService
 type LineService interface {
 	ValidateSignature(ctx context.Context, externalChannelSecret, signature string, payload []byte) bool
 	ParseLineEvents(ctx context.Context, payload []byte) ([]domain.LineEvent, domain.Error)
+	GetProfile(ctx context.Context, accessToken, externalMemberID string) (*domain.LineProfile, domain.Error)
+	GetGroupMemberProfile(ctx context.Context, accessToken string, sourceType domain.LineSourceType, externalGroupID, externalMemberID string) (*domain.LineProfile, domain.Error)
+	GetGroupSummary(ctx context.Context, accessToken string, sourceType domain.LineSourceType, externalGroupID string) (*domain.LineGroupSummary, domain.Error)
 }
 
-//go:generate mockgen -destination automock/eventbridge.go -package=automock . EventBridge
-type EventBridge interface {
-	PutEvent(ctx context.Context, data string) domain.Error
+// EventBus publishes events to the worker. Results of the events are returned in the same order,
+// which is nil if the event is published, or a ParameterError if the event could never be published.
+//
+//go:generate mockgen -destination automock/event_bus.go -package=automock . EventBus
+type EventBus interface {
+	PutEvents(ctx context.Context, data []string) []domain.Error
 }
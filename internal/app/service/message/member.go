@@ -0,0 +1,149 @@
+package message
+
+import (
+	"context"
+	"time"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+const (
+	// memberProfileRefreshBatchSize is the number of members claimed at once for profile refresh
+	memberProfileRefreshBatchSize = 20
+	// memberProfileRefreshLease is how long a claimed member is held before others could claim it again
+	memberProfileRefreshLease = 5 * time.Minute
+)
+
+// profileBackoff delays fetching profiles and group summaries again after failures
+var profileBackoff = domain.Backoff{Min: time.Minute, Max: 24 * time.Hour}
+
+// recordChannelMember keeps the membership of the event's source up to date. Follow and unfollow
+// events change whether the member blocks the channel, and every event marks the member as seen.
+// Profiles are fetched by RefreshMemberProfiles in the background, so the webhook doesn't wait for
+// LINE.
+func (s *MessageService) recordChannelMember(ctx context.Context, channel domain.Channel, e domain.LineEvent) *domain.ChannelMember {
+	if e.ExternalMemberID == "" {
+		return nil
+	}
+
+	at := e.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	params := domain.UpsertChannelMemberParams{
+		ChannelID:        channel.ID,
+		ExternalMemberID: e.ExternalMemberID,
+		SeenAt:           at,
+		SourceType:       e.SourceType,
+		ExternalGroupID:  e.SourceGroupID(),
+	}
+	switch e.EventType {
+	case domain.LineEventTypeFollow:
+		params.FollowedAt = &at
+	case domain.LineEventTypeUnfollow:
+		params.UnfollowedAt = &at
+	}
+
+	member, err := s.memberRepo.UpsertChannelMember(ctx, params)
+	if err != nil {
+		// the event is still forwarded, so we only log the failure here
+		s.logger(ctx).Error().Err(err).
+			Int("channelID", channel.ID).
+			Str("externalMemberID", e.ExternalMemberID).
+			Msg("failed to record channel member")
+		return nil
+	}
+	return member
+}
+
+// RefreshMemberProfiles fetches profiles of members which are new or expired. Members failed to be
+// fetched are retried later with exponential backoff, and the stale profile is kept meanwhile.
+func (s *MessageService) RefreshMemberProfiles(ctx context.Context) domain.Error {
+	for {
+		members, err := s.memberRepo.ClaimChannelMembersForProfileRefresh(ctx, time.Now().Add(memberProfileRefreshLease), memberProfileRefreshBatchSize)
+		if err != nil {
+			s.logger(ctx).Error().Err(err).Msg("failed to claim channel members for profile refresh")
+			return err
+		}
+
+		channelIDs := make([]int, 0, len(members))
+		for _, member := range members {
+			channelIDs = append(channelIDs, member.ChannelID)
+		}
+		channels, err := s.getChannels(ctx, channelIDs)
+		if err != nil {
+			return err
+		}
+
+		for _, member := range members {
+			// members of deleted channels are deleted along with them
+			if channel, ok := channels[member.ChannelID]; ok {
+				s.refreshMemberProfile(ctx, channel, member)
+			}
+		}
+
+		if len(members) < memberProfileRefreshBatchSize || ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// getChannels returns the channels of the given IDs by their ID
+func (s *MessageService) getChannels(ctx context.Context, channelIDs []int) (map[int]domain.Channel, domain.Error) {
+	channels := make(map[int]domain.Channel)
+	if len(channelIDs) == 0 {
+		return channels, nil
+	}
+
+	list, err := s.channelRepo.GetChannelsByIDs(ctx, channelIDs)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Msg("failed to get channels")
+		return nil, err
+	}
+	for _, channel := range list {
+		channels[channel.ID] = channel
+	}
+	return channels, nil
+}
+
+// refreshMemberProfile fetches the member's profile from LINE, and schedules the next refresh. The
+// profile is fetched from the group or the room where the member was last seen, since the member may
+// not be a friend of the channel.
+func (s *MessageService) refreshMemberProfile(ctx context.Context, channel domain.Channel, member domain.ChannelMember) {
+	var params domain.UpdateChannelMemberProfileParams
+
+	var profile *domain.LineProfile
+	var err domain.Error
+	if member.ExternalGroupID != "" {
+		profile, err = s.lineService.GetGroupMemberProfile(ctx, channel.AccessToken, member.SourceType, member.ExternalGroupID, member.ExternalMemberID)
+	} else {
+		profile, err = s.lineService.GetProfile(ctx, channel.AccessToken, member.ExternalMemberID)
+	}
+	if err == nil {
+		now := time.Now()
+		attempts, refreshAfter := 0, now.Add(s.profileTTL)
+		params.Profile = profile
+		params.ProfileFetchedAt = &now
+		params.ProfileRefreshAttempts = &attempts
+		params.ProfileRefreshAfter = &refreshAfter
+	} else {
+		attempts := member.ProfileRefreshAttempts + 1
+		retryAt := time.Now().Add(profileBackoff.Delay(attempts))
+		params.ProfileRefreshAttempts = &attempts
+		params.ProfileRefreshAfter = &retryAt
+		s.logger(ctx).Warn().Err(err).
+			Int("channelID", channel.ID).
+			Str("externalMemberID", member.ExternalMemberID).
+			Int("attempts", attempts).
+			Time("retryAt", retryAt).
+			Msg("failed to get member profile")
+	}
+
+	if err := s.memberRepo.UpdateChannelMemberProfile(ctx, member.ID, params); err != nil {
+		// the member would be claimed again after the lease
+		s.logger(ctx).Error().Err(err).
+			Int("channelID", channel.ID).
+			Str("externalMemberID", member.ExternalMemberID).
+			Msg("failed to save member profile")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"time"
 
 	"github.com/rs/zerolog"
 
@@ -12,21 +13,45 @@ import (
 
 type MessageService struct {
 	channelRepo ChannelRepository
+	memberRepo  ChannelMemberRepository
+	groupRepo   ChannelGroupRepository
+	inboundRepo InboundMessageRepository
+	outboxRepo  OutboxEventRepository
+	eventStore  WebhookEventStore
 	lineService LineService
-	eventBridge EventBridge
+	eventBus    EventBus
+	profileTTL  time.Duration
+	dedupeTTL   time.Duration
 }
 
 type MessageServiceParam struct {
 	ChannelRepo ChannelRepository
+	MemberRepo  ChannelMemberRepository
+	GroupRepo   ChannelGroupRepository
+	InboundRepo InboundMessageRepository
+	OutboxRepo  OutboxEventRepository
+	EventStore  WebhookEventStore
 	LineService LineService
-	EventBridge EventBridge
+	EventBus    EventBus
+	// ProfileTTL is how long member profiles and group summaries fetched from LINE are used before they
+	// are fetched again
+	ProfileTTL time.Duration
+	// DedupeTTL is how long received webhook events are remembered to drop their redeliveries
+	DedupeTTL time.Duration
 }
 
 func NewMessageService(_ context.Context, param MessageServiceParam) *MessageService {
 	return &MessageService{
 		channelRepo: param.ChannelRepo,
+		memberRepo:  param.MemberRepo,
+		groupRepo:   param.GroupRepo,
+		inboundRepo: param.InboundRepo,
+		outboxRepo:  param.OutboxRepo,
+		eventStore:  param.EventStore,
 		lineService: param.LineService,
-		eventBridge: param.EventBridge,
+		eventBus:    param.EventBus,
+		profileTTL:  param.ProfileTTL,
+		dedupeTTL:   param.DedupeTTL,
 	}
 }
 
@@ -36,6 +61,9 @@ func (s *MessageService) logger(ctx context.Context) *zerolog.Logger {
 	return &l
 }
 
+// ReceiveWebhookFromLine stores events of the webhook in the outbox, which are published to the event
+// bus by RelayOutboxEvents. Events received before are dropped. The webhook fails if the events cannot
+// be stored, so LINE could redeliver it.
 func (s *MessageService) ReceiveWebhookFromLine(ctx context.Context, webhook domain.LineWebhook) domain.Error {
 	// Check if the given channel is existed
 	channel, err := s.channelRepo.GetChannelByExternalID(ctx, webhook.ExternalChannelID)
@@ -51,15 +79,8 @@ func (s *MessageService) ReceiveWebhookFromLine(ctx context.Context, webhook dom
 		return err
 	}
 
-	type event struct {
-		ChannelAccessToken string          `json:"channelAccessToken"`
-		EventType          string          `json:"eventType"`
-		ExternalMemberID   string          `json:"externalMemberID"`
-		ReplyToken         string          `json:"replyToken"`
-		EventContent       json.RawMessage `json:"eventContent"`
-	}
-
-	// Handle Line events, we support Message, Follow, Unfollow events so far.
+	// Publish all types of Line events with their decoded payloads
+	outboxEvents := make([]domain.WebhookOutboxEvent, 0, len(events))
 	for _, e := range events {
 		s.logger(ctx).Info().
 			Str("eventType", string(e.EventType)).
@@ -67,12 +88,31 @@ func (s *MessageService) ReceiveWebhookFromLine(ctx context.Context, webhook dom
 			Bytes("eventContent", e.EventContent).
 			Msg("get line event")
 
-		evt := event{
+		member := s.recordChannelMember(ctx, *channel, e)
+		s.recordChannelGroup(ctx, *channel, e)
+		s.recordInboundMessage(ctx, *channel, e)
+
+		evt := domain.ChannelEvent{
+			OrganizationID:     channel.OrganizationID,
+			ChannelID:          channel.ID,
 			ChannelAccessToken: channel.AccessToken,
 			ExternalMemberID:   e.ExternalMemberID,
+			SourceType:         e.SourceType,
+			ExternalGroupID:    e.ExternalGroupID,
+			ExternalRoomID:     e.ExternalRoomID,
 			EventContent:       e.EventContent,
 			ReplyToken:         e.ReplyToken,
-			EventType:          string(e.EventType),
+			EventType:          e.EventType,
+			Message:            e.Message,
+			Postback:           e.Postback,
+			Members:            e.Members,
+			Unsend:             e.Unsend,
+			Beacon:             e.Beacon,
+			AccountLink:        e.AccountLink,
+			VideoPlayComplete:  e.VideoPlayComplete,
+		}
+		if member != nil {
+			evt.DisplayName = member.Profile.DisplayName
 		}
 
 		data, err := json.Marshal(evt)
@@ -81,9 +121,14 @@ func (s *MessageService) ReceiveWebhookFromLine(ctx context.Context, webhook dom
 			continue
 		}
 
-		_ = s.eventBridge.PutEvent(ctx, string(data))
+		outboxEvents = append(outboxEvents, domain.WebhookOutboxEvent{
+			WebhookEventID: e.WebhookEventID,
+			Payload:        string(data),
+		})
 	}
-	return nil
+
+	// Store the events, dropping those which have been received, e.g. redelivered by LINE
+	return s.storeWebhookEvents(ctx, events, outboxEvents)
 }
 
 func (s *MessageService) decodeLineWebhook(ctx context.Context, externalChannelSecret string, webhook domain.LineWebhook) ([]domain.LineEvent, domain.Error) {
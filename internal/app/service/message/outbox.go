@@ -0,0 +1,111 @@
+package message
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+const (
+	// outboxRelayBatchSize is the number of outbox events claimed at once for publishing
+	outboxRelayBatchSize = 10
+	// outboxRelayLease is how long a claimed event is held before others could claim it again
+	outboxRelayLease = time.Minute
+	// outboxMaxAttempts is how many times an event is published before the relay gives up on it, which
+	// is about four hours with the backoff
+	outboxMaxAttempts = 50
+	// outboxRetention is how long sent events are kept before they are purged
+	outboxRetention = 7 * 24 * time.Hour
+)
+
+// outboxBackoff delays publishing events again after failures
+var outboxBackoff = domain.Backoff{Min: 5 * time.Second, Max: 5 * time.Minute}
+
+// RelayOutboxEvents publishes events in the outbox which are due. Each claimed batch is published
+// together, which keeps events of a multi-event webhook in as few requests as possible. Events failed
+// to be published are retried with exponential backoff, until they are sent or the relay gives up.
+func (s *MessageService) RelayOutboxEvents(ctx context.Context) domain.Error {
+	for {
+		events, err := s.outboxRepo.ClaimOutboxEvents(ctx, time.Now().Add(outboxRelayLease), outboxRelayBatchSize)
+		if err != nil {
+			s.logger(ctx).Error().Err(err).Msg("failed to claim outbox events")
+			return err
+		}
+
+		s.relayOutboxEvents(ctx, events)
+
+		if len(events) < outboxRelayBatchSize || ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+func (s *MessageService) relayOutboxEvents(ctx context.Context, events []domain.OutboxEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	payloads := make([]string, 0, len(events))
+	for _, event := range events {
+		payloads = append(payloads, event.Payload)
+	}
+
+	results := s.eventBus.PutEvents(ctx, payloads)
+	for i, event := range events {
+		s.finishOutboxEvent(ctx, event, results[i])
+	}
+}
+
+// finishOutboxEvent marks the event sent, or schedules the next attempt if it failed to be published.
+// The event is marked dead instead if it runs out of attempts, or the event bus rejects it as invalid,
+// e.g. exceeding the size limit, which no retry would fix.
+func (s *MessageService) finishOutboxEvent(ctx context.Context, event domain.OutboxEvent, err domain.Error) {
+	var params domain.UpdateOutboxEventParams
+
+	if err == nil {
+		now := time.Now()
+		params.SentAt = &now
+	} else {
+		attempts, lastError := event.Attempts+1, err.Error()
+		params.Attempts = &attempts
+		params.LastError = &lastError
+
+		var invalid domain.ParameterError
+		if errors.As(err, &invalid) || attempts >= outboxMaxAttempts {
+			now := time.Now()
+			params.DeadAt = &now
+			s.logger(ctx).Error().Err(err).
+				Int64("outboxEventID", event.ID).
+				Int("attempts", attempts).
+				Msg("give up publishing outbox event")
+		} else {
+			retryAt := time.Now().Add(outboxBackoff.Delay(attempts))
+			params.NextAttemptAt = &retryAt
+			s.logger(ctx).Error().Err(err).
+				Int64("outboxEventID", event.ID).
+				Int("attempts", attempts).
+				Time("retryAt", retryAt).
+				Msg("failed to publish outbox event")
+		}
+	}
+
+	if err = s.outboxRepo.UpdateOutboxEvent(ctx, event.ID, params); err != nil {
+		// a sent event would be published again after the lease, which consumers must tolerate anyway
+		s.logger(ctx).Error().Err(err).Int64("outboxEventID", event.ID).Msg("failed to update outbox event")
+	}
+}
+
+// PurgeSentOutboxEvents deletes events which have been sent for longer than the retention
+func (s *MessageService) PurgeSentOutboxEvents(ctx context.Context) domain.Error {
+	count, err := s.outboxRepo.DeleteSentOutboxEvents(ctx, time.Now().Add(-outboxRetention))
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Msg("failed to purge sent outbox events")
+		return err
+	}
+	if count > 0 {
+		s.logger(ctx).Info().Int("count", count).Msg("sent outbox events are purged")
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package organization
+
+import (
+	"context"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+//go:generate mockgen -destination automock/organization_repository.go -package=automock . OrganizationRepository
+type OrganizationRepository interface {
+	CreateOrganization(ctx context.Context, organization domain.Organization) (*domain.Organization, domain.Error)
+	GetOrganizationByID(ctx context.Context, organizationID int) (*domain.Organization, domain.Error)
+	ListOrganizations(ctx context.Context, pagination domain.Pagination) ([]domain.Organization, int, domain.Error)
+	UpdateOrganization(ctx context.Context, organizationID int, params domain.UpdateOrganizationParams) (*domain.Organization, domain.Error)
+}
@@ -0,0 +1,78 @@
+package organization
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+type OrgService struct {
+	orgRepo OrganizationRepository
+}
+
+type OrgServiceParam struct {
+	OrgRepo OrganizationRepository
+}
+
+func NewOrgService(_ context.Context, param OrgServiceParam) *OrgService {
+	return &OrgService{
+		orgRepo: param.OrgRepo,
+	}
+}
+
+// logger wrap the execution context with component info
+func (s *OrgService) logger(ctx context.Context) *zerolog.Logger {
+	l := zerolog.Ctx(ctx).With().Str("service", "organization").Logger()
+	return &l
+}
+
+type CreateOrganizationParam struct {
+	Name string
+}
+
+func (s *OrgService) CreateOrganization(ctx context.Context, param CreateOrganizationParam) (*domain.Organization, domain.Error) {
+	organization, err := s.orgRepo.CreateOrganization(ctx, domain.Organization{
+		Name: param.Name,
+	})
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Msg("failed to create organization")
+		return nil, err
+	}
+	return organization, nil
+}
+
+func (s *OrgService) GetOrganization(ctx context.Context, organizationID int) (*domain.Organization, domain.Error) {
+	organization, err := s.orgRepo.GetOrganizationByID(ctx, organizationID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("organizationID", organizationID).Msg("failed to get organization")
+		return nil, err
+	}
+	return organization, nil
+}
+
+// ListOrganizations returns organizations of the given page and the total number of organizations.
+func (s *OrgService) ListOrganizations(ctx context.Context, pagination domain.Pagination) ([]domain.Organization, int, domain.Error) {
+	organizations, total, err := s.orgRepo.ListOrganizations(ctx, pagination)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Msg("failed to list organizations")
+		return nil, 0, err
+	}
+	return organizations, total, nil
+}
+
+type UpdateOrganizationParam struct {
+	Name *string
+}
+
+func (s *OrgService) UpdateOrganization(ctx context.Context, organizationID int, param UpdateOrganizationParam) (*domain.Organization, domain.Error) {
+	organization, err := s.orgRepo.UpdateOrganization(ctx, organizationID, domain.UpdateOrganizationParams{
+		Name: param.Name,
+	})
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("organizationID", organizationID).Msg("failed to update organization")
+		return nil, err
+	}
+	return organization, nil
+}
@@ -0,0 +1,46 @@
+package organization
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rs/zerolog"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+// RoleService decides what principals could do in their organizations with the permissions granted to
+// their roles. The admin is granted all permissions.
+type RoleService struct{}
+
+type RoleServiceParam struct{}
+
+func NewRoleService(_ context.Context, _ RoleServiceParam) *RoleService {
+	return &RoleService{}
+}
+
+// logger wrap the execution context with component info
+func (s *RoleService) logger(ctx context.Context) *zerolog.Logger {
+	l := zerolog.Ctx(ctx).With().Str("service", "role").Logger()
+	return &l
+}
+
+// ListRoles returns all roles from the most to the least privileged
+func (s *RoleService) ListRoles(_ context.Context) []domain.Role {
+	return domain.Roles()
+}
+
+// Authorize returns PermissionDeniedError if the principal is not granted the permission
+func (s *RoleService) Authorize(ctx context.Context, principal domain.Principal, permission domain.Permission) domain.Error {
+	if principal.Can(permission) {
+		return nil
+	}
+
+	s.logger(ctx).Info().
+		Str("principalID", principal.ID()).
+		Str("role", string(principal.Role)).
+		Str("permission", string(permission)).
+		Msg("permission is denied")
+	msg := "permission " + string(permission) + " is required"
+	return domain.NewPermissionDeniedError(msg, errors.New(msg))
+}
@@ -0,0 +1,28 @@
+package outbound
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+//go:generate mockgen -destination automock/channel_repository.go -package=automock . ChannelRepository
+type ChannelRepository interface {
+	GetChannelByID(ctx context.Context, organizationID, channelID int) (*domain.Channel, domain.Error)
+}
+
+//go:generate mockgen -destination automock/outbound_message_repository.go -package=automock . OutboundMessageRepository
+type OutboundMessageRepository interface {
+	CreateOutboundMessages(ctx context.Context, messages []domain.OutboundMessage) ([]domain.OutboundMessage, domain.Error)
+	GetOutboundMessage(ctx context.Context, channelID int, messageID int64) (*domain.OutboundMessage, domain.Error)
+	ClaimOutboundMessageForRetry(ctx context.Context, channelID int, messageID int64, staleBefore time.Time) (*domain.OutboundMessage, domain.Error)
+	FinishOutboundMessageRetry(ctx context.Context, messageID int64, attempt domain.OutboundMessage) (*domain.OutboundMessage, domain.Error)
+	ListOutboundMessages(ctx context.Context, channelID int, filter domain.OutboundMessageFilter, pagination domain.Pagination) ([]domain.OutboundMessage, int, domain.Error)
+}
+
+//go:generate mockgen -destination automock/line_service.go -package=automock . LineService
+type LineService interface {
+	PushRawMessages(ctx context.Context, accessToken, to, retryKey string, messages json.RawMessage) domain.LineSendAttempt
+}
@@ -0,0 +1,193 @@
+package outbound
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+// OutboundService keeps the log of messages sent to LINE, and resends failed deliveries
+type OutboundService struct {
+	channelRepo  ChannelRepository
+	outboundRepo OutboundMessageRepository
+	lineService  LineService
+}
+
+type OutboundServiceParam struct {
+	ChannelRepo  ChannelRepository
+	OutboundRepo OutboundMessageRepository
+	LineService  LineService
+}
+
+func NewOutboundService(_ context.Context, param OutboundServiceParam) *OutboundService {
+	return &OutboundService{
+		channelRepo:  param.ChannelRepo,
+		outboundRepo: param.OutboundRepo,
+		lineService:  param.LineService,
+	}
+}
+
+// logger wrap the execution context with component info
+func (s *OutboundService) logger(ctx context.Context) *zerolog.Logger {
+	l := zerolog.Ctx(ctx).With().Str("service", "outbound").Logger()
+	return &l
+}
+
+type RecordAttemptsParam struct {
+	ChannelID int
+	Recipient string
+	// Payload is the JSON array of the sent messages
+	Payload  json.RawMessage
+	Attempts []domain.LineSendAttempt
+}
+
+// RecordAttempts logs attempts of sending the messages as one delivery
+func (s *OutboundService) RecordAttempts(ctx context.Context, param RecordAttemptsParam) ([]domain.OutboundMessage, domain.Error) {
+	deliveryID, err := newDeliveryID()
+	if err != nil {
+		return nil, domain.NewInternalError("", err)
+	}
+
+	messages := make([]domain.OutboundMessage, 0, len(param.Attempts))
+	for _, attempt := range param.Attempts {
+		messages = append(messages, newOutboundMessage(param.ChannelID, deliveryID, param.Recipient, param.Payload, attempt))
+	}
+
+	created, dErr := s.outboundRepo.CreateOutboundMessages(ctx, messages)
+	if dErr != nil {
+		s.logger(ctx).Error().Err(dErr).
+			Int("channelID", param.ChannelID).
+			Str("deliveryID", deliveryID).
+			Msg("failed to record outbound messages")
+		return nil, dErr
+	}
+	return created, nil
+}
+
+// ListOutboundMessages returns attempts of the channel in the given page, latest first, and the total
+// number of them
+func (s *OutboundService) ListOutboundMessages(ctx context.Context, organizationID, channelID int, filter domain.OutboundMessageFilter, pagination domain.Pagination) ([]domain.OutboundMessage, int, domain.Error) {
+	// Make sure the channel belongs to the organization
+	if _, err := s.channelRepo.GetChannelByID(ctx, organizationID, channelID); err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to get channel")
+		return nil, 0, err
+	}
+
+	messages, total, err := s.outboundRepo.ListOutboundMessages(ctx, channelID, filter, pagination)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to list outbound messages")
+		return nil, 0, err
+	}
+	return messages, total, nil
+}
+
+func (s *OutboundService) GetOutboundMessage(ctx context.Context, organizationID, channelID int, messageID int64) (*domain.OutboundMessage, domain.Error) {
+	// Make sure the channel belongs to the organization
+	if _, err := s.channelRepo.GetChannelByID(ctx, organizationID, channelID); err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to get channel")
+		return nil, err
+	}
+
+	message, err := s.outboundRepo.GetOutboundMessage(ctx, channelID, messageID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int64("outboundMessageID", messageID).Msg("failed to get outbound message")
+		return nil, err
+	}
+	return message, nil
+}
+
+// outboundRetryClaimTimeout is how long a retry could hold its claim on the delivery, which is longer
+// than pushing to LINE takes. Claims older than it are left by retries which never finished.
+const outboundRetryClaimTimeout = time.Minute
+
+// RetryOutboundMessage pushes messages of the failed attempt again, since reply tokens could only be
+// used once. The retry is logged as an attempt of the same delivery, which could not be retried once
+// any of its attempts succeeds, nor while another retry of it is in progress.
+func (s *OutboundService) RetryOutboundMessage(ctx context.Context, organizationID, channelID int, messageID int64) (*domain.OutboundMessage, domain.Error) {
+	channel, err := s.channelRepo.GetChannelByID(ctx, organizationID, channelID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to get channel")
+		return nil, err
+	}
+
+	message, err := s.outboundRepo.GetOutboundMessage(ctx, channelID, messageID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int64("outboundMessageID", messageID).Msg("failed to get outbound message")
+		return nil, err
+	}
+	if message.Recipient == "" {
+		msg := "outbound message has no recipient to push to"
+		return nil, domain.NewParameterError(msg, errors.New(msg))
+	}
+
+	// claim the delivery, so concurrent retries don't push the messages twice
+	message, err = s.outboundRepo.ClaimOutboundMessageForRetry(ctx, channelID, messageID, time.Now().Add(-outboundRetryClaimTimeout))
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int64("outboundMessageID", messageID).Msg("failed to claim outbound message for retry")
+		return nil, err
+	}
+
+	attempt := s.lineService.PushRawMessages(ctx, channel.AccessToken, message.Recipient, newRetryKey(message.DeliveryID), message.Payload)
+	if attempt.Err != nil {
+		s.logger(ctx).Warn().Err(attempt.Err).Str("deliveryID", message.DeliveryID).Msg("failed to retry outbound message")
+	}
+
+	created, err := s.outboundRepo.FinishOutboundMessageRetry(ctx, message.ID,
+		newOutboundMessage(channelID, message.DeliveryID, message.Recipient, message.Payload, attempt))
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Str("deliveryID", message.DeliveryID).Msg("failed to record outbound message")
+		return nil, err
+	}
+	return created, nil
+}
+
+func newOutboundMessage(channelID int, deliveryID, recipient string, payload json.RawMessage, attempt domain.LineSendAttempt) domain.OutboundMessage {
+	message := domain.OutboundMessage{
+		ChannelID:     channelID,
+		DeliveryID:    deliveryID,
+		Recipient:     recipient,
+		Mode:          attempt.Mode,
+		Payload:       payload,
+		LineRequestID: attempt.RequestID,
+		HTTPStatus:    attempt.HTTPStatus,
+		Status:        domain.OutboundMessageStatusSucceeded,
+	}
+	if attempt.Err != nil {
+		message.Status = domain.OutboundMessageStatusFailed
+		message.Error = attempt.Err.Error()
+	}
+	return message
+}
+
+// newDeliveryID returns a random ID grouping attempts of a delivery
+func newDeliveryID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// retryKeyNamespace is the namespace of retry keys derived from delivery IDs
+var retryKeyNamespace = []byte{0x3b, 0x6e, 0x52, 0x0c, 0x8f, 0x41, 0x4d, 0x27, 0xa9, 0x15, 0x70, 0xd2, 0x64, 0xc8, 0x1e, 0x93}
+
+// newRetryKey derives the retry key of LINE from the delivery ID as a name-based UUID (version 5),
+// so every retry of a delivery is pushed with the same key, and LINE accepts it at most once
+func newRetryKey(deliveryID string) string {
+	h := sha1.New()
+	h.Write(retryKeyNamespace)
+	h.Write([]byte(deliveryID))
+	b := h.Sum(nil)[:16]
+	b[6] = (b[6] & 0x0f) | 0x50
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,21 @@
+package outbound
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestNewRetryKey(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	key := newRetryKey("0123456789abcdef0123456789abcdef")
+	if !uuid.MatchString(key) {
+		t.Fatalf("newRetryKey() = %q, want a version 5 UUID", key)
+	}
+	if again := newRetryKey("0123456789abcdef0123456789abcdef"); again != key {
+		t.Fatalf("newRetryKey() = %q for the same delivery, want %q", again, key)
+	}
+	if other := newRetryKey("fedcba9876543210fedcba9876543210"); other == key {
+		t.Fatalf("newRetryKey() returns the same key for another delivery")
+	}
+}
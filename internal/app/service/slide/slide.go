@@ -2,6 +2,7 @@ package slide
 
 import (
 	"context"
+	"errors"
 
 	"github.com/rs/zerolog"
 
@@ -9,9 +10,9 @@ import (
 )
 
 type Repository interface {
-	GetSlideURLByPage(ctx context.Context, channelID, page int) (string, int, domain.Error)
-	GetLastPageNumber(ctx context.Context, channelID int) (int, domain.Error)
-	UpdateCurrentPage(ctx context.Context, channelID, page int) domain.Error
+	GetSlideURLByPage(ctx context.Context, organizationID, channelID, page int) (string, int, domain.Error)
+	GetLastPageNumber(ctx context.Context, organizationID, channelID int) (int, domain.Error)
+	UpdateCurrentPage(ctx context.Context, organizationID, channelID, page int) domain.Error
 }
 
 type SlideService struct {
@@ -26,8 +27,8 @@ func NewSlideService(_ context.Context, repo Repository) *SlideService {
 
 // GetSlideURL returns the slide URL specified by page. If p is out of scope,
 // it would return page 1.
-func (s *SlideService) GetSlideURL(ctx context.Context, channelID, p int) (url string, page int, err domain.Error) {
-	last, err := s.repo.GetLastPageNumber(ctx, channelID)
+func (s *SlideService) GetSlideURL(ctx context.Context, organizationID, channelID, p int) (url string, page int, err domain.Error) {
+	last, err := s.repo.GetLastPageNumber(ctx, organizationID, channelID)
 	if err != nil {
 		zerolog.Ctx(ctx).Error().Err(err).Msg("fail to get last page")
 		return "", 0, err
@@ -38,7 +39,7 @@ func (s *SlideService) GetSlideURL(ctx context.Context, channelID, p int) (url s
 		p = 1
 	}
 
-	url, page, err = s.repo.GetSlideURLByPage(ctx, channelID, p)
+	url, page, err = s.repo.GetSlideURLByPage(ctx, organizationID, channelID, p)
 	if err != nil {
 		zerolog.Ctx(ctx).Error().Err(err).Int("page", p).Msg("fail to get slide url")
 		return "", 0, err
@@ -48,8 +49,8 @@ func (s *SlideService) GetSlideURL(ctx context.Context, channelID, p int) (url s
 }
 
 // GetPrevNext returns the previous and next page number
-func (s *SlideService) GetPrevNext(ctx context.Context, channelID, p int) (prev int, next int, err domain.Error) {
-	last, err := s.repo.GetLastPageNumber(ctx, channelID)
+func (s *SlideService) GetPrevNext(ctx context.Context, organizationID, channelID, p int) (prev int, next int, err domain.Error) {
+	last, err := s.repo.GetLastPageNumber(ctx, organizationID, channelID)
 	if err != nil {
 		zerolog.Ctx(ctx).Error().Err(err).Int("page", p).Msg("fail to get last page")
 		return 0, 0, err
@@ -70,12 +71,29 @@ func (s *SlideService) GetPrevNext(ctx context.Context, channelID, p int) (prev
 	return prev, next, err
 }
 
-// UpdateCurrentPage marked the specified page to be enabled, and all other pages are disabled.
-func (s *SlideService) UpdateCurrentPage(ctx context.Context, channelID, p int) domain.Error {
-	err := s.repo.UpdateCurrentPage(ctx, channelID, p)
+// UpdateCurrentPage marks the page as the current one, which the chatbot sends, and returns its URL.
+// Pages out of the slide deck are rejected.
+func (s *SlideService) UpdateCurrentPage(ctx context.Context, organizationID, channelID, p int) (string, domain.Error) {
+	last, err := s.repo.GetLastPageNumber(ctx, organizationID, channelID)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Msg("fail to get last page")
+		return "", err
+	}
+	if p < 1 || p > last {
+		msg := "page is out of the slide deck"
+		return "", domain.NewParameterError(msg, errors.New(msg))
+	}
+
+	url, _, err := s.repo.GetSlideURLByPage(ctx, organizationID, channelID, p)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Int("page", p).Msg("fail to get slide url")
+		return "", err
+	}
+
+	err = s.repo.UpdateCurrentPage(ctx, organizationID, channelID, p)
 	if err != nil {
 		zerolog.Ctx(ctx).Error().Err(err).Int("page", p).Msg("fail to update current page")
-		return err
+		return "", err
 	}
-	return nil
+	return url, nil
 }
@@ -0,0 +1,29 @@
+package tag
+
+import (
+	"context"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+//go:generate mockgen -destination automock/channel_repository.go -package=automock . ChannelRepository
+type ChannelRepository interface {
+	GetChannelByID(ctx context.Context, organizationID, channelID int) (*domain.Channel, domain.Error)
+}
+
+//go:generate mockgen -destination automock/tag_repository.go -package=automock . TagRepository
+type TagRepository interface {
+	CreateTag(ctx context.Context, tag domain.Tag) (*domain.Tag, domain.Error)
+	GetTagByID(ctx context.Context, channelID, tagID int) (*domain.Tag, domain.Error)
+	ListTags(ctx context.Context, channelID int) ([]domain.Tag, domain.Error)
+	UpdateTag(ctx context.Context, channelID, tagID int, params domain.UpdateTagParams) (*domain.Tag, domain.Error)
+	DeleteTag(ctx context.Context, channelID, tagID int) domain.Error
+}
+
+//go:generate mockgen -destination automock/tag_channel_member_repository.go -package=automock . TagChannelMemberRepository
+type TagChannelMemberRepository interface {
+	AttachTag(ctx context.Context, channelID, tagID int, externalMemberIDs []string) (int, domain.Error)
+	DetachTag(ctx context.Context, channelID, tagID int, externalMemberIDs []string) (int, domain.Error)
+	ListMemberTags(ctx context.Context, channelID int, externalMemberID string) ([]domain.Tag, domain.Error)
+	ListChannelMembersByTags(ctx context.Context, channelID int, expression domain.TagExpression, pagination domain.Pagination) ([]domain.ChannelMember, int, domain.Error)
+}
@@ -0,0 +1,154 @@
+package tag
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/rs/zerolog"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+// MaxBulkMembers limits how many members could be tagged or untagged in one request
+const MaxBulkMembers = 1000
+
+// TagChannelMemberService attaches tags to channel members and selects members by their tags, so
+// groups of members could be targeted.
+type TagChannelMemberService struct {
+	channelRepo   ChannelRepository
+	tagRepo       TagRepository
+	tagMemberRepo TagChannelMemberRepository
+}
+
+type TagChannelMemberServiceParam struct {
+	ChannelRepo   ChannelRepository
+	TagRepo       TagRepository
+	TagMemberRepo TagChannelMemberRepository
+}
+
+func NewTagChannelMemberService(_ context.Context, param TagChannelMemberServiceParam) *TagChannelMemberService {
+	return &TagChannelMemberService{
+		channelRepo:   param.ChannelRepo,
+		tagRepo:       param.TagRepo,
+		tagMemberRepo: param.TagMemberRepo,
+	}
+}
+
+// logger wrap the execution context with component info
+func (s *TagChannelMemberService) logger(ctx context.Context) *zerolog.Logger {
+	l := zerolog.Ctx(ctx).With().Str("service", "tag_channel_member").Logger()
+	return &l
+}
+
+// getTag returns the tag after making sure its channel belongs to the organization
+func (s *TagChannelMemberService) getTag(ctx context.Context, organizationID, channelID, tagID int) (*domain.Tag, domain.Error) {
+	if _, err := s.channelRepo.GetChannelByID(ctx, organizationID, channelID); err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to get channel")
+		return nil, err
+	}
+
+	tag, err := s.tagRepo.GetTagByID(ctx, channelID, tagID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("tagID", tagID).Msg("failed to get tag")
+		return nil, err
+	}
+	return tag, nil
+}
+
+func checkBulkMembers(externalMemberIDs []string) domain.Error {
+	if len(externalMemberIDs) == 0 {
+		msg := "no member is given"
+		return domain.NewParameterError(msg, errors.New(msg))
+	}
+	if len(externalMemberIDs) > MaxBulkMembers {
+		msg := "at most " + strconv.Itoa(MaxBulkMembers) + " members could be given at once"
+		return domain.NewParameterError(msg, errors.New(msg))
+	}
+	return nil
+}
+
+// AttachTag attaches the tag to the members. Unknown members and members who already have the tag
+// are skipped, and the number of newly tagged members is returned.
+func (s *TagChannelMemberService) AttachTag(ctx context.Context, organizationID, channelID, tagID int, externalMemberIDs []string) (int, domain.Error) {
+	if err := checkBulkMembers(externalMemberIDs); err != nil {
+		return 0, err
+	}
+	if _, err := s.getTag(ctx, organizationID, channelID, tagID); err != nil {
+		return 0, err
+	}
+
+	count, err := s.tagMemberRepo.AttachTag(ctx, channelID, tagID, externalMemberIDs)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("tagID", tagID).Msg("failed to attach tag")
+		return 0, err
+	}
+	return count, nil
+}
+
+// DetachTag detaches the tag from the members, and returns the number of untagged members
+func (s *TagChannelMemberService) DetachTag(ctx context.Context, organizationID, channelID, tagID int, externalMemberIDs []string) (int, domain.Error) {
+	if err := checkBulkMembers(externalMemberIDs); err != nil {
+		return 0, err
+	}
+	if _, err := s.getTag(ctx, organizationID, channelID, tagID); err != nil {
+		return 0, err
+	}
+
+	count, err := s.tagMemberRepo.DetachTag(ctx, channelID, tagID, externalMemberIDs)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("tagID", tagID).Msg("failed to detach tag")
+		return 0, err
+	}
+	return count, nil
+}
+
+func (s *TagChannelMemberService) ListMemberTags(ctx context.Context, organizationID, channelID int, externalMemberID string) ([]domain.Tag, domain.Error) {
+	if _, err := s.channelRepo.GetChannelByID(ctx, organizationID, channelID); err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to get channel")
+		return nil, err
+	}
+
+	tags, err := s.tagMemberRepo.ListMemberTags(ctx, channelID, externalMemberID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Str("externalMemberID", externalMemberID).Msg("failed to list member tags")
+		return nil, err
+	}
+	return tags, nil
+}
+
+// ListSegmentMembers returns members of the channel matching the tag expression in the given page and
+// the total number of them. Tags in the expression must belong to the channel.
+func (s *TagChannelMemberService) ListSegmentMembers(ctx context.Context, organizationID, channelID int, expression domain.TagExpression, pagination domain.Pagination) ([]domain.ChannelMember, int, domain.Error) {
+	if err := expression.Validate(); err != nil {
+		return nil, 0, domain.NewParameterError(err.Error(), err)
+	}
+
+	if _, err := s.channelRepo.GetChannelByID(ctx, organizationID, channelID); err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to get channel")
+		return nil, 0, err
+	}
+
+	tags, err := s.tagRepo.ListTags(ctx, channelID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to list tags")
+		return nil, 0, err
+	}
+	known := make(map[int]bool, len(tags))
+	for _, tag := range tags {
+		known[tag.ID] = true
+	}
+	for _, tagID := range expression.AllTagIDs() {
+		if !known[tagID] {
+			msg := "tag " + strconv.Itoa(tagID) + " is not found in the channel"
+			return nil, 0, domain.NewParameterError(msg, errors.New(msg))
+		}
+	}
+
+	members, total, err := s.tagMemberRepo.ListChannelMembersByTags(ctx, channelID, expression, pagination)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to list segment members")
+		return nil, 0, err
+	}
+	return members, total, nil
+}
@@ -0,0 +1,121 @@
+package tag
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+type TagService struct {
+	channelRepo ChannelRepository
+	tagRepo     TagRepository
+}
+
+type TagServiceParam struct {
+	ChannelRepo ChannelRepository
+	TagRepo     TagRepository
+}
+
+func NewTagService(_ context.Context, param TagServiceParam) *TagService {
+	return &TagService{
+		channelRepo: param.ChannelRepo,
+		tagRepo:     param.TagRepo,
+	}
+}
+
+// logger wrap the execution context with component info
+func (s *TagService) logger(ctx context.Context) *zerolog.Logger {
+	l := zerolog.Ctx(ctx).With().Str("service", "tag").Logger()
+	return &l
+}
+
+// checkChannel makes sure the channel belongs to the organization
+func (s *TagService) checkChannel(ctx context.Context, organizationID, channelID int) domain.Error {
+	if _, err := s.channelRepo.GetChannelByID(ctx, organizationID, channelID); err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to get channel")
+		return err
+	}
+	return nil
+}
+
+type CreateTagParam struct {
+	OrganizationID int
+	ChannelID      int
+	Name           string
+}
+
+func (s *TagService) CreateTag(ctx context.Context, param CreateTagParam) (*domain.Tag, domain.Error) {
+	if err := s.checkChannel(ctx, param.OrganizationID, param.ChannelID); err != nil {
+		return nil, err
+	}
+
+	tag, err := s.tagRepo.CreateTag(ctx, domain.Tag{
+		ChannelID: param.ChannelID,
+		Name:      param.Name,
+	})
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", param.ChannelID).Msg("failed to create tag")
+		return nil, err
+	}
+	return tag, nil
+}
+
+func (s *TagService) GetTag(ctx context.Context, organizationID, channelID, tagID int) (*domain.Tag, domain.Error) {
+	if err := s.checkChannel(ctx, organizationID, channelID); err != nil {
+		return nil, err
+	}
+
+	tag, err := s.tagRepo.GetTagByID(ctx, channelID, tagID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("tagID", tagID).Msg("failed to get tag")
+		return nil, err
+	}
+	return tag, nil
+}
+
+func (s *TagService) ListTags(ctx context.Context, organizationID, channelID int) ([]domain.Tag, domain.Error) {
+	if err := s.checkChannel(ctx, organizationID, channelID); err != nil {
+		return nil, err
+	}
+
+	tags, err := s.tagRepo.ListTags(ctx, channelID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("channelID", channelID).Msg("failed to list tags")
+		return nil, err
+	}
+	return tags, nil
+}
+
+type UpdateTagParam struct {
+	Name *string
+}
+
+func (s *TagService) UpdateTag(ctx context.Context, organizationID, channelID, tagID int, param UpdateTagParam) (*domain.Tag, domain.Error) {
+	if err := s.checkChannel(ctx, organizationID, channelID); err != nil {
+		return nil, err
+	}
+
+	tag, err := s.tagRepo.UpdateTag(ctx, channelID, tagID, domain.UpdateTagParams{
+		Name: param.Name,
+	})
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("tagID", tagID).Msg("failed to update tag")
+		return nil, err
+	}
+	return tag, nil
+}
+
+// DeleteTag deletes the tag and detaches it from all members
+func (s *TagService) DeleteTag(ctx context.Context, organizationID, channelID, tagID int) domain.Error {
+	if err := s.checkChannel(ctx, organizationID, channelID); err != nil {
+		return err
+	}
+
+	if err := s.tagRepo.DeleteTag(ctx, channelID, tagID); err != nil {
+		s.logger(ctx).Error().Err(err).Int("tagID", tagID).Msg("failed to delete tag")
+		return err
+	}
+	return nil
+}
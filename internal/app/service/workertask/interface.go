@@ -0,0 +1,17 @@
+package workertask
+
+import (
+	"context"
+	"time"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+//go:generate mockgen -destination automock/worker_task_repository.go -package=automock . WorkerTaskRepository
+type WorkerTaskRepository interface {
+	CreateWorkerTask(ctx context.Context, task domain.WorkerTask) (*domain.WorkerTask, domain.Error)
+	StartWorkerTask(ctx context.Context, taskID int, startedAt time.Time) (*domain.WorkerTask, domain.Error)
+	FinishWorkerTask(ctx context.Context, taskID int, status domain.WorkerTaskStatus, lastError string, finishedAt time.Time) domain.Error
+	GetWorkerTaskByID(ctx context.Context, organizationID, taskID int) (*domain.WorkerTask, domain.Error)
+	ListWorkerTasks(ctx context.Context, organizationID int, filter domain.WorkerTaskFilter, pagination domain.Pagination) ([]domain.WorkerTask, int, domain.Error)
+}
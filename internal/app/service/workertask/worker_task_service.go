@@ -0,0 +1,96 @@
+package workertask
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+// WorkerTaskService records events handled by the worker, so failed ones could be inspected later
+type WorkerTaskService struct {
+	taskRepo WorkerTaskRepository
+}
+
+type WorkerTaskServiceParam struct {
+	TaskRepo WorkerTaskRepository
+}
+
+func NewWorkerTaskService(_ context.Context, param WorkerTaskServiceParam) *WorkerTaskService {
+	return &WorkerTaskService{
+		taskRepo: param.TaskRepo,
+	}
+}
+
+// logger wrap the execution context with component info
+func (s *WorkerTaskService) logger(ctx context.Context) *zerolog.Logger {
+	l := zerolog.Ctx(ctx).With().Str("service", "worker_task").Logger()
+	return &l
+}
+
+type StartTaskParam struct {
+	OrganizationID int
+	ChannelID      int
+	EventID        string
+	EventType      string
+	Payload        []byte
+}
+
+// StartTask records the event as a task and marks it running. Retried deliveries of the same event
+// start the existing task again.
+func (s *WorkerTaskService) StartTask(ctx context.Context, param StartTaskParam) (*domain.WorkerTask, domain.Error) {
+	task, err := s.taskRepo.CreateWorkerTask(ctx, domain.WorkerTask{
+		OrganizationID: param.OrganizationID,
+		ChannelID:      param.ChannelID,
+		EventID:        param.EventID,
+		EventType:      param.EventType,
+		Payload:        param.Payload,
+	})
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Str("eventID", param.EventID).Msg("failed to create worker task")
+		return nil, err
+	}
+
+	task, err = s.taskRepo.StartWorkerTask(ctx, task.ID, time.Now())
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Str("eventID", param.EventID).Msg("failed to start worker task")
+		return nil, err
+	}
+	return task, nil
+}
+
+// FinishTask records the result of the task. The task fails if taskErr is not nil.
+func (s *WorkerTaskService) FinishTask(ctx context.Context, taskID int, taskErr error) domain.Error {
+	status, lastError := domain.WorkerTaskStatusSucceeded, ""
+	if taskErr != nil {
+		status, lastError = domain.WorkerTaskStatusFailed, taskErr.Error()
+	}
+
+	err := s.taskRepo.FinishWorkerTask(ctx, taskID, status, lastError, time.Now())
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("taskID", taskID).Msg("failed to finish worker task")
+		return err
+	}
+	return nil
+}
+
+func (s *WorkerTaskService) GetTask(ctx context.Context, organizationID, taskID int) (*domain.WorkerTask, domain.Error) {
+	task, err := s.taskRepo.GetWorkerTaskByID(ctx, organizationID, taskID)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("taskID", taskID).Msg("failed to get worker task")
+		return nil, err
+	}
+	return task, nil
+}
+
+// ListTasks returns tasks of the organization in the given page and the total number of them
+func (s *WorkerTaskService) ListTasks(ctx context.Context, organizationID int, filter domain.WorkerTaskFilter, pagination domain.Pagination) ([]domain.WorkerTask, int, domain.Error) {
+	tasks, total, err := s.taskRepo.ListWorkerTasks(ctx, organizationID, filter, pagination)
+	if err != nil {
+		s.logger(ctx).Error().Err(err).Int("organizationID", organizationID).Msg("failed to list worker tasks")
+		return nil, 0, err
+	}
+	return tasks, total, nil
+}
@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// AccessToken is a channel access token issued by LINE
+type AccessToken struct {
+	Token     string
+	ExpiredAt time.Time
+	// KeyID identifies a v2.1 access token at LINE. It's empty for v2 access tokens.
+	KeyID string
+}
+
+// ChannelAccessToken tracks a v2.1 access token issued for a channel, so it could be revoked
+// before it expires.
+type ChannelAccessToken struct {
+	ID          int
+	ChannelID   int
+	KeyID       string
+	AccessToken string
+	ExpiredAt   time.Time
+	RevokedAt   *time.Time
+	CreatedAt   time.Time
+}
@@ -0,0 +1,30 @@
+package domain
+
+import "time"
+
+// AccessTokenRevocation is an access token to be revoked at LINE, which is either replaced and waiting
+// for its grace period, or failed to be revoked. It is retried until it is revoked or the access
+// token expires. It keeps its own copy of channel credentials since the channel might have been
+// deleted.
+type AccessTokenRevocation struct {
+	ID                    int
+	ExternalChannelID     string
+	ExternalChannelSecret string
+	AccessToken           string
+	AccessTokenVersion    AccessTokenVersion
+	AccessTokenKeyID      string
+	AccessTokenExpiredAt  time.Time
+	Attempts              int
+	LastError             string
+	NextAttemptAt         time.Time
+	RevokedAt             *time.Time
+	CreatedAt             time.Time
+}
+
+// UpdateAccessTokenRevocationParams contains the fields to be updated. Nil fields are left unchanged.
+type UpdateAccessTokenRevocationParams struct {
+	Attempts      *int
+	LastError     *string
+	NextAttemptAt *time.Time
+	RevokedAt     *time.Time
+}
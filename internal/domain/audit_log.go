@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+type AuditAction string
+
+const (
+	AuditActionRevealChannelSecret = AuditAction("channel.secret.reveal")
+)
+
+type AuditResourceType string
+
+const (
+	AuditResourceTypeChannel = AuditResourceType("channel")
+)
+
+// Actor identifies who performs an operation
+type Actor struct {
+	ID        string
+	RequestID string
+	ClientIP  string
+}
+
+// AuditLog records a privileged operation performed by an actor
+type AuditLog struct {
+	ID           int
+	Actor        Actor
+	Action       AuditAction
+	ResourceType AuditResourceType
+	ResourceID   string
+	CreatedAt    time.Time
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"strconv"
+	"time"
+)
+
+// APIKey authenticates requests to the admin API on behalf of an organization. Only the hash of the
+// key is stored, and the key itself is shown once when it's issued.
+type APIKey struct {
+	ID             int
+	OrganizationID int
+	Name           string
+	Role           Role
+	// Prefix is the beginning of the key to tell keys apart without revealing them
+	Prefix     string
+	Hash       string
+	CreatedBy  string
+	LastUsedAt *time.Time
+	ExpiredAt  *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+// Active tells whether the key could still be used at the given time
+func (k APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiredAt == nil || now.Before(*k.ExpiredAt)
+}
+
+type PrincipalType string
+
+const (
+	// PrincipalTypeAdmin is authenticated by the admin API key given at startup, and it could access
+	// all organizations
+	PrincipalTypeAdmin = PrincipalType("admin")
+	// PrincipalTypeAPIKey is authenticated by an API key of an organization
+	PrincipalTypeAPIKey = PrincipalType("api_key")
+)
+
+// Principal is who an authenticated request acts as
+type Principal struct {
+	Type           PrincipalType
+	APIKeyID       int
+	OrganizationID int
+	// Role is the role of the API key in its organization. The admin has no role since it's granted
+	// all permissions.
+	Role Role
+}
+
+// ID identifies the principal in audit logs
+func (p Principal) ID() string {
+	if p.Type == PrincipalTypeAPIKey {
+		return string(p.Type) + ":" + strconv.Itoa(p.APIKeyID)
+	}
+	return string(p.Type)
+}
+
+func (p Principal) IsAdmin() bool {
+	return p.Type == PrincipalTypeAdmin
+}
+
+// Can tells whether the principal is granted the permission in its organization
+func (p Principal) Can(permission Permission) bool {
+	return p.IsAdmin() || p.Role.Has(permission)
+}
+
+// CanAccessOrganization tells whether the principal could work on resources of the organization
+func (p Principal) CanAccessOrganization(organizationID int) bool {
+	return p.IsAdmin() || p.OrganizationID == organizationID
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// Backoff is an exponential backoff, which doubles the delay after each failure within the bounds
+type Backoff struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+// Delay returns the delay before the next attempt after the given number of failures
+func (b Backoff) Delay(attempts int) time.Duration {
+	delay := b.Min
+	for i := 1; i < attempts && delay < b.Max; i++ {
+		delay *= 2
+	}
+	if delay > b.Max {
+		delay = b.Max
+	}
+	return delay
+}
@@ -1,14 +1,89 @@
 package domain
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// AccessTokenVersion is the kind of channel access token a channel issues
+type AccessTokenVersion string
+
+const (
+	// AccessTokenVersionV2 is the short-lived token issued with the channel secret
+	AccessTokenVersionV2 = AccessTokenVersion("v2")
+	// AccessTokenVersionV21 is the token issued with a JWT signed by the channel's assertion key
+	AccessTokenVersionV21 = AccessTokenVersion("v2.1")
+)
+
+// ChannelHealthStatus tells whether LINE accepts the channel's credentials
+type ChannelHealthStatus string
+
+const (
+	// ChannelHealthStatusUnknown is the status of channels which have not been checked yet
+	ChannelHealthStatusUnknown   = ChannelHealthStatus("unknown")
+	ChannelHealthStatusHealthy   = ChannelHealthStatus("healthy")
+	ChannelHealthStatusUnhealthy = ChannelHealthStatus("unhealthy")
+)
 
 type Channel struct {
 	ID                    int
+	OrganizationID        int
 	Name                  string
 	ExternalChannelID     string
 	ExternalChannelSecret string
 	AccessToken           string
 	AccessTokenExpiredAt  time.Time
-	CreatedAt             time.Time
-	UpdatedAt             time.Time
+	AccessTokenVersion    AccessTokenVersion
+	// AccessTokenKeyID is the key ID LINE assigned to the current v2.1 access token
+	AccessTokenKeyID string
+	// AssertionKeyID and AssertionPrivateKey are the registered assertion signing key used to issue
+	// v2.1 access tokens. The private key is either PEM or JWK encoded.
+	AssertionKeyID      string
+	AssertionPrivateKey string
+	// AccessTokenRefreshAttempts is the number of consecutive failures to refresh the access token,
+	// and AccessTokenRefreshAfter is the earliest time the refresher could pick up the channel again.
+	AccessTokenRefreshAttempts int
+	AccessTokenRefreshAfter    time.Time
+	// HealthStatus, HealthCheckedAt and HealthError are the result of the last health check, and
+	// HealthCheckAfter is the earliest time the health checker could pick up the channel again.
+	HealthStatus     ChannelHealthStatus
+	HealthCheckedAt  *time.Time
+	HealthError      string
+	HealthCheckAfter time.Time
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// UpdateChannelParams contains the channel fields to be updated. Nil fields are left unchanged.
+type UpdateChannelParams struct {
+	Name                       *string
+	ExternalChannelSecret      *string
+	AccessToken                *string
+	AccessTokenExpiredAt       *time.Time
+	AccessTokenVersion         *AccessTokenVersion
+	AccessTokenKeyID           *string
+	AssertionKeyID             *string
+	AssertionPrivateKey        *string
+	AccessTokenRefreshAttempts *int
+	AccessTokenRefreshAfter    *time.Time
+	HealthStatus               *ChannelHealthStatus
+	HealthCheckedAt            *time.Time
+	HealthError                *string
+	HealthCheckAfter           *time.Time
+}
+
+// SecretLast4 returns the last 4 characters of the channel secret, which is all a client could see.
+// Secrets of 4 characters or fewer return empty, since their last 4 characters are the whole secret.
+func (c Channel) SecretLast4() string {
+	if len(c.ExternalChannelSecret) <= 4 {
+		return ""
+	}
+	return c.ExternalChannelSecret[len(c.ExternalChannelSecret)-4:]
+}
+
+// SecretFingerprint returns a short hash of the channel secret to tell secrets apart without revealing them
+func (c Channel) SecretFingerprint() string {
+	sum := sha256.Sum256([]byte(c.ExternalChannelSecret))
+	return "sha256:" + hex.EncodeToString(sum[:8])
 }
@@ -0,0 +1,40 @@
+package domain
+
+import "encoding/json"
+
+// ChannelEvent is the LINE event of a channel, which is published to the event bus and handled by
+// the worker. The payload of its type is decoded, so the worker doesn't need to parse EventContent.
+type ChannelEvent struct {
+	OrganizationID     int             `json:"organizationID"`
+	ChannelID          int             `json:"channelID"`
+	ChannelAccessToken string          `json:"channelAccessToken"`
+	EventType          LineEventType   `json:"eventType"`
+	ExternalMemberID   string          `json:"externalMemberID"`
+	SourceType         LineSourceType  `json:"sourceType"`
+	ExternalGroupID    string          `json:"externalGroupID,omitempty"`
+	ExternalRoomID     string          `json:"externalRoomID,omitempty"`
+	DisplayName        string          `json:"displayName,omitempty"`
+	ReplyToken         string          `json:"replyToken"`
+	EventContent       json.RawMessage `json:"eventContent"`
+
+	Message           *LineMessage           `json:"message,omitempty"`
+	Postback          *LinePostback          `json:"postback,omitempty"`
+	Members           *LineMembers           `json:"members,omitempty"`
+	Unsend            *LineUnsend            `json:"unsend,omitempty"`
+	Beacon            *LineBeacon            `json:"beacon,omitempty"`
+	AccountLink       *LineAccountLink       `json:"accountLink,omitempty"`
+	VideoPlayComplete *LineVideoPlayComplete `json:"videoPlayComplete,omitempty"`
+}
+
+// ConversationID returns where messages to the event should be sent, which is the group or the room
+// the event happens in, or the user otherwise.
+func (e ChannelEvent) ConversationID() string {
+	switch e.SourceType {
+	case LineSourceTypeGroup:
+		return e.ExternalGroupID
+	case LineSourceTypeRoom:
+		return e.ExternalRoomID
+	default:
+		return e.ExternalMemberID
+	}
+}
@@ -0,0 +1,50 @@
+package domain
+
+import "time"
+
+// ChannelGroup is a group or a room (multi-person chat) the channel is in
+type ChannelGroup struct {
+	ID              int
+	ChannelID       int
+	ExternalGroupID string
+	// Type is either group or room
+	Type LineSourceType
+	// JoinedAt and LeftAt are the time of the latest join and leave events
+	JoinedAt *time.Time
+	LeftAt   *time.Time
+	// Left tells whether the channel has left the group, which is when the latest join event is older
+	// than the latest leave event
+	Left       bool
+	LastSeenAt time.Time
+	// Summary is fetched from LINE, and SummaryFetchedAt is nil if it has not been fetched yet
+	Summary          LineGroupSummary
+	SummaryFetchedAt *time.Time
+	// SummaryRefreshAttempts is the number of consecutive failures to fetch the summary, and
+	// SummaryRefreshAfter is when the summary is fetched next time
+	SummaryRefreshAttempts int
+	SummaryRefreshAfter    time.Time
+	CreatedAt              time.Time
+	UpdatedAt              time.Time
+}
+
+// UpsertChannelGroupParams records an event in the group. The group is created if it's the first
+// event of the group in the channel. Timestamps older than the stored ones are ignored, so events
+// could be recorded in any order.
+type UpsertChannelGroupParams struct {
+	ChannelID       int
+	ExternalGroupID string
+	Type            LineSourceType
+	JoinedAt        *time.Time
+	LeftAt          *time.Time
+	SeenAt          time.Time
+	// RefreshSummary makes the summary fetched again soon, e.g. when its members change
+	RefreshSummary bool
+}
+
+// UpdateChannelGroupSummaryParams contains the fields to be updated. Nil fields are left unchanged.
+type UpdateChannelGroupSummaryParams struct {
+	Summary                *LineGroupSummary
+	SummaryFetchedAt       *time.Time
+	SummaryRefreshAttempts *int
+	SummaryRefreshAfter    *time.Time
+}
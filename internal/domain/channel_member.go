@@ -0,0 +1,59 @@
+package domain
+
+import "time"
+
+// ChannelMember is a LINE user who has interacted with a channel
+type ChannelMember struct {
+	ID               int
+	ChannelID        int
+	ExternalMemberID string
+	// FollowedAt and UnfollowedAt are the time of the latest follow and unfollow events
+	FollowedAt   *time.Time
+	UnfollowedAt *time.Time
+	// Blocked tells whether the member has blocked the channel, which is when the latest follow
+	// event is older than the latest unfollow event
+	Blocked    bool
+	LastSeenAt time.Time
+	// SourceType is where the member was last seen, and ExternalGroupID is the group or the room if
+	// it's not a one-on-one chat. The profile is fetched from there, since LINE only returns profiles
+	// of users who are not friends of the channel within the groups and rooms they are in.
+	SourceType      LineSourceType
+	ExternalGroupID string
+	// Profile is fetched from LINE, and ProfileFetchedAt is nil if it has not been fetched yet
+	Profile          LineProfile
+	ProfileFetchedAt *time.Time
+	// ProfileRefreshAttempts is the number of consecutive failures to fetch the profile, and
+	// ProfileRefreshAfter is when the profile is fetched next time
+	ProfileRefreshAttempts int
+	ProfileRefreshAfter    time.Time
+	CreatedAt              time.Time
+	UpdatedAt              time.Time
+}
+
+// UpsertChannelMemberParams records an event of the member. The member is created if it's the first
+// event of the member in the channel. Timestamps older than the stored ones are ignored, so events
+// could be recorded in any order.
+type UpsertChannelMemberParams struct {
+	ChannelID        int
+	ExternalMemberID string
+	FollowedAt       *time.Time
+	UnfollowedAt     *time.Time
+	SeenAt           time.Time
+	// SourceType and ExternalGroupID are where the member is seen, which replace the stored ones
+	// unless the member has been seen later
+	SourceType      LineSourceType
+	ExternalGroupID string
+}
+
+// UpdateChannelMemberProfileParams contains the fields to be updated. Nil fields are left unchanged.
+type UpdateChannelMemberProfileParams struct {
+	Profile                *LineProfile
+	ProfileFetchedAt       *time.Time
+	ProfileRefreshAttempts *int
+	ProfileRefreshAfter    *time.Time
+}
+
+// ChannelMemberFilter filters channel members in list operations. Nil fields are not filtered.
+type ChannelMemberFilter struct {
+	Blocked *bool
+}
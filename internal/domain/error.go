@@ -144,3 +144,63 @@ func (e ParameterError) ClientMsg() string {
 	}
 	return e.clientMsg
 }
+
+// UnauthorizedError is used when requests are not authenticated
+type UnauthorizedError struct {
+	clientMsg string
+	err       error
+}
+
+func NewUnauthorizedError(clientMsg string, err error) Error {
+	if err, ok := err.(Error); ok {
+		return err
+	}
+	return UnauthorizedError{
+		clientMsg: clientMsg,
+		err:       err,
+	}
+}
+
+func (e UnauthorizedError) Error() string {
+	if e.err != nil {
+		return e.err.Error()
+	}
+	return ""
+}
+
+func (e UnauthorizedError) ClientMsg() string {
+	if e.clientMsg == "" {
+		return e.Error()
+	}
+	return e.clientMsg
+}
+
+// PermissionDeniedError is used when authenticated requests are not allowed to do the operation
+type PermissionDeniedError struct {
+	clientMsg string
+	err       error
+}
+
+func NewPermissionDeniedError(clientMsg string, err error) Error {
+	if err, ok := err.(Error); ok {
+		return err
+	}
+	return PermissionDeniedError{
+		clientMsg: clientMsg,
+		err:       err,
+	}
+}
+
+func (e PermissionDeniedError) Error() string {
+	if e.err != nil {
+		return e.err.Error()
+	}
+	return ""
+}
+
+func (e PermissionDeniedError) ClientMsg() string {
+	if e.clientMsg == "" {
+		return e.Error()
+	}
+	return e.clientMsg
+}
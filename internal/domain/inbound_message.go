@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// InboundMessage is a message sent to a channel by a user
+type InboundMessage struct {
+	ID                int64
+	ChannelID         int
+	ExternalMessageID string
+	ExternalMemberID  string
+	// SourceType and ExternalGroupID tell the conversation where the message is sent, and
+	// ExternalGroupID is the group or the room ID if it's not sent to the channel directly
+	SourceType      LineSourceType
+	ExternalGroupID string
+	MessageType     string
+	// Text is only set for text messages
+	Text string
+	// Raw is the webhook event of the message
+	Raw       json.RawMessage
+	SentAt    time.Time
+	CreatedAt time.Time
+}
+
+// InboundMessageFilter filters inbound messages in search operations. Empty fields are not filtered.
+type InboundMessageFilter struct {
+	ExternalMemberID string
+	// SentFrom is inclusive and SentTo is exclusive
+	SentFrom *time.Time
+	SentTo   *time.Time
+	// Query matches messages whose text contains every whitespace separated term of it, ignoring
+	// case. Terms are matched as substrings, so text without spaces between words, e.g. Chinese and
+	// Japanese, is searchable as well.
+	Query string
+}
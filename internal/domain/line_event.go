@@ -0,0 +1,48 @@
+package domain
+
+// Payloads of LINE webhook events by their types. Join and leave events have no payload.
+// Reference: https://developers.line.biz/en/reference/messaging-api/#webhook-event-objects
+
+// LineMessage is the payload of message events. Text is only set for text messages.
+type LineMessage struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+// LinePostback is the payload of postback events. Params are set by datetime picker and rich menu
+// switch actions.
+type LinePostback struct {
+	Data   string            `json:"data"`
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// LineMembers is the payload of memberJoined and memberLeft events, which are IDs of the users
+type LineMembers struct {
+	ExternalMemberIDs []string `json:"externalMemberIDs"`
+}
+
+// LineUnsend is the payload of unsend events
+type LineUnsend struct {
+	MessageID string `json:"messageID"`
+}
+
+// LineBeacon is the payload of beacon events
+type LineBeacon struct {
+	HWID string `json:"hwid"`
+	// Type is one of enter, leave, banner and stay
+	Type          string `json:"type"`
+	DeviceMessage []byte `json:"deviceMessage,omitempty"`
+}
+
+// LineAccountLink is the payload of accountLink events
+type LineAccountLink struct {
+	// Result is either ok or failed
+	Result string `json:"result"`
+	Nonce  string `json:"nonce"`
+}
+
+// LineVideoPlayComplete is the payload of videoPlayComplete events
+type LineVideoPlayComplete struct {
+	TrackingID string `json:"trackingID"`
+}
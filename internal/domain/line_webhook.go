@@ -1,5 +1,7 @@
 package domain
 
+import "time"
+
 type LineWebhook struct {
 	ExternalChannelID string
 	Signature         string
@@ -9,14 +11,101 @@ type LineWebhook struct {
 type LineEventType string
 
 const (
-	LineEventTypeMessage  = LineEventType("message")
-	LineEventTypeFollow   = LineEventType("follow")
-	LineEventTypeUnfollow = LineEventType("unfollow")
+	LineEventTypeMessage           = LineEventType("message")
+	LineEventTypeFollow            = LineEventType("follow")
+	LineEventTypeUnfollow          = LineEventType("unfollow")
+	LineEventTypeJoin              = LineEventType("join")
+	LineEventTypeLeave             = LineEventType("leave")
+	LineEventTypeMemberJoined      = LineEventType("memberJoined")
+	LineEventTypeMemberLeft        = LineEventType("memberLeft")
+	LineEventTypePostback          = LineEventType("postback")
+	LineEventTypeUnsend            = LineEventType("unsend")
+	LineEventTypeBeacon            = LineEventType("beacon")
+	LineEventTypeAccountLink       = LineEventType("accountLink")
+	LineEventTypeVideoPlayComplete = LineEventType("videoPlayComplete")
+)
+
+// LineSourceType is the type of the conversation where an event happens
+type LineSourceType string
+
+const (
+	LineSourceTypeUser  = LineSourceType("user")
+	LineSourceTypeGroup = LineSourceType("group")
+	LineSourceTypeRoom  = LineSourceType("room")
 )
 
 type LineEvent struct {
+	// ExternalMemberID is the user sending the event, which could be empty in groups and rooms, e.g.
+	// join events.
 	ExternalMemberID string
-	EventType        LineEventType
-	ReplyToken       string
-	EventContent     []byte
+	SourceType       LineSourceType
+	// ExternalGroupID and ExternalRoomID are set if the event happens in a group or a room
+	ExternalGroupID string
+	ExternalRoomID  string
+	EventType       LineEventType
+	ReplyToken      string
+	EventContent    []byte
+	Timestamp       time.Time
+	// WebhookEventID identifies the event, which stays the same when LINE redelivers it
+	WebhookEventID string
+	IsRedelivery   bool
+
+	// Payload of the event, where only the one of its type is set
+	Message           *LineMessage
+	Postback          *LinePostback
+	Members           *LineMembers
+	Unsend            *LineUnsend
+	Beacon            *LineBeacon
+	AccountLink       *LineAccountLink
+	VideoPlayComplete *LineVideoPlayComplete
+}
+
+// SourceGroupID returns the ID of the group or the room the event happens in, which is empty for
+// events of one-on-one chats
+func (e LineEvent) SourceGroupID() string {
+	switch e.SourceType {
+	case LineSourceTypeGroup:
+		return e.ExternalGroupID
+	case LineSourceTypeRoom:
+		return e.ExternalRoomID
+	default:
+		return ""
+	}
+}
+
+// LineProfile is the profile a LINE user shows to the channel
+type LineProfile struct {
+	DisplayName   string
+	PictureURL    string
+	StatusMessage string
+	Language      string
+}
+
+// LineGroupSummary is the summary of a group or a room. Rooms have no name and picture.
+type LineGroupSummary struct {
+	Name        string
+	PictureURL  string
+	MemberCount int
+}
+
+// LineWebhookTestResult is the result of LINE sending a test webhook event to the endpoint
+type LineWebhookTestResult struct {
+	Success bool
+	// StatusCode is the status code returned by the endpoint
+	StatusCode int
+	Reason     string
+	Detail     string
+	Timestamp  time.Time
+}
+
+// LineWebhookStatus reports whether the webhook endpoint is set to the channel at LINE and whether
+// LINE could reach it.
+type LineWebhookStatus struct {
+	Endpoint   string
+	Configured bool
+	Reachable  bool
+	// StatusCode, Reason and Detail explain why the endpoint is not reachable
+	StatusCode int
+	Reason     string
+	Detail     string
 }
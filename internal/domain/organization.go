@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// DefaultOrganizationID is the organization owning channels and slides which were created before
+// organizations are introduced
+const DefaultOrganizationID = 1
+
+// Organization owns channels and slides. Every team sharing the deployment has its own organization.
+type Organization struct {
+	ID        int
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// UpdateOrganizationParams contains the organization fields to be updated. Nil fields are left unchanged.
+type UpdateOrganizationParams struct {
+	Name *string
+}
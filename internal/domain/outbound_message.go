@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// LineSendMode is the API a message is sent to LINE with
+type LineSendMode string
+
+const (
+	LineSendModeReply = LineSendMode("reply")
+	LineSendModePush  = LineSendMode("push")
+)
+
+// LineSendAttempt is the outcome of one call to LINE sending messages
+type LineSendAttempt struct {
+	Mode LineSendMode
+	// RequestID and HTTPStatus are returned by LINE, which are empty if LINE is not reached
+	RequestID  string
+	HTTPStatus int
+	// Err is nil if the messages are sent
+	Err error
+}
+
+type OutboundMessageStatus string
+
+const (
+	OutboundMessageStatusSucceeded = OutboundMessageStatus("succeeded")
+	OutboundMessageStatusFailed    = OutboundMessageStatus("failed")
+	// OutboundMessageStatusRetrying is a failed attempt being retried
+	OutboundMessageStatusRetrying = OutboundMessageStatus("retrying")
+)
+
+// OutboundMessage is an attempt to send messages to a recipient. Attempts of one delivery, e.g. the
+// push falling back from a failed reply, and retries of it share the DeliveryID.
+type OutboundMessage struct {
+	ID         int64
+	ChannelID  int
+	DeliveryID string
+	// Recipient is the user, the group or the room ID
+	Recipient string
+	Mode      LineSendMode
+	// Payload is the JSON array of the sent messages
+	Payload       json.RawMessage
+	LineRequestID string
+	HTTPStatus    int
+	Status        OutboundMessageStatus
+	Error         string
+	CreatedAt     time.Time
+}
+
+// OutboundMessageFilter filters outbound messages in list operations. Empty fields are not filtered.
+type OutboundMessageFilter struct {
+	Recipient  string
+	DeliveryID string
+	Status     OutboundMessageStatus
+}
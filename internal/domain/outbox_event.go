@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// OutboxEvent is an event waiting to be published to the event bus. Events are stored in the outbox
+// first, so they are not lost if the event bus is unavailable, and the relay publishes them later.
+type OutboxEvent struct {
+	ID int64
+	// Payload is the event published as it is. It's encrypted at rest since it contains the channel
+	// access token.
+	Payload       string
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+	SentAt        *time.Time
+	// DeadAt is set when the relay gives up publishing the event. Dead events are kept in the outbox
+	// for inspection and are published again only if DeadAt is cleared.
+	DeadAt    *time.Time
+	CreatedAt time.Time
+}
+
+// UpdateOutboxEventParams contains the fields to be updated. Nil fields are left unchanged.
+type UpdateOutboxEventParams struct {
+	Attempts      *int
+	LastError     *string
+	NextAttemptAt *time.Time
+	SentAt        *time.Time
+	DeadAt        *time.Time
+}
+
+// WebhookOutboxEvent is an event of a LINE webhook to be stored in the outbox
+type WebhookOutboxEvent struct {
+	// WebhookEventID identifies the event among redeliveries. Events without it are always stored.
+	WebhookEventID string
+	Payload        string
+}
@@ -0,0 +1,12 @@
+package domain
+
+const (
+	DefaultPaginationLimit = 20
+	MaxPaginationLimit     = 100
+)
+
+// Pagination is the offset-based pagination used by list operations
+type Pagination struct {
+	Limit  int
+	Offset int
+}
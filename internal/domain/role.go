@@ -0,0 +1,78 @@
+package domain
+
+// Permission is an operation on resources of an organization
+type Permission string
+
+const (
+	PermissionOrganizationManage = Permission("organization.manage")
+	PermissionChannelRead        = Permission("channel.read")
+	PermissionChannelManage      = Permission("channel.manage")
+	PermissionSlideRead          = Permission("slide.read")
+	PermissionSlideEdit          = Permission("slide.edit")
+	PermissionMessageSend        = Permission("message.send")
+	PermissionMessageRead        = Permission("message.read")
+	PermissionMemberTag          = Permission("member.tag")
+)
+
+// Role is a set of permissions granted to API keys of an organization
+type Role string
+
+const (
+	// RoleOwner manages the organization, its API keys and channel credentials
+	RoleOwner = Role("owner")
+	// RoleEditor works on slides, messages and member tags of existing channels
+	RoleEditor = Role("editor")
+	// RoleViewer only reads
+	RoleViewer = Role("viewer")
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleOwner: {
+		PermissionOrganizationManage,
+		PermissionChannelRead,
+		PermissionChannelManage,
+		PermissionSlideRead,
+		PermissionSlideEdit,
+		PermissionMessageSend,
+		PermissionMessageRead,
+		PermissionMemberTag,
+	},
+	RoleEditor: {
+		PermissionChannelRead,
+		PermissionSlideRead,
+		PermissionSlideEdit,
+		PermissionMessageSend,
+		PermissionMessageRead,
+		PermissionMemberTag,
+	},
+	RoleViewer: {
+		PermissionChannelRead,
+		PermissionSlideRead,
+	},
+}
+
+// Roles returns all roles from the most to the least privileged
+func Roles() []Role {
+	return []Role{RoleOwner, RoleEditor, RoleViewer}
+}
+
+// Valid tells whether the role is defined
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Permissions returns the permissions granted to the role
+func (r Role) Permissions() []Permission {
+	return rolePermissions[r]
+}
+
+// Has tells whether the role is granted the permission
+func (r Role) Has(permission Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// MaxTagExpressionDepth limits how deeply tag expressions could be nested
+const MaxTagExpressionDepth = 5
+
+// Tag labels members of a channel
+type Tag struct {
+	ID        int
+	ChannelID int
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// UpdateTagParams contains the tag fields to be updated. Nil fields are left unchanged.
+type UpdateTagParams struct {
+	Name *string
+}
+
+type TagOperator string
+
+const (
+	TagOperatorAnd = TagOperator("and")
+	TagOperatorOr  = TagOperator("or")
+)
+
+// TagExpression selects members by their tags. Members match it if they match the operator applied
+// to all of its operands, which are having each tag of TagIDs and matching each nested expression.
+type TagExpression struct {
+	Operator    TagOperator
+	TagIDs      []int
+	Expressions []TagExpression
+}
+
+// Validate checks the expression has known operators, no empty operand list and limited depth
+func (e TagExpression) Validate() error {
+	return e.validate(1)
+}
+
+func (e TagExpression) validate(depth int) error {
+	if depth > MaxTagExpressionDepth {
+		return errors.New("tag expression is nested too deeply")
+	}
+	if e.Operator != TagOperatorAnd && e.Operator != TagOperatorOr {
+		return errors.New("unknown tag operator")
+	}
+	if len(e.TagIDs) == 0 && len(e.Expressions) == 0 {
+		return errors.New("tag expression has no operand")
+	}
+	for _, sub := range e.Expressions {
+		if err := sub.validate(depth + 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AllTagIDs returns IDs of all tags referenced by the expression and its nested expressions
+func (e TagExpression) AllTagIDs() []int {
+	ids := append([]int{}, e.TagIDs...)
+	for _, sub := range e.Expressions {
+		ids = append(ids, sub.AllTagIDs()...)
+	}
+	return ids
+}
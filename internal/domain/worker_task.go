@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type WorkerTaskStatus string
+
+const (
+	// WorkerTaskStatusPending is the status of tasks received by the worker but not started yet
+	WorkerTaskStatusPending   = WorkerTaskStatus("pending")
+	WorkerTaskStatusRunning   = WorkerTaskStatus("running")
+	WorkerTaskStatusSucceeded = WorkerTaskStatus("succeeded")
+	WorkerTaskStatusFailed    = WorkerTaskStatus("failed")
+)
+
+// WorkerTask tracks an event handled by the worker. Retried deliveries of the same event share the
+// task, and Attempts counts how many times it has been started.
+type WorkerTask struct {
+	ID             int
+	OrganizationID int
+	ChannelID      int
+	// EventID identifies the delivered event, which stays the same when it's retried
+	EventID   string
+	EventType string
+	// Payload is the LINE event being handled. Channel credentials are never stored in it.
+	Payload    json.RawMessage
+	Status     WorkerTaskStatus
+	Attempts   int
+	LastError  string
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// WorkerTaskFilter filters worker tasks in list operations. Empty fields are not filtered.
+type WorkerTaskFilter struct {
+	Status WorkerTaskStatus
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/david7482/aws-serverless-service/html"
 	"github.com/david7482/aws-serverless-service/internal/app"
+	"github.com/david7482/aws-serverless-service/internal/domain"
 )
 
 func RegisterHandlers(router *gin.Engine, app *app.Application) {
@@ -28,10 +29,66 @@ func registerAPIHandlers(router *gin.Engine, app *app.Application) {
 		webhookGroup.POST("/line/:external_channel_id/events", ReceiveWebhookFromLine(app))
 	}
 
-	// Add channel namespace
-	channelGroup := v1.Group("/channel")
+	// Add auth namespace
+	authGroup := v1.Group("/auth")
 	{
-		channelGroup.POST("/line/channels", CreateLineChannel(app))
+		authGroup.POST("/token", IssueBearerToken(app))
+		authGroup.GET("/roles", ListRoles(app))
+	}
+
+	// Add organization namespace
+	v1.POST("/orgs", requireAdmin(), CreateOrganization(app))
+	v1.GET("/orgs", ListOrganizations(app))
+	orgGroup := v1.Group("/orgs/:org_id", requireOrganization(app))
+	{
+		manageOrganization := requirePermission(app, domain.PermissionOrganizationManage)
+		orgGroup.GET("", GetOrganization(app))
+		orgGroup.PATCH("", manageOrganization, UpdateOrganization(app))
+		orgGroup.POST("/api-keys", manageOrganization, IssueAPIKey(app))
+		orgGroup.GET("/api-keys", manageOrganization, ListAPIKeys(app))
+		orgGroup.PATCH("/api-keys/:key_id", manageOrganization, UpdateAPIKey(app))
+		orgGroup.DELETE("/api-keys/:key_id", manageOrganization, RevokeAPIKey(app))
+		orgGroup.GET("/worker-tasks", manageOrganization, ListWorkerTasks(app))
+		orgGroup.GET("/worker-tasks/:task_id", manageOrganization, GetWorkerTask(app))
+	}
+
+	// Add channel namespace, whose resources all belong to the organization
+	channelGroup := orgGroup.Group("/channel")
+	{
+		readChannel := requirePermission(app, domain.PermissionChannelRead)
+		manageChannel := requirePermission(app, domain.PermissionChannelManage)
+		tagMember := requirePermission(app, domain.PermissionMemberTag)
+		readMessage := requirePermission(app, domain.PermissionMessageRead)
+		sendMessage := requirePermission(app, domain.PermissionMessageSend)
+		editSlide := requirePermission(app, domain.PermissionSlideEdit)
+		channelGroup.POST("/line/channels", manageChannel, CreateLineChannel(app))
+		channelGroup.GET("/line/channels", readChannel, ListLineChannels(app))
+		channelGroup.GET("/line/channels/:channel_id", readChannel, GetLineChannel(app))
+		channelGroup.PATCH("/line/channels/:channel_id", manageChannel, UpdateLineChannel(app))
+		channelGroup.DELETE("/line/channels/:channel_id", manageChannel, DeleteLineChannel(app))
+		channelGroup.POST("/line/channels/:channel_id/secret/reveal", manageChannel, RevealLineChannelSecret(app))
+		channelGroup.GET("/line/channels/:channel_id/access-tokens", manageChannel, ListLineChannelAccessTokens(app))
+		channelGroup.DELETE("/line/channels/:channel_id/access-tokens/:token_id", manageChannel, RevokeLineChannelAccessToken(app))
+		channelGroup.PUT("/line/channels/:channel_id/slide/current-page", editSlide, UpdateLineChannelSlideCurrentPage(app))
+		channelGroup.GET("/line/channels/:channel_id/members", readChannel, ListLineChannelMembers(app))
+		channelGroup.GET("/line/channels/:channel_id/members/:external_member_id", readChannel, GetLineChannelMember(app))
+		channelGroup.POST("/line/channels/:channel_id/segments/members", readChannel, ListLineChannelSegmentMembers(app))
+		channelGroup.GET("/line/channels/:channel_id/groups", readChannel, ListLineChannelGroups(app))
+		channelGroup.GET("/line/channels/:channel_id/groups/:external_group_id", readChannel, GetLineChannelGroup(app))
+		channelGroup.GET("/line/channels/:channel_id/messages", readMessage, SearchLineChannelMessages(app))
+		channelGroup.GET("/line/channels/:channel_id/outbound-messages", readMessage, ListLineChannelOutboundMessages(app))
+		channelGroup.GET("/line/channels/:channel_id/outbound-messages/:message_id", readMessage, GetLineChannelOutboundMessage(app))
+		channelGroup.POST("/line/channels/:channel_id/outbound-messages/:message_id/retry", sendMessage, RetryLineChannelOutboundMessage(app))
+		channelGroup.GET("/line/channels/:channel_id/members/:external_member_id/tags", readChannel, ListLineChannelMemberTags(app))
+		channelGroup.PUT("/line/channels/:channel_id/members/:external_member_id/tags/:tag_id", tagMember, AttachLineChannelMemberTag(app))
+		channelGroup.DELETE("/line/channels/:channel_id/members/:external_member_id/tags/:tag_id", tagMember, DetachLineChannelMemberTag(app))
+		channelGroup.POST("/line/channels/:channel_id/tags", tagMember, CreateLineChannelTag(app))
+		channelGroup.GET("/line/channels/:channel_id/tags", readChannel, ListLineChannelTags(app))
+		channelGroup.GET("/line/channels/:channel_id/tags/:tag_id", readChannel, GetLineChannelTag(app))
+		channelGroup.PATCH("/line/channels/:channel_id/tags/:tag_id", tagMember, UpdateLineChannelTag(app))
+		channelGroup.DELETE("/line/channels/:channel_id/tags/:tag_id", tagMember, DeleteLineChannelTag(app))
+		channelGroup.POST("/line/channels/:channel_id/tags/:tag_id/members/attach", tagMember, BulkAttachLineChannelTag(app))
+		channelGroup.POST("/line/channels/:channel_id/tags/:tag_id/members/detach", tagMember, BulkDetachLineChannelTag(app))
 	}
 }
 
@@ -41,4 +98,5 @@ func registerHTMLHandlers(router *gin.Engine, app *app.Application) {
 	router.SetHTMLTemplate(templ)
 
 	router.GET("/slide", RenderSlidePage(app))
+	router.GET("/orgs/:org_id/channels/:channel_id/slide", requireOrganization(app), requirePermission(app, domain.PermissionSlideRead), RenderSlidePage(app))
 }
@@ -0,0 +1,217 @@
+package router
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/david7482/aws-serverless-service/internal/app"
+	"github.com/david7482/aws-serverless-service/internal/app/service/auth"
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+type apiKeyResponse struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Role       string     `json:"role"`
+	Prefix     string     `json:"prefix"`
+	CreatedBy  string     `json:"createdBy"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	ExpiredAt  *time.Time `json:"expiredAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func newAPIKeyResponse(key domain.APIKey) apiKeyResponse {
+	return apiKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Role:       string(key.Role),
+		Prefix:     key.Prefix,
+		CreatedBy:  key.CreatedBy,
+		LastUsedAt: key.LastUsedAt,
+		ExpiredAt:  key.ExpiredAt,
+		RevokedAt:  key.RevokedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
+
+// IssueBearerToken exchanges the credential of the request for a short-lived bearer token
+func IssueBearerToken(app *app.Application) gin.HandlerFunc {
+	type Response struct {
+		AccessToken string    `json:"accessToken"`
+		TokenType   string    `json:"tokenType"`
+		ExpiredAt   time.Time `json:"expiredAt"`
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		principal, err := principalFromContext(c)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		token, expiredAt, err := app.TokenService.IssueToken(ctx, principal)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		respondWithJSON(c, http.StatusCreated, Response{
+			AccessToken: token,
+			TokenType:   "Bearer",
+			ExpiredAt:   expiredAt,
+		})
+	}
+}
+
+func IssueAPIKey(app *app.Application) gin.HandlerFunc {
+	type Body struct {
+		Name string `json:"name" binding:"required,max=255"`
+		// Role of the key in the organization, which is viewer if it's omitted
+		Role string `json:"role" binding:"omitempty,oneof=owner editor viewer"`
+		// ExpiresIn is the lifetime of the key in seconds. The key never expires if it's omitted.
+		ExpiresIn int `json:"expiresIn" binding:"omitempty,min=1"`
+	}
+	type Response struct {
+		apiKeyResponse
+		// Key is only returned once
+		Key string `json:"key"`
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		var body Body
+		if err := c.ShouldBind(&body); err != nil {
+			respondWithError(c, domain.NewParameterError("invalid parameter", err))
+			return
+		}
+
+		role := domain.RoleViewer
+		if body.Role != "" {
+			role = domain.Role(body.Role)
+		}
+
+		key, plaintext, err := app.AccountService.IssueAPIKey(ctx, auth.IssueAPIKeyParam{
+			OrganizationID: organizationFromContext(c).ID,
+			Name:           body.Name,
+			Role:           role,
+			ExpiresIn:      time.Duration(body.ExpiresIn) * time.Second,
+			CreatedBy:      actorFromContext(c),
+		})
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		respondWithJSON(c, http.StatusCreated, Response{
+			apiKeyResponse: newAPIKeyResponse(*key),
+			Key:            plaintext,
+		})
+	}
+}
+
+func ListAPIKeys(app *app.Application) gin.HandlerFunc {
+	type Response struct {
+		APIKeys []apiKeyResponse `json:"apiKeys"`
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		keys, err := app.AccountService.ListAPIKeys(ctx, organizationFromContext(c).ID)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		res := Response{APIKeys: make([]apiKeyResponse, 0, len(keys))}
+		for _, key := range keys {
+			res.APIKeys = append(res.APIKeys, newAPIKeyResponse(key))
+		}
+
+		respondWithJSON(c, http.StatusOK, res)
+	}
+}
+
+func UpdateAPIKey(app *app.Application) gin.HandlerFunc {
+	type Body struct {
+		Role string `json:"role" binding:"required,oneof=owner editor viewer"`
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		keyID, err := parseIntParam(c, "key_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		var body Body
+		if err := c.ShouldBind(&body); err != nil {
+			respondWithError(c, domain.NewParameterError("invalid parameter", err))
+			return
+		}
+
+		key, err := app.AccountService.UpdateAPIKeyRole(ctx, organizationFromContext(c).ID, keyID, domain.Role(body.Role), actorFromContext(c))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		respondWithJSON(c, http.StatusOK, newAPIKeyResponse(*key))
+	}
+}
+
+func RevokeAPIKey(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		keyID, err := parseIntParam(c, "key_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		err = app.AccountService.RevokeAPIKey(ctx, organizationFromContext(c).ID, keyID, actorFromContext(c))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		respondWithoutBody(c, http.StatusNoContent)
+	}
+}
+
+// ListRoles returns the roles API keys could be assigned and the permissions granted to them
+func ListRoles(app *app.Application) gin.HandlerFunc {
+	type Role struct {
+		Name        string   `json:"name"`
+		Permissions []string `json:"permissions"`
+	}
+	type Response struct {
+		Roles []Role `json:"roles"`
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		roles := app.RoleService.ListRoles(ctx)
+
+		res := Response{Roles: make([]Role, 0, len(roles))}
+		for _, role := range roles {
+			permissions := make([]string, 0, len(role.Permissions()))
+			for _, permission := range role.Permissions() {
+				permissions = append(permissions, string(permission))
+			}
+			res.Roles = append(res.Roles, Role{Name: string(role), Permissions: permissions})
+		}
+
+		respondWithJSON(c, http.StatusOK, res)
+	}
+}
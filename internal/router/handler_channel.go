@@ -7,22 +7,69 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/david7482/aws-serverless-service/internal/app"
+	"github.com/david7482/aws-serverless-service/internal/app/service/channel"
 	"github.com/david7482/aws-serverless-service/internal/domain"
 )
 
+// channelResponse never contains the channel secret. Only its last 4 characters and fingerprint
+// are returned, and the secret itself is only available through RevealLineChannelSecret.
+type channelResponse struct {
+	ID                               int        `json:"id"`
+	Name                             string     `json:"name"`
+	ExternalChannelID                string     `json:"externalChannelID"`
+	ExternalChannelSecretLast4       string     `json:"externalChannelSecretLast4"`
+	ExternalChannelSecretFingerprint string     `json:"externalChannelSecretFingerprint"`
+	AccessTokenVersion               string     `json:"accessTokenVersion"`
+	AccessTokenExpiredAt             time.Time  `json:"accessTokenExpiredAt"`
+	AssertionKeyID                   string     `json:"assertionKeyID,omitempty"`
+	HealthStatus                     string     `json:"healthStatus"`
+	HealthCheckedAt                  *time.Time `json:"healthCheckedAt"`
+	HealthError                      string     `json:"healthError,omitempty"`
+	CreatedAt                        time.Time  `json:"created_at"`
+	UpdatedAt                        time.Time  `json:"updated_at"`
+}
+
+func newChannelResponse(channel domain.Channel) channelResponse {
+	return channelResponse{
+		ID:                               channel.ID,
+		Name:                             channel.Name,
+		ExternalChannelID:                channel.ExternalChannelID,
+		ExternalChannelSecretLast4:       channel.SecretLast4(),
+		ExternalChannelSecretFingerprint: channel.SecretFingerprint(),
+		AccessTokenVersion:               string(channel.AccessTokenVersion),
+		AccessTokenExpiredAt:             channel.AccessTokenExpiredAt,
+		AssertionKeyID:                   channel.AssertionKeyID,
+		HealthStatus:                     string(channel.HealthStatus),
+		HealthCheckedAt:                  channel.HealthCheckedAt,
+		HealthError:                      channel.HealthError,
+		CreatedAt:                        channel.CreatedAt,
+		UpdatedAt:                        channel.UpdatedAt,
+	}
+}
+
 func CreateLineChannel(app *app.Application) gin.HandlerFunc {
 	type Body struct {
 		ExternalChannelID     string `json:"externalChannelID" binding:"required"`
 		ExternalChannelSecret string `json:"externalChannelSecret" binding:"required"`
+		// Optional assertion key to issue v2.1 access tokens
+		AssertionKeyID      string `json:"assertionKeyID" binding:"required_with=AssertionPrivateKey"`
+		AssertionPrivateKey string `json:"assertionPrivateKey" binding:"required_with=AssertionKeyID"`
+		// Set the webhook endpoint at LINE and test it
+		ConfigureWebhook bool `json:"configureWebhook"`
+	}
+	type Webhook struct {
+		Endpoint   string `json:"endpoint"`
+		Configured bool   `json:"configured"`
+		Reachable  bool   `json:"reachable"`
+		StatusCode int    `json:"statusCode,omitempty"`
+		Reason     string `json:"reason,omitempty"`
+		Detail     string `json:"detail,omitempty"`
 	}
-
 	type Response struct {
-		ID                    int       `json:"id"`
-		Name                  string    `json:"name"`
-		ExternalChannelID     string    `json:"externalChannelID"`
-		ExternalChannelSecret string    `json:"externalChannelSecret"`
-		CreatedAt             time.Time `json:"created_at"`
+		channelResponse
+		Webhook *Webhook `json:"webhook,omitempty"`
 	}
+
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
 
@@ -33,21 +80,249 @@ func CreateLineChannel(app *app.Application) gin.HandlerFunc {
 			return
 		}
 
-		channel, err := app.ChannelService.CreateChannel(ctx, body.ExternalChannelID, body.ExternalChannelSecret)
+		createdChannel, webhook, err := app.ChannelService.CreateChannel(ctx, channel.CreateChannelParam{
+			OrganizationID:        organizationFromContext(c).ID,
+			ExternalChannelID:     body.ExternalChannelID,
+			ExternalChannelSecret: body.ExternalChannelSecret,
+			AssertionKeyID:        body.AssertionKeyID,
+			AssertionPrivateKey:   body.AssertionPrivateKey,
+			ConfigureWebhook:      body.ConfigureWebhook,
+		})
 		if err != nil {
 			respondWithError(c, err)
 			return
 		}
 
-		res := Response{
-			ID:                    channel.ID,
-			Name:                  channel.Name,
-			ExternalChannelID:     channel.ExternalChannelID,
-			ExternalChannelSecret: channel.ExternalChannelSecret,
-			CreatedAt:             channel.CreatedAt,
+		res := Response{channelResponse: newChannelResponse(*createdChannel)}
+		if webhook != nil {
+			res.Webhook = &Webhook{
+				Endpoint:   webhook.Endpoint,
+				Configured: webhook.Configured,
+				Reachable:  webhook.Reachable,
+				StatusCode: webhook.StatusCode,
+				Reason:     webhook.Reason,
+				Detail:     webhook.Detail,
+			}
 		}
-
 		respondWithJSON(c, http.StatusCreated, res)
 		return
 	}
 }
+
+func ListLineChannels(app *app.Application) gin.HandlerFunc {
+	type Response struct {
+		Channels []channelResponse `json:"channels"`
+		Total    int               `json:"total"`
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		pagination, err := parsePagination(c)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		channels, total, err := app.ChannelService.ListChannels(ctx, organizationFromContext(c).ID, pagination)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		res := Response{
+			Channels: make([]channelResponse, 0, len(channels)),
+			Total:    total,
+		}
+		for _, channel := range channels {
+			res.Channels = append(res.Channels, newChannelResponse(channel))
+		}
+
+		respondWithJSON(c, http.StatusOK, res)
+	}
+}
+
+func GetLineChannel(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		channelID, err := parseIntParam(c, "channel_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		channel, err := app.ChannelService.GetChannel(ctx, organizationFromContext(c).ID, channelID)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		respondWithJSON(c, http.StatusOK, newChannelResponse(*channel))
+	}
+}
+
+func UpdateLineChannel(app *app.Application) gin.HandlerFunc {
+	type Body struct {
+		Name                  *string `json:"name" binding:"omitempty,min=1,max=255"`
+		ExternalChannelSecret *string `json:"externalChannelSecret" binding:"omitempty,min=1"`
+		AccessTokenVersion    *string `json:"accessTokenVersion" binding:"omitempty,oneof=v2 v2.1"`
+		AssertionKeyID        *string `json:"assertionKeyID" binding:"omitempty,min=1"`
+		AssertionPrivateKey   *string `json:"assertionPrivateKey" binding:"omitempty,min=1"`
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		channelID, err := parseIntParam(c, "channel_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		var body Body
+		if err := c.ShouldBind(&body); err != nil {
+			respondWithError(c, domain.NewParameterError("invalid parameter", err))
+			return
+		}
+
+		param := channel.UpdateChannelParam{
+			Name:                  body.Name,
+			ExternalChannelSecret: body.ExternalChannelSecret,
+			AssertionKeyID:        body.AssertionKeyID,
+			AssertionPrivateKey:   body.AssertionPrivateKey,
+		}
+		if body.AccessTokenVersion != nil {
+			version := domain.AccessTokenVersion(*body.AccessTokenVersion)
+			param.AccessTokenVersion = &version
+		}
+
+		updatedChannel, err := app.ChannelService.UpdateChannel(ctx, organizationFromContext(c).ID, channelID, param)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		respondWithJSON(c, http.StatusOK, newChannelResponse(*updatedChannel))
+	}
+}
+
+func DeleteLineChannel(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		channelID, err := parseIntParam(c, "channel_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		err = app.ChannelService.DeleteChannel(ctx, organizationFromContext(c).ID, channelID)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		respondWithoutBody(c, http.StatusNoContent)
+	}
+}
+
+func RevealLineChannelSecret(app *app.Application) gin.HandlerFunc {
+	type Response struct {
+		ExternalChannelSecret string `json:"externalChannelSecret"`
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		channelID, err := parseIntParam(c, "channel_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		secret, err := app.ChannelService.RevealChannelSecret(ctx, organizationFromContext(c).ID, channelID, actorFromContext(c))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		respondWithJSON(c, http.StatusOK, Response{ExternalChannelSecret: secret})
+	}
+}
+
+func ListLineChannelAccessTokens(app *app.Application) gin.HandlerFunc {
+	type AccessToken struct {
+		ID        int        `json:"id"`
+		KeyID     string     `json:"keyID"`
+		InUse     bool       `json:"inUse"`
+		ExpiredAt time.Time  `json:"expiredAt"`
+		RevokedAt *time.Time `json:"revokedAt"`
+		CreatedAt time.Time  `json:"created_at"`
+	}
+	type Response struct {
+		AccessTokens []AccessToken `json:"accessTokens"`
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		channelID, err := parseIntParam(c, "channel_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		channel, err := app.ChannelService.GetChannel(ctx, organizationFromContext(c).ID, channelID)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		tokens, err := app.ChannelService.ListChannelAccessTokens(ctx, organizationFromContext(c).ID, channelID)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		// The access token itself is never returned
+		res := Response{AccessTokens: make([]AccessToken, 0, len(tokens))}
+		for _, token := range tokens {
+			res.AccessTokens = append(res.AccessTokens, AccessToken{
+				ID:        token.ID,
+				KeyID:     token.KeyID,
+				InUse:     token.KeyID == channel.AccessTokenKeyID,
+				ExpiredAt: token.ExpiredAt,
+				RevokedAt: token.RevokedAt,
+				CreatedAt: token.CreatedAt,
+			})
+		}
+
+		respondWithJSON(c, http.StatusOK, res)
+	}
+}
+
+func RevokeLineChannelAccessToken(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		channelID, err := parseIntParam(c, "channel_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		tokenID, err := parseIntParam(c, "token_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		err = app.ChannelService.RevokeChannelAccessToken(ctx, organizationFromContext(c).ID, channelID, tokenID)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		respondWithoutBody(c, http.StatusNoContent)
+	}
+}
@@ -0,0 +1,101 @@
+package router
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/david7482/aws-serverless-service/internal/app"
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+type channelGroupResponse struct {
+	ExternalGroupID  string     `json:"externalGroupID"`
+	Type             string     `json:"type"`
+	Name             string     `json:"name,omitempty"`
+	PictureURL       string     `json:"pictureURL,omitempty"`
+	MemberCount      int        `json:"memberCount"`
+	JoinedAt         *time.Time `json:"joinedAt"`
+	LeftAt           *time.Time `json:"leftAt"`
+	Left             bool       `json:"left"`
+	LastSeenAt       time.Time  `json:"lastSeenAt"`
+	SummaryFetchedAt *time.Time `json:"summaryFetchedAt"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+func newChannelGroupResponse(group domain.ChannelGroup) channelGroupResponse {
+	return channelGroupResponse{
+		ExternalGroupID:  group.ExternalGroupID,
+		Type:             string(group.Type),
+		Name:             group.Summary.Name,
+		PictureURL:       group.Summary.PictureURL,
+		MemberCount:      group.Summary.MemberCount,
+		JoinedAt:         group.JoinedAt,
+		LeftAt:           group.LeftAt,
+		Left:             group.Left,
+		LastSeenAt:       group.LastSeenAt,
+		SummaryFetchedAt: group.SummaryFetchedAt,
+		CreatedAt:        group.CreatedAt,
+		UpdatedAt:        group.UpdatedAt,
+	}
+}
+
+func ListLineChannelGroups(app *app.Application) gin.HandlerFunc {
+	type Response struct {
+		Groups []channelGroupResponse `json:"groups"`
+		Total  int                    `json:"total"`
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		channelID, err := parseIntParam(c, "channel_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		pagination, err := parsePagination(c)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		groups, total, err := app.MemberService.ListChannelGroups(ctx, organizationFromContext(c).ID, channelID, pagination)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		res := Response{
+			Groups: make([]channelGroupResponse, 0, len(groups)),
+			Total:  total,
+		}
+		for _, group := range groups {
+			res.Groups = append(res.Groups, newChannelGroupResponse(group))
+		}
+
+		respondWithJSON(c, http.StatusOK, res)
+	}
+}
+
+func GetLineChannelGroup(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		channelID, err := parseIntParam(c, "channel_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		group, err := app.MemberService.GetChannelGroup(ctx, organizationFromContext(c).ID, channelID, c.Param("external_group_id"))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		respondWithJSON(c, http.StatusOK, newChannelGroupResponse(*group))
+	}
+}
@@ -0,0 +1,93 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/david7482/aws-serverless-service/internal/app"
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+type inboundMessageResponse struct {
+	ID                int64           `json:"id"`
+	ExternalMessageID string          `json:"externalMessageID"`
+	ExternalMemberID  string          `json:"externalMemberID"`
+	SourceType        string          `json:"sourceType"`
+	ExternalGroupID   string          `json:"externalGroupID,omitempty"`
+	MessageType       string          `json:"messageType"`
+	Text              string          `json:"text,omitempty"`
+	Raw               json.RawMessage `json:"raw"`
+	SentAt            time.Time       `json:"sentAt"`
+	CreatedAt         time.Time       `json:"created_at"`
+}
+
+func newInboundMessageResponse(message domain.InboundMessage) inboundMessageResponse {
+	return inboundMessageResponse{
+		ID:                message.ID,
+		ExternalMessageID: message.ExternalMessageID,
+		ExternalMemberID:  message.ExternalMemberID,
+		SourceType:        string(message.SourceType),
+		ExternalGroupID:   message.ExternalGroupID,
+		MessageType:       message.MessageType,
+		Text:              message.Text,
+		Raw:               message.Raw,
+		SentAt:            message.SentAt,
+		CreatedAt:         message.CreatedAt,
+	}
+}
+
+// SearchLineChannelMessages searches messages sent to the channel. They could be filtered by
+// externalMemberID, sent time between from and to in RFC 3339, and terms in the text q.
+func SearchLineChannelMessages(app *app.Application) gin.HandlerFunc {
+	type Response struct {
+		Messages []inboundMessageResponse `json:"messages"`
+		Total    int                      `json:"total"`
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		channelID, err := parseIntParam(c, "channel_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		pagination, err := parsePagination(c)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		filter := domain.InboundMessageFilter{
+			ExternalMemberID: c.Query("externalMemberID"),
+			Query:            c.Query("q"),
+		}
+		if filter.SentFrom, err = parseTimeQuery(c, "from"); err != nil {
+			respondWithError(c, err)
+			return
+		}
+		if filter.SentTo, err = parseTimeQuery(c, "to"); err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		messages, total, err := app.HistoryService.SearchInboundMessages(ctx, organizationFromContext(c).ID, channelID, filter, pagination)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		res := Response{
+			Messages: make([]inboundMessageResponse, 0, len(messages)),
+			Total:    total,
+		}
+		for _, message := range messages {
+			res.Messages = append(res.Messages, newInboundMessageResponse(message))
+		}
+
+		respondWithJSON(c, http.StatusOK, res)
+	}
+}
@@ -0,0 +1,113 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/david7482/aws-serverless-service/internal/app"
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+type channelMemberResponse struct {
+	ExternalMemberID string     `json:"externalMemberID"`
+	FollowedAt       *time.Time `json:"followedAt"`
+	UnfollowedAt     *time.Time `json:"unfollowedAt"`
+	Blocked          bool       `json:"blocked"`
+	LastSeenAt       time.Time  `json:"lastSeenAt"`
+	DisplayName      string     `json:"displayName"`
+	PictureURL       string     `json:"pictureURL,omitempty"`
+	StatusMessage    string     `json:"statusMessage,omitempty"`
+	Language         string     `json:"language,omitempty"`
+	ProfileFetchedAt *time.Time `json:"profileFetchedAt"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+func newChannelMemberResponse(member domain.ChannelMember) channelMemberResponse {
+	return channelMemberResponse{
+		ExternalMemberID: member.ExternalMemberID,
+		FollowedAt:       member.FollowedAt,
+		UnfollowedAt:     member.UnfollowedAt,
+		Blocked:          member.Blocked,
+		LastSeenAt:       member.LastSeenAt,
+		DisplayName:      member.Profile.DisplayName,
+		PictureURL:       member.Profile.PictureURL,
+		StatusMessage:    member.Profile.StatusMessage,
+		Language:         member.Profile.Language,
+		ProfileFetchedAt: member.ProfileFetchedAt,
+		CreatedAt:        member.CreatedAt,
+		UpdatedAt:        member.UpdatedAt,
+	}
+}
+
+func ListLineChannelMembers(app *app.Application) gin.HandlerFunc {
+	type Response struct {
+		Members []channelMemberResponse `json:"members"`
+		Total   int                     `json:"total"`
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		channelID, err := parseIntParam(c, "channel_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		pagination, err := parsePagination(c)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		// Optionally filter members by whether they block the channel
+		var filter domain.ChannelMemberFilter
+		if value, ok := c.GetQuery("blocked"); ok {
+			blocked, parseErr := strconv.ParseBool(value)
+			if parseErr != nil {
+				respondWithError(c, domain.NewParameterError("invalid blocked", parseErr))
+				return
+			}
+			filter.Blocked = &blocked
+		}
+
+		members, total, err := app.MemberService.ListChannelMembers(ctx, organizationFromContext(c).ID, channelID, filter, pagination)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		res := Response{
+			Members: make([]channelMemberResponse, 0, len(members)),
+			Total:   total,
+		}
+		for _, member := range members {
+			res.Members = append(res.Members, newChannelMemberResponse(member))
+		}
+
+		respondWithJSON(c, http.StatusOK, res)
+	}
+}
+
+func GetLineChannelMember(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		channelID, err := parseIntParam(c, "channel_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		member, err := app.MemberService.GetChannelMember(ctx, organizationFromContext(c).ID, channelID, c.Param("external_member_id"))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		respondWithJSON(c, http.StatusOK, newChannelMemberResponse(*member))
+	}
+}
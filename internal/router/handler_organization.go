@@ -0,0 +1,139 @@
+package router
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/david7482/aws-serverless-service/internal/app"
+	"github.com/david7482/aws-serverless-service/internal/app/service/organization"
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+type organizationResponse struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newOrganizationResponse(org domain.Organization) organizationResponse {
+	return organizationResponse{
+		ID:        org.ID,
+		Name:      org.Name,
+		CreatedAt: org.CreatedAt,
+		UpdatedAt: org.UpdatedAt,
+	}
+}
+
+func CreateOrganization(app *app.Application) gin.HandlerFunc {
+	type Body struct {
+		Name string `json:"name" binding:"required,max=255"`
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		var body Body
+		if err := c.ShouldBind(&body); err != nil {
+			respondWithError(c, domain.NewParameterError("invalid parameter", err))
+			return
+		}
+
+		org, err := app.OrgService.CreateOrganization(ctx, organization.CreateOrganizationParam{
+			Name: body.Name,
+		})
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		respondWithJSON(c, http.StatusCreated, newOrganizationResponse(*org))
+	}
+}
+
+func ListOrganizations(app *app.Application) gin.HandlerFunc {
+	type Response struct {
+		Organizations []organizationResponse `json:"organizations"`
+		Total         int                    `json:"total"`
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		pagination, err := parsePagination(c)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		principal, err := principalFromContext(c)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		// Principals other than the admin only see their own organization
+		if !principal.IsAdmin() {
+			org, err := app.OrgService.GetOrganization(ctx, principal.OrganizationID)
+			if err != nil {
+				respondWithError(c, err)
+				return
+			}
+			respondWithJSON(c, http.StatusOK, Response{
+				Organizations: []organizationResponse{newOrganizationResponse(*org)},
+				Total:         1,
+			})
+			return
+		}
+
+		orgs, total, err := app.OrgService.ListOrganizations(ctx, pagination)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		res := Response{
+			Organizations: make([]organizationResponse, 0, len(orgs)),
+			Total:         total,
+		}
+		for _, org := range orgs {
+			res.Organizations = append(res.Organizations, newOrganizationResponse(org))
+		}
+
+		respondWithJSON(c, http.StatusOK, res)
+	}
+}
+
+func GetOrganization(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		respondWithJSON(c, http.StatusOK, newOrganizationResponse(organizationFromContext(c)))
+	}
+}
+
+func UpdateOrganization(app *app.Application) gin.HandlerFunc {
+	type Body struct {
+		Name *string `json:"name" binding:"omitempty,min=1,max=255"`
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		var body Body
+		if err := c.ShouldBind(&body); err != nil {
+			respondWithError(c, domain.NewParameterError("invalid parameter", err))
+			return
+		}
+
+		org, err := app.OrgService.UpdateOrganization(ctx, organizationFromContext(c).ID, organization.UpdateOrganizationParam{
+			Name: body.Name,
+		})
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		respondWithJSON(c, http.StatusOK, newOrganizationResponse(*org))
+	}
+}
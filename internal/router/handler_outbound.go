@@ -0,0 +1,156 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/david7482/aws-serverless-service/internal/app"
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+type outboundMessageResponse struct {
+	ID            int64           `json:"id"`
+	DeliveryID    string          `json:"deliveryID"`
+	Recipient     string          `json:"recipient"`
+	Mode          string          `json:"mode"`
+	Payload       json.RawMessage `json:"payload"`
+	LineRequestID string          `json:"lineRequestID,omitempty"`
+	HTTPStatus    int             `json:"httpStatus"`
+	Status        string          `json:"status"`
+	Error         string          `json:"error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+func newOutboundMessageResponse(message domain.OutboundMessage) outboundMessageResponse {
+	return outboundMessageResponse{
+		ID:            message.ID,
+		DeliveryID:    message.DeliveryID,
+		Recipient:     message.Recipient,
+		Mode:          string(message.Mode),
+		Payload:       message.Payload,
+		LineRequestID: message.LineRequestID,
+		HTTPStatus:    message.HTTPStatus,
+		Status:        string(message.Status),
+		Error:         message.Error,
+		CreatedAt:     message.CreatedAt,
+	}
+}
+
+// parseOutboundMessageID parses the message_id path parameter, which is a 64-bit integer
+func parseOutboundMessageID(c *gin.Context) (int64, domain.Error) {
+	id, err := strconv.ParseInt(c.Param("message_id"), 10, 64)
+	if err != nil {
+		return 0, domain.NewParameterError("invalid message_id", err)
+	}
+	return id, nil
+}
+
+// ListLineChannelOutboundMessages lists attempts of sending messages, which could be filtered by
+// recipient, deliveryID and status.
+func ListLineChannelOutboundMessages(app *app.Application) gin.HandlerFunc {
+	type Response struct {
+		Messages []outboundMessageResponse `json:"messages"`
+		Total    int                       `json:"total"`
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		channelID, err := parseIntParam(c, "channel_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		pagination, err := parsePagination(c)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		filter := domain.OutboundMessageFilter{
+			Recipient:  c.Query("recipient"),
+			DeliveryID: c.Query("deliveryID"),
+			Status:     domain.OutboundMessageStatus(c.Query("status")),
+		}
+		switch filter.Status {
+		case "", domain.OutboundMessageStatusSucceeded, domain.OutboundMessageStatusFailed, domain.OutboundMessageStatusRetrying:
+		default:
+			msg := "invalid status"
+			respondWithError(c, domain.NewParameterError(msg, errors.New(msg)))
+			return
+		}
+
+		messages, total, err := app.OutboundService.ListOutboundMessages(ctx, organizationFromContext(c).ID, channelID, filter, pagination)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		res := Response{
+			Messages: make([]outboundMessageResponse, 0, len(messages)),
+			Total:    total,
+		}
+		for _, message := range messages {
+			res.Messages = append(res.Messages, newOutboundMessageResponse(message))
+		}
+
+		respondWithJSON(c, http.StatusOK, res)
+	}
+}
+
+func GetLineChannelOutboundMessage(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		channelID, err := parseIntParam(c, "channel_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		messageID, err := parseOutboundMessageID(c)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		message, err := app.OutboundService.GetOutboundMessage(ctx, organizationFromContext(c).ID, channelID, messageID)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		respondWithJSON(c, http.StatusOK, newOutboundMessageResponse(*message))
+	}
+}
+
+// RetryLineChannelOutboundMessage pushes messages of the failed attempt again, and returns the new
+// attempt, whose status tells whether the retry succeeds.
+func RetryLineChannelOutboundMessage(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		channelID, err := parseIntParam(c, "channel_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		messageID, err := parseOutboundMessageID(c)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		message, err := app.OutboundService.RetryOutboundMessage(ctx, organizationFromContext(c).ID, channelID, messageID)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		respondWithJSON(c, http.StatusCreated, newOutboundMessageResponse(*message))
+	}
+}
@@ -10,12 +10,28 @@ import (
 	"github.com/david7482/aws-serverless-service/internal/domain"
 )
 
-const channelID = 1
+// defaultSlideChannelID is the channel whose slides are rendered by the page without organization
+// and channel in its path
+const defaultSlideChannelID = 1
 
+// RenderSlidePage renders a page of the slide deck with links to the previous and the next pages. The
+// page is read-only, and the current page sent by the chatbot is changed through the admin API, see
+// UpdateLineChannelSlideCurrentPage.
 func RenderSlidePage(app *app.Application) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
 
+		organizationID, channelID := domain.DefaultOrganizationID, defaultSlideChannelID
+		if c.Param("org_id") != "" {
+			var err domain.Error
+			organizationID = organizationFromContext(c).ID
+			channelID, err = parseIntParam(c, "channel_id")
+			if err != nil {
+				respondWithError(c, err)
+				return
+			}
+		}
+
 		// Get query parameter with default value
 		page := c.DefaultQuery("page", "1")
 
@@ -25,19 +41,13 @@ func RenderSlidePage(app *app.Application) gin.HandlerFunc {
 			return
 		}
 
-		url, p, err := app.SlideService.GetSlideURL(ctx, channelID, p)
-		if err != nil {
-			respondWithError(c, err)
-			return
-		}
-
-		prev, next, err := app.SlideService.GetPrevNext(ctx, channelID, p)
+		url, p, err := app.SlideService.GetSlideURL(ctx, organizationID, channelID, p)
 		if err != nil {
 			respondWithError(c, err)
 			return
 		}
 
-		err = app.SlideService.UpdateCurrentPage(ctx, channelID, p)
+		prev, next, err := app.SlideService.GetPrevNext(ctx, organizationID, channelID, p)
 		if err != nil {
 			respondWithError(c, err)
 			return
@@ -51,3 +61,39 @@ func RenderSlidePage(app *app.Application) gin.HandlerFunc {
 		})
 	}
 }
+
+// UpdateLineChannelSlideCurrentPage changes the current page of the slide deck, which the chatbot sends
+func UpdateLineChannelSlideCurrentPage(app *app.Application) gin.HandlerFunc {
+	type Body struct {
+		Page int `json:"page" binding:"required,min=1"`
+	}
+
+	type Response struct {
+		Page int    `json:"page"`
+		URL  string `json:"url"`
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		channelID, err := parseIntParam(c, "channel_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		var body Body
+		if err := c.ShouldBind(&body); err != nil {
+			respondWithError(c, domain.NewParameterError("invalid parameter", err))
+			return
+		}
+
+		url, err := app.SlideService.UpdateCurrentPage(ctx, organizationFromContext(c).ID, channelID, body.Page)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		respondWithJSON(c, http.StatusOK, Response{Page: body.Page, URL: url})
+	}
+}
@@ -0,0 +1,364 @@
+package router
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/david7482/aws-serverless-service/internal/app"
+	"github.com/david7482/aws-serverless-service/internal/app/service/tag"
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+type tagResponse struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newTagResponse(tag domain.Tag) tagResponse {
+	return tagResponse{
+		ID:        tag.ID,
+		Name:      tag.Name,
+		CreatedAt: tag.CreatedAt,
+		UpdatedAt: tag.UpdatedAt,
+	}
+}
+
+func newTagsResponse(tags []domain.Tag) []tagResponse {
+	res := make([]tagResponse, 0, len(tags))
+	for _, tag := range tags {
+		res = append(res, newTagResponse(tag))
+	}
+	return res
+}
+
+// tagExpressionBody is the JSON form of domain.TagExpression, e.g.
+// {"operator": "or", "tagIDs": [1], "expressions": [{"operator": "and", "tagIDs": [2, 3]}]}
+// selects members having tag 1, or having both tag 2 and tag 3.
+type tagExpressionBody struct {
+	Operator    string              `json:"operator"`
+	TagIDs      []int               `json:"tagIDs"`
+	Expressions []tagExpressionBody `json:"expressions"`
+}
+
+func (b tagExpressionBody) toDomain() domain.TagExpression {
+	expression := domain.TagExpression{
+		Operator: domain.TagOperator(b.Operator),
+		TagIDs:   b.TagIDs,
+	}
+	for _, sub := range b.Expressions {
+		expression.Expressions = append(expression.Expressions, sub.toDomain())
+	}
+	return expression
+}
+
+func CreateLineChannelTag(app *app.Application) gin.HandlerFunc {
+	type Body struct {
+		Name string `json:"name" binding:"required,max=255"`
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		channelID, err := parseIntParam(c, "channel_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		var body Body
+		if err := c.ShouldBind(&body); err != nil {
+			respondWithError(c, domain.NewParameterError("invalid parameter", err))
+			return
+		}
+
+		created, err := app.TagService.CreateTag(ctx, tag.CreateTagParam{
+			OrganizationID: organizationFromContext(c).ID,
+			ChannelID:      channelID,
+			Name:           body.Name,
+		})
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		respondWithJSON(c, http.StatusCreated, newTagResponse(*created))
+	}
+}
+
+func ListLineChannelTags(app *app.Application) gin.HandlerFunc {
+	type Response struct {
+		Tags []tagResponse `json:"tags"`
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		channelID, err := parseIntParam(c, "channel_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		tags, err := app.TagService.ListTags(ctx, organizationFromContext(c).ID, channelID)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		respondWithJSON(c, http.StatusOK, Response{Tags: newTagsResponse(tags)})
+	}
+}
+
+func GetLineChannelTag(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		channelID, err := parseIntParam(c, "channel_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		tagID, err := parseIntParam(c, "tag_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		got, err := app.TagService.GetTag(ctx, organizationFromContext(c).ID, channelID, tagID)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		respondWithJSON(c, http.StatusOK, newTagResponse(*got))
+	}
+}
+
+func UpdateLineChannelTag(app *app.Application) gin.HandlerFunc {
+	type Body struct {
+		Name *string `json:"name" binding:"omitempty,min=1,max=255"`
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		channelID, err := parseIntParam(c, "channel_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		tagID, err := parseIntParam(c, "tag_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		var body Body
+		if err := c.ShouldBind(&body); err != nil {
+			respondWithError(c, domain.NewParameterError("invalid parameter", err))
+			return
+		}
+
+		updated, err := app.TagService.UpdateTag(ctx, organizationFromContext(c).ID, channelID, tagID, tag.UpdateTagParam{
+			Name: body.Name,
+		})
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		respondWithJSON(c, http.StatusOK, newTagResponse(*updated))
+	}
+}
+
+func DeleteLineChannelTag(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		channelID, err := parseIntParam(c, "channel_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		tagID, err := parseIntParam(c, "tag_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		err = app.TagService.DeleteTag(ctx, organizationFromContext(c).ID, channelID, tagID)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		respondWithoutBody(c, http.StatusNoContent)
+	}
+}
+
+// bulkTagLineChannelMembers attaches or detaches the tag on members given in the body
+func bulkTagLineChannelMembers(app *app.Application, attach bool) gin.HandlerFunc {
+	type Body struct {
+		ExternalMemberIDs []string `json:"externalMemberIDs" binding:"required,min=1"`
+	}
+	type Response struct {
+		// Count is the number of members whose tags are changed
+		Count int `json:"count"`
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		channelID, err := parseIntParam(c, "channel_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		tagID, err := parseIntParam(c, "tag_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		var body Body
+		if err := c.ShouldBind(&body); err != nil {
+			respondWithError(c, domain.NewParameterError("invalid parameter", err))
+			return
+		}
+
+		bulk := app.TagChannelMemberService.DetachTag
+		if attach {
+			bulk = app.TagChannelMemberService.AttachTag
+		}
+		count, err := bulk(ctx, organizationFromContext(c).ID, channelID, tagID, body.ExternalMemberIDs)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		respondWithJSON(c, http.StatusOK, Response{Count: count})
+	}
+}
+
+func BulkAttachLineChannelTag(app *app.Application) gin.HandlerFunc {
+	return bulkTagLineChannelMembers(app, true)
+}
+
+func BulkDetachLineChannelTag(app *app.Application) gin.HandlerFunc {
+	return bulkTagLineChannelMembers(app, false)
+}
+
+// tagLineChannelMember attaches or detaches the tag on the member of the path
+func tagLineChannelMember(app *app.Application, attach bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		channelID, err := parseIntParam(c, "channel_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		tagID, err := parseIntParam(c, "tag_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		bulk := app.TagChannelMemberService.DetachTag
+		if attach {
+			bulk = app.TagChannelMemberService.AttachTag
+		}
+		_, err = bulk(ctx, organizationFromContext(c).ID, channelID, tagID, []string{c.Param("external_member_id")})
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		respondWithoutBody(c, http.StatusNoContent)
+	}
+}
+
+func AttachLineChannelMemberTag(app *app.Application) gin.HandlerFunc {
+	return tagLineChannelMember(app, true)
+}
+
+func DetachLineChannelMemberTag(app *app.Application) gin.HandlerFunc {
+	return tagLineChannelMember(app, false)
+}
+
+func ListLineChannelMemberTags(app *app.Application) gin.HandlerFunc {
+	type Response struct {
+		Tags []tagResponse `json:"tags"`
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		channelID, err := parseIntParam(c, "channel_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		tags, err := app.TagChannelMemberService.ListMemberTags(ctx, organizationFromContext(c).ID, channelID, c.Param("external_member_id"))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		respondWithJSON(c, http.StatusOK, Response{Tags: newTagsResponse(tags)})
+	}
+}
+
+// ListLineChannelSegmentMembers returns members matching the tag expression in the body. It's a POST
+// since the expression doesn't fit in query strings well.
+func ListLineChannelSegmentMembers(app *app.Application) gin.HandlerFunc {
+	type Body struct {
+		Expression tagExpressionBody `json:"expression" binding:"required"`
+	}
+	type Response struct {
+		Members []channelMemberResponse `json:"members"`
+		Total   int                     `json:"total"`
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		channelID, err := parseIntParam(c, "channel_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		pagination, err := parsePagination(c)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		var body Body
+		if err := c.ShouldBind(&body); err != nil {
+			respondWithError(c, domain.NewParameterError("invalid parameter", err))
+			return
+		}
+
+		members, total, err := app.TagChannelMemberService.ListSegmentMembers(ctx, organizationFromContext(c).ID, channelID, body.Expression.toDomain(), pagination)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		res := Response{
+			Members: make([]channelMemberResponse, 0, len(members)),
+			Total:   total,
+		}
+		for _, member := range members {
+			res.Members = append(res.Members, newChannelMemberResponse(member))
+		}
+
+		respondWithJSON(c, http.StatusOK, res)
+	}
+}
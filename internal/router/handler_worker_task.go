@@ -0,0 +1,112 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/david7482/aws-serverless-service/internal/app"
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+type workerTaskResponse struct {
+	ID         int             `json:"id"`
+	ChannelID  int             `json:"channelID"`
+	EventID    string          `json:"eventID"`
+	EventType  string          `json:"eventType"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+	Status     string          `json:"status"`
+	Attempts   int             `json:"attempts"`
+	LastError  string          `json:"lastError,omitempty"`
+	StartedAt  *time.Time      `json:"startedAt"`
+	FinishedAt *time.Time      `json:"finishedAt"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// newWorkerTaskResponse returns the task. The payload is only included when inspecting a single task.
+func newWorkerTaskResponse(task domain.WorkerTask, withPayload bool) workerTaskResponse {
+	res := workerTaskResponse{
+		ID:         task.ID,
+		ChannelID:  task.ChannelID,
+		EventID:    task.EventID,
+		EventType:  task.EventType,
+		Status:     string(task.Status),
+		Attempts:   task.Attempts,
+		LastError:  task.LastError,
+		StartedAt:  task.StartedAt,
+		FinishedAt: task.FinishedAt,
+		CreatedAt:  task.CreatedAt,
+		UpdatedAt:  task.UpdatedAt,
+	}
+	if withPayload {
+		res.Payload = task.Payload
+	}
+	return res
+}
+
+func ListWorkerTasks(app *app.Application) gin.HandlerFunc {
+	type Response struct {
+		Tasks []workerTaskResponse `json:"tasks"`
+		Total int                  `json:"total"`
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		pagination, err := parsePagination(c)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		// Optionally filter tasks by status, e.g. status=failed
+		filter := domain.WorkerTaskFilter{Status: domain.WorkerTaskStatus(c.Query("status"))}
+		switch filter.Status {
+		case "", domain.WorkerTaskStatusPending, domain.WorkerTaskStatusRunning, domain.WorkerTaskStatusSucceeded, domain.WorkerTaskStatusFailed:
+		default:
+			msg := "invalid status"
+			respondWithError(c, domain.NewParameterError(msg, errors.New(msg)))
+			return
+		}
+
+		tasks, total, err := app.WorkerTaskService.ListTasks(ctx, organizationFromContext(c).ID, filter, pagination)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		res := Response{
+			Tasks: make([]workerTaskResponse, 0, len(tasks)),
+			Total: total,
+		}
+		for _, task := range tasks {
+			res.Tasks = append(res.Tasks, newWorkerTaskResponse(task, false))
+		}
+
+		respondWithJSON(c, http.StatusOK, res)
+	}
+}
+
+func GetWorkerTask(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		taskID, err := parseIntParam(c, "task_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		task, err := app.WorkerTaskService.GetTask(ctx, organizationFromContext(c).ID, taskID)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		respondWithJSON(c, http.StatusOK, newWorkerTaskResponse(*task, true))
+	}
+}
@@ -0,0 +1,98 @@
+package router
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/david7482/aws-serverless-service/internal/app"
+	"github.com/david7482/aws-serverless-service/internal/app/service/auth"
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+const contextKeyOrganization = "organization"
+
+// requireOrganization loads the organization of the org_id path parameter, so handlers under it only
+// work on resources of the organization. Requests must be authenticated, and organizations the
+// principal cannot access are reported as not found.
+func requireOrganization(app *app.Application) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		organizationID, err := parseIntParam(c, "org_id")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		principal, err := principalFromContext(c)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		if !principal.CanAccessOrganization(organizationID) {
+			msg := "organization is not found"
+			respondWithError(c, domain.NewResourceNotFoundError(msg, errors.New(msg)))
+			return
+		}
+
+		organization, err := app.OrgService.GetOrganization(ctx, organizationID)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.Set(contextKeyOrganization, *organization)
+		c.Next()
+	}
+}
+
+// organizationFromContext returns the organization loaded by requireOrganization
+func organizationFromContext(c *gin.Context) domain.Organization {
+	return c.MustGet(contextKeyOrganization).(domain.Organization)
+}
+
+// principalFromContext returns the principal of the authenticated request
+func principalFromContext(c *gin.Context) (domain.Principal, domain.Error) {
+	principal, ok := auth.PrincipalFromContext(c.Request.Context())
+	if !ok {
+		msg := "request is not authenticated"
+		return domain.Principal{}, domain.NewUnauthorizedError(msg, errors.New(msg))
+	}
+	return principal, nil
+}
+
+// requireAdmin only allows the admin to access the handlers
+func requireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, err := principalFromContext(c)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		if !principal.IsAdmin() {
+			msg := "admin API key is required"
+			respondWithError(c, domain.NewPermissionDeniedError(msg, errors.New(msg)))
+			return
+		}
+		c.Next()
+	}
+}
+
+// requirePermission only allows principals granted the permission in the organization to access the
+// handlers. It's used after requireOrganization, which has checked the principal could access the
+// organization.
+func requirePermission(app *app.Application, permission domain.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, err := principalFromContext(c)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		if err := app.RoleService.Authorize(c.Request.Context(), principal, permission); err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,65 @@
+package router
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/requestid"
+	"github.com/gin-gonic/gin"
+
+	"github.com/david7482/aws-serverless-service/internal/app/service/auth"
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+// parseIntParam parses the path parameter with the given name as an integer
+func parseIntParam(c *gin.Context, name string) (int, domain.Error) {
+	value, err := strconv.Atoi(c.Param(name))
+	if err != nil {
+		return 0, domain.NewParameterError("invalid "+name, err)
+	}
+	return value, nil
+}
+
+// parsePagination parses the limit and offset query parameters with default values
+func parsePagination(c *gin.Context) (domain.Pagination, domain.Error) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(domain.DefaultPaginationLimit)))
+	if err != nil || limit <= 0 || limit > domain.MaxPaginationLimit {
+		return domain.Pagination{}, domain.NewParameterError("invalid limit", err)
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		return domain.Pagination{}, domain.NewParameterError("invalid offset", err)
+	}
+
+	return domain.Pagination{
+		Limit:  limit,
+		Offset: offset,
+	}, nil
+}
+
+// parseTimeQuery parses the optional RFC 3339 time in the query string
+func parseTimeQuery(c *gin.Context, key string) (*time.Time, domain.Error) {
+	value, ok := c.GetQuery(key)
+	if !ok || value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, domain.NewParameterError("invalid "+key, err)
+	}
+	return &t, nil
+}
+
+// actorFromContext identifies who sends the request
+func actorFromContext(c *gin.Context) domain.Actor {
+	actor := domain.Actor{
+		ID:        "anonymous",
+		RequestID: requestid.Get(c),
+		ClientIP:  c.ClientIP(),
+	}
+	if principal, ok := auth.PrincipalFromContext(c.Request.Context()); ok {
+		actor.ID = principal.ID()
+	}
+	return actor
+}
@@ -13,11 +13,13 @@ import (
 type ErrorCategory string
 
 const (
-	ErrorCategoryParameter = ErrorCategory("PARAMETER_ERROR")
-	ErrorCategoryResource  = ErrorCategory("RESOURCE_ERROR")
-	ErrorCategoryInternal  = ErrorCategory("INTERNAL_ERROR")
-	ErrorCategoryExternal  = ErrorCategory("EXTERNAL_ERROR")
-	ErrorCategoryUnknown   = ErrorCategory("UNKNOWN_ERROR")
+	ErrorCategoryParameter  = ErrorCategory("PARAMETER_ERROR")
+	ErrorCategoryResource   = ErrorCategory("RESOURCE_ERROR")
+	ErrorCategoryAuth       = ErrorCategory("AUTH_ERROR")
+	ErrorCategoryPermission = ErrorCategory("PERMISSION_ERROR")
+	ErrorCategoryInternal   = ErrorCategory("INTERNAL_ERROR")
+	ErrorCategoryExternal   = ErrorCategory("EXTERNAL_ERROR")
+	ErrorCategoryUnknown    = ErrorCategory("UNKNOWN_ERROR")
 )
 
 type ErrorMessage struct {
@@ -45,6 +47,12 @@ func respondWithError(c *gin.Context, err error) {
 	c.AbortWithStatusJSON(code, payload)
 }
 
+// AbortWithError responds with the error and stops the handler chain. It's for middlewares outside
+// this package.
+func AbortWithError(c *gin.Context, err error) {
+	respondWithError(c, err)
+}
+
 func parseError(err error) (int, ErrorCategory, string) {
 	// Handle InternalError
 	var internalProcessError domain.InternalError
@@ -70,6 +78,18 @@ func parseError(err error) (int, ErrorCategory, string) {
 		return http.StatusBadRequest, ErrorCategoryParameter, parameterError.ClientMsg()
 	}
 
+	// Handle UnauthorizedError
+	var unauthorizedError domain.UnauthorizedError
+	if valid := errors.As(err, &unauthorizedError); valid {
+		return http.StatusUnauthorized, ErrorCategoryAuth, unauthorizedError.ClientMsg()
+	}
+
+	// Handle PermissionDeniedError
+	var permissionDeniedError domain.PermissionDeniedError
+	if valid := errors.As(err, &permissionDeniedError); valid {
+		return http.StatusForbidden, ErrorCategoryPermission, permissionDeniedError.ClientMsg()
+	}
+
 	// Return default status code and category
 	return http.StatusInternalServerError, ErrorCategoryUnknown, "unknown internal error"
 }
@@ -0,0 +1,31 @@
+package worker
+
+import (
+	"context"
+
+	"github.com/david7482/aws-serverless-service/internal/adapter/line"
+	"github.com/david7482/aws-serverless-service/internal/app/service/outbound"
+	"github.com/david7482/aws-serverless-service/internal/app/service/workertask"
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+//go:generate mockgen -destination automock/slide_repository.go -package=automock . SlideRepository
+type SlideRepository interface {
+	GetEnabledSlideURL(ctx context.Context, organizationID, channelID int) (string, domain.Error)
+}
+
+//go:generate mockgen -destination automock/task_service.go -package=automock . TaskService
+type TaskService interface {
+	StartTask(ctx context.Context, param workertask.StartTaskParam) (*domain.WorkerTask, domain.Error)
+	FinishTask(ctx context.Context, taskID int, taskErr error) domain.Error
+}
+
+//go:generate mockgen -destination automock/line_service.go -package=automock . LineService
+type LineService interface {
+	SendMessage(ctx context.Context, params line.SendMessageParams) ([]domain.LineSendAttempt, error)
+}
+
+//go:generate mockgen -destination automock/outbound_service.go -package=automock . OutboundService
+type OutboundService interface {
+	RecordAttempts(ctx context.Context, param outbound.RecordAttemptsParam) ([]domain.OutboundMessage, domain.Error)
+}
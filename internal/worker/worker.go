@@ -0,0 +1,177 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/line/line-bot-sdk-go/v7/linebot"
+	"github.com/rs/zerolog"
+
+	"github.com/david7482/aws-serverless-service/internal/adapter/line"
+	"github.com/david7482/aws-serverless-service/internal/app/service/outbound"
+	"github.com/david7482/aws-serverless-service/internal/app/service/workertask"
+	"github.com/david7482/aws-serverless-service/internal/domain"
+)
+
+const (
+	// downloadSlideText is the message asking for the slide
+	downloadSlideText = "Download Slide"
+	// downloadSlidePostbackData is the postback data of actions asking for the slide, e.g. rich menus
+	downloadSlidePostbackData = "action=download_slide"
+)
+
+// Worker replies webhook events, which is run by the Lambda worker, or by the service itself in
+// local mode.
+type Worker struct {
+	slideRepo       SlideRepository
+	taskService     TaskService
+	lineService     LineService
+	outboundService OutboundService
+	handlers        map[domain.LineEventType]eventHandler
+}
+
+// eventHandler handles events of one type
+type eventHandler func(ctx context.Context, e domain.ChannelEvent) error
+
+type WorkerParam struct {
+	SlideRepo       SlideRepository
+	TaskService     TaskService
+	LineService     LineService
+	OutboundService OutboundService
+}
+
+func NewWorker(_ context.Context, param WorkerParam) *Worker {
+	w := &Worker{
+		slideRepo:       param.SlideRepo,
+		taskService:     param.TaskService,
+		lineService:     param.LineService,
+		outboundService: param.OutboundService,
+	}
+	w.handlers = map[domain.LineEventType]eventHandler{
+		domain.LineEventTypeMessage:           w.handleMessage,
+		domain.LineEventTypePostback:          w.handlePostback,
+		domain.LineEventTypeFollow:            w.ignoreEvent,
+		domain.LineEventTypeUnfollow:          w.ignoreEvent,
+		domain.LineEventTypeJoin:              w.ignoreEvent,
+		domain.LineEventTypeLeave:             w.ignoreEvent,
+		domain.LineEventTypeMemberJoined:      w.ignoreEvent,
+		domain.LineEventTypeMemberLeft:        w.ignoreEvent,
+		domain.LineEventTypeUnsend:            w.ignoreEvent,
+		domain.LineEventTypeBeacon:            w.ignoreEvent,
+		domain.LineEventTypeAccountLink:       w.ignoreEvent,
+		domain.LineEventTypeVideoPlayComplete: w.ignoreEvent,
+	}
+	return w
+}
+
+// logger wrap the execution context with component info
+func (w *Worker) logger(ctx context.Context) *zerolog.Logger {
+	l := zerolog.Ctx(ctx).With().Str("component", "worker").Logger()
+	return &l
+}
+
+// HandleEvent handles the event and tracks it as a task. The eventID must stay the same when the
+// event is retried.
+func (w *Worker) HandleEvent(ctx context.Context, eventID string, e domain.ChannelEvent) error {
+	// Track the event as a task. The reply is still sent if it cannot be tracked.
+	task, _ := w.taskService.StartTask(ctx, workertask.StartTaskParam{
+		OrganizationID: e.OrganizationID,
+		ChannelID:      e.ChannelID,
+		EventID:        eventID,
+		EventType:      string(e.EventType),
+		Payload:        e.EventContent,
+	})
+
+	err := w.handleEvent(ctx, e)
+	if task != nil {
+		_ = w.taskService.FinishTask(ctx, task.ID, err)
+	}
+	return err
+}
+
+// handleEvent routes the event to the handler of its type
+func (w *Worker) handleEvent(ctx context.Context, e domain.ChannelEvent) error {
+	handler, ok := w.handlers[e.EventType]
+	if !ok {
+		w.logger(ctx).Warn().Str("eventType", string(e.EventType)).Msg("no handler for the event type")
+		return nil
+	}
+	return handler(ctx, e)
+}
+
+func (w *Worker) handleMessage(ctx context.Context, e domain.ChannelEvent) error {
+	if e.Message == nil || e.Message.Text != downloadSlideText {
+		// do nothing if it's not DownloadSlide message
+		return nil
+	}
+	return w.sendSlide(ctx, e)
+}
+
+func (w *Worker) handlePostback(ctx context.Context, e domain.ChannelEvent) error {
+	if e.Postback == nil || e.Postback.Data != downloadSlidePostbackData {
+		return nil
+	}
+	return w.sendSlide(ctx, e)
+}
+
+// ignoreEvent is the handler of event types which need no reply
+func (w *Worker) ignoreEvent(ctx context.Context, e domain.ChannelEvent) error {
+	w.logger(ctx).Debug().Str("eventType", string(e.EventType)).Msg("ignore event")
+	return nil
+}
+
+// sendSlide replies the enabled slide of the channel
+func (w *Worker) sendSlide(ctx context.Context, e domain.ChannelEvent) error {
+	// Query necessary information from repository
+	url, err := w.slideRepo.GetEnabledSlideURL(ctx, e.OrganizationID, e.ChannelID)
+	if err != nil {
+		w.logger(ctx).Error().Err(err).Msg("fail to get enabled slide URL")
+		return err
+	}
+
+	// Reply the image message with slide URL
+	params := line.SendMessageParams{
+		AccessToken: e.ChannelAccessToken,
+		ReplyToken:  e.ReplyToken,
+		To:          e.ConversationID(),
+		Messages:    slideMessages(e.DisplayName, url),
+	}
+	attempts, sendErr := w.lineService.SendMessage(ctx, params)
+	w.recordAttempts(ctx, e.ChannelID, params, attempts)
+	if sendErr != nil {
+		w.logger(ctx).Error().Err(sendErr).Msg("fail to send slide message")
+		return sendErr
+	}
+
+	return nil
+}
+
+// recordAttempts logs attempts of sending the messages. The result of sending is kept even if it
+// cannot be logged.
+func (w *Worker) recordAttempts(ctx context.Context, channelID int, params line.SendMessageParams, attempts []domain.LineSendAttempt) {
+	if len(attempts) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(params.Messages)
+	if err != nil {
+		w.logger(ctx).Error().Err(err).Msg("fail to marshal sent messages")
+		return
+	}
+	_, _ = w.outboundService.RecordAttempts(ctx, outbound.RecordAttemptsParam{
+		ChannelID: channelID,
+		Recipient: params.To,
+		Payload:   payload,
+		Attempts:  attempts,
+	})
+}
+
+// slideMessages replies the slide, greeting the member by name if the profile is known
+func slideMessages(displayName, url string) []linebot.SendingMessage {
+	var messages []linebot.SendingMessage
+	if displayName != "" {
+		messages = append(messages, linebot.NewTextMessage(fmt.Sprintf("Hi %s, here is the slide.", displayName)))
+	}
+	return append(messages, linebot.NewImageMessage(url, url))
+}
@@ -0,0 +1,5 @@
+-- Existing keys had full access to their organization, so they become owners
+alter table api_key
+    add column role varchar(16) default 'owner'::character varying not null;
+alter table api_key
+    alter column role set default 'viewer'::character varying;
@@ -0,0 +1,20 @@
+create table channel_member
+(
+    id                 serial
+        constraint channel_member_pk
+            primary key,
+    channel_id         integer                                not null
+        constraint channel_member_channel_id_fk
+            references channel
+            on delete cascade,
+    external_member_id varchar(64)                            not null,
+    followed_at        timestamp with time zone,
+    unfollowed_at      timestamp with time zone,
+    blocked            boolean                  default false not null,
+    last_seen_at       timestamp with time zone               not null,
+    created_at         timestamp with time zone default now() not null,
+    updated_at         timestamp with time zone default now() not null
+);
+
+create unique index channel_member_channel_id_external_member_id_uniq
+    on channel_member (channel_id, external_member_id);
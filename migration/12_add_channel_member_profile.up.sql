@@ -0,0 +1,6 @@
+alter table channel_member
+    add column display_name       varchar(255)  default ''::character varying not null,
+    add column picture_url        varchar(1024) default ''::character varying not null,
+    add column status_message     text          default ''::text              not null,
+    add column language           varchar(16)   default ''::character varying not null,
+    add column profile_fetched_at timestamp with time zone;
@@ -0,0 +1,34 @@
+create table tag
+(
+    id         serial
+        constraint tag_pk
+            primary key,
+    channel_id integer                                not null
+        constraint tag_channel_id_fk
+            references channel
+            on delete cascade,
+    name       varchar(255)                           not null,
+    created_at timestamp with time zone default now() not null,
+    updated_at timestamp with time zone default now() not null
+);
+
+create unique index tag_channel_id_name_uniq
+    on tag (channel_id, name);
+
+create table tag_channel_member
+(
+    tag_id            integer                                not null
+        constraint tag_channel_member_tag_id_fk
+            references tag
+            on delete cascade,
+    channel_member_id integer                                not null
+        constraint tag_channel_member_channel_member_id_fk
+            references channel_member
+            on delete cascade,
+    created_at        timestamp with time zone default now() not null,
+    constraint tag_channel_member_pk
+        primary key (tag_id, channel_member_id)
+);
+
+create index tag_channel_member_channel_member_id_idx
+    on tag_channel_member (channel_member_id);
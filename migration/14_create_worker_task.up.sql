@@ -0,0 +1,26 @@
+create table worker_task
+(
+    id              serial
+        constraint worker_task_pk
+            primary key,
+    organization_id integer                                                not null
+        constraint worker_task_organization_id_fk
+            references organization
+            on delete cascade,
+    channel_id      integer                                                not null,
+    event_id        varchar(255)                                           not null,
+    event_type      varchar(64)              default ''::character varying not null,
+    payload         jsonb                    default '{}'::jsonb           not null,
+    status          varchar(16)              default 'pending'::character varying not null,
+    attempts        integer                  default 0                     not null,
+    last_error      text                     default ''::text              not null,
+    started_at      timestamp with time zone,
+    finished_at     timestamp with time zone,
+    created_at      timestamp with time zone default now()                 not null,
+    updated_at      timestamp with time zone default now()                 not null
+);
+
+create unique index worker_task_event_id_uniq
+    on worker_task (event_id);
+create index worker_task_organization_id_status_idx
+    on worker_task (organization_id, status);
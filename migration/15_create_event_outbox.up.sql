@@ -0,0 +1,19 @@
+create table event_outbox
+(
+    id              bigserial
+        constraint event_outbox_pk
+            primary key,
+    payload         text                                                   not null,
+    attempts        integer                  default 0                     not null,
+    last_error      text                     default ''::text              not null,
+    next_attempt_at timestamp with time zone default now()                 not null,
+    sent_at         timestamp with time zone,
+    created_at      timestamp with time zone default now()                 not null
+);
+
+create index event_outbox_next_attempt_at_idx
+    on event_outbox (next_attempt_at)
+    where sent_at is null;
+create index event_outbox_sent_at_idx
+    on event_outbox (sent_at)
+    where sent_at is not null;
@@ -0,0 +1,11 @@
+create table webhook_event
+(
+    webhook_event_id varchar(255)                                  not null
+        constraint webhook_event_pk
+            primary key,
+    expire_at        timestamp with time zone                      not null,
+    created_at       timestamp with time zone default now()        not null
+);
+
+create index webhook_event_expire_at_idx
+    on webhook_event (expire_at);
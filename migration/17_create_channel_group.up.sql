@@ -0,0 +1,25 @@
+create table channel_group
+(
+    id                 serial
+        constraint channel_group_pk
+            primary key,
+    channel_id         integer                                                not null
+        constraint channel_group_channel_id_fk
+            references channel
+            on delete cascade,
+    external_group_id  varchar(64)                                            not null,
+    type               varchar(16)                                            not null,
+    name               varchar(255)             default ''::character varying not null,
+    picture_url        varchar(1024)            default ''::character varying not null,
+    member_count       integer                  default 0                     not null,
+    joined_at          timestamp with time zone,
+    left_at            timestamp with time zone,
+    has_left           boolean                  default false                 not null,
+    last_seen_at       timestamp with time zone                               not null,
+    summary_fetched_at timestamp with time zone,
+    created_at         timestamp with time zone default now()                 not null,
+    updated_at         timestamp with time zone default now()                 not null
+);
+
+create unique index channel_group_channel_id_external_group_id_uniq
+    on channel_group (channel_id, external_group_id);
@@ -0,0 +1,30 @@
+create extension if not exists pg_trgm;
+
+create table inbound_message
+(
+    id                  bigserial
+        constraint inbound_message_pk
+            primary key,
+    channel_id          integer                                                not null
+        constraint inbound_message_channel_id_fk
+            references channel
+            on delete cascade,
+    external_message_id varchar(64)                                            not null,
+    external_member_id  varchar(64)              default ''::character varying not null,
+    source_type         varchar(16)              default ''::character varying not null,
+    external_group_id   varchar(64)              default ''::character varying not null,
+    message_type        varchar(32)                                            not null,
+    text                text                     default ''::text              not null,
+    raw                 jsonb                    default '{}'::jsonb           not null,
+    sent_at             timestamp with time zone                               not null,
+    created_at          timestamp with time zone default now()                 not null
+);
+
+create unique index inbound_message_channel_id_external_message_id_uniq
+    on inbound_message (channel_id, external_message_id);
+create index inbound_message_channel_id_sent_at_idx
+    on inbound_message (channel_id, sent_at);
+create index inbound_message_channel_id_external_member_id_sent_at_idx
+    on inbound_message (channel_id, external_member_id, sent_at);
+create index inbound_message_text_trgm_idx
+    on inbound_message using gin (text gin_trgm_ops);
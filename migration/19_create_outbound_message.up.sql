@@ -0,0 +1,24 @@
+create table outbound_message
+(
+    id              bigserial
+        constraint outbound_message_pk
+            primary key,
+    channel_id      integer                                                not null
+        constraint outbound_message_channel_id_fk
+            references channel
+            on delete cascade,
+    delivery_id     varchar(64)                                            not null,
+    recipient       varchar(64)                                            not null,
+    mode            varchar(16)                                            not null,
+    payload         jsonb                    default '[]'::jsonb           not null,
+    line_request_id varchar(64)              default ''::character varying not null,
+    http_status     integer                  default 0                     not null,
+    status          varchar(16)                                            not null,
+    error           text                     default ''::text              not null,
+    created_at      timestamp with time zone default now()                 not null
+);
+
+create index outbound_message_channel_id_created_at_idx
+    on outbound_message (channel_id, created_at);
+create index outbound_message_delivery_id_idx
+    on outbound_message (delivery_id);
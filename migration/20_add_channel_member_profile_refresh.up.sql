@@ -0,0 +1,6 @@
+alter table channel_member
+    add column profile_refresh_attempts integer                  default 0     not null,
+    add column profile_refresh_after    timestamp with time zone default now() not null;
+
+create index channel_member_profile_refresh_after_idx
+    on channel_member (profile_refresh_after);
@@ -0,0 +1,7 @@
+alter table event_outbox
+    add column dead_at timestamp with time zone;
+
+drop index event_outbox_next_attempt_at_idx;
+create index event_outbox_next_attempt_at_idx
+    on event_outbox (next_attempt_at)
+    where sent_at is null and dead_at is null;
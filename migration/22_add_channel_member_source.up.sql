@@ -0,0 +1,3 @@
+alter table channel_member
+    add column source_type       varchar(16) default 'user'::character varying not null,
+    add column external_group_id varchar(64) default ''::character varying     not null;
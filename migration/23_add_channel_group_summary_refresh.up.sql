@@ -0,0 +1,6 @@
+alter table channel_group
+    add column summary_refresh_attempts integer                  default 0     not null,
+    add column summary_refresh_after    timestamp with time zone default now() not null;
+
+create index channel_group_summary_refresh_after_idx
+    on channel_group (summary_refresh_after);
@@ -0,0 +1,2 @@
+alter table outbound_message
+    add column retry_claimed_at timestamp with time zone;
@@ -0,0 +1,22 @@
+-- notify instances caching channels with the external channel ID of the changed channel. Health and
+-- token refresh bookkeeping is left out since cached channels don't use it.
+create or replace function notify_channel_changed() returns trigger as
+$$
+begin
+    if tg_op in ('UPDATE', 'DELETE') then
+        perform pg_notify('channel_changed', old.external_channel_id);
+    end if;
+    if tg_op in ('INSERT', 'UPDATE') then
+        perform pg_notify('channel_changed', new.external_channel_id);
+    end if;
+    return null;
+end;
+$$ language plpgsql;
+
+create trigger channel_changed_trigger
+    after insert or delete or update of organization_id, name, external_channel_id, external_channel_secret,
+        access_token, access_token_expired_at, access_token_version, access_token_key_id, assertion_key_id,
+        assertion_private_key
+    on channel
+    for each row
+execute procedure notify_channel_changed();
@@ -0,0 +1,6 @@
+alter table channel
+    add column access_token_refresh_attempts integer                  default 0     not null,
+    add column access_token_refresh_after    timestamp with time zone default now() not null;
+
+create index channel_access_token_expired_at_idx
+    on channel (access_token_expired_at);
@@ -0,0 +1,16 @@
+create table audit_log
+(
+    id               serial
+        constraint audit_log_pk
+            primary key,
+    actor_id         varchar(255)                                       not null,
+    actor_request_id varchar(255)             default ''::character varying not null,
+    actor_client_ip  varchar(64)              default ''::character varying not null,
+    action           varchar(255)                                       not null,
+    resource_type    varchar(255)                                       not null,
+    resource_id      varchar(255)                                       not null,
+    created_at       timestamp with time zone default now()             not null
+);
+
+create index audit_log_resource_idx
+    on audit_log (resource_type, resource_id);
@@ -0,0 +1,24 @@
+alter table channel
+    add column access_token_version  varchar(16)  default 'v2'::character varying not null,
+    add column access_token_key_id   varchar(255) default ''::character varying   not null,
+    add column assertion_key_id      varchar(255) default ''::character varying   not null,
+    add column assertion_private_key text         default ''::text                not null;
+
+create table channel_access_token
+(
+    id           serial
+        constraint channel_access_token_pk
+            primary key,
+    channel_id   integer                                not null
+        constraint channel_access_token_channel_id_fk_channel_id
+            references channel
+            on delete cascade,
+    key_id       varchar(255)                           not null,
+    access_token text                                   not null,
+    expired_at   timestamp with time zone               not null,
+    revoked_at   timestamp with time zone,
+    created_at   timestamp with time zone default now() not null
+);
+
+create unique index channel_access_token_channel_id_key_id_uniq
+    on channel_access_token (channel_id, key_id);
@@ -0,0 +1,21 @@
+create table access_token_revocation
+(
+    id                      serial
+        constraint access_token_revocation_pk
+            primary key,
+    external_channel_id     varchar(255)                                           not null,
+    external_channel_secret text                                                   not null,
+    access_token            text                                                   not null,
+    access_token_version    varchar(16)                                            not null,
+    access_token_key_id     varchar(255)             default ''::character varying not null,
+    access_token_expired_at timestamp with time zone                               not null,
+    attempts                integer                  default 0                     not null,
+    last_error              text                     default ''::text              not null,
+    next_attempt_at         timestamp with time zone default now()                 not null,
+    revoked_at              timestamp with time zone,
+    created_at              timestamp with time zone default now()                 not null
+);
+
+create index access_token_revocation_next_attempt_at_idx
+    on access_token_revocation (next_attempt_at)
+    where revoked_at is null;
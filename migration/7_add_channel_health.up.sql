@@ -0,0 +1,8 @@
+alter table channel
+    add column health_status      varchar(16)              default 'unknown'::character varying not null,
+    add column health_checked_at  timestamp with time zone,
+    add column health_error       text                     default ''::text                     not null,
+    add column health_check_after timestamp with time zone default now()                        not null;
+
+create index channel_health_check_after_idx
+    on channel (health_check_after);
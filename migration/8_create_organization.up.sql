@@ -0,0 +1,31 @@
+create table organization
+(
+    id         serial
+        constraint organization_pk
+            primary key,
+    name       varchar(255)                                           not null,
+    created_at timestamp with time zone default now()                 not null,
+    updated_at timestamp with time zone default now()                 not null
+);
+
+-- Existing channels and slides are owned by the default organization
+insert into organization (id, name)
+values (1, 'default');
+select setval('organization_id_seq', (select max(id) from organization));
+
+alter table channel
+    add column organization_id integer default 1 not null
+        constraint channel_organization_id_fk
+            references organization;
+alter table channel
+    alter column organization_id drop default;
+
+create index channel_organization_id_idx
+    on channel (organization_id);
+
+alter table slide
+    add column organization_id integer default 1 not null
+        constraint slide_organization_id_fk
+            references organization;
+alter table slide
+    alter column organization_id drop default;
@@ -0,0 +1,23 @@
+create table api_key
+(
+    id              serial
+        constraint api_key_pk
+            primary key,
+    organization_id integer                                                not null
+        constraint api_key_organization_id_fk
+            references organization
+            on delete cascade,
+    name            varchar(255)             default ''::character varying not null,
+    key_prefix      varchar(16)                                            not null,
+    key_hash        varchar(64)                                            not null,
+    created_by      varchar(255)             default ''::character varying not null,
+    last_used_at    timestamp with time zone,
+    expired_at      timestamp with time zone,
+    revoked_at      timestamp with time zone,
+    created_at      timestamp with time zone default now()                 not null
+);
+
+create unique index api_key_key_hash_uniq
+    on api_key (key_hash);
+create index api_key_organization_id_idx
+    on api_key (organization_id);
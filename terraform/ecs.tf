@@ -34,6 +34,14 @@ data "aws_ssm_parameter" "database_dsn" {
   name = "chatbot-postgres-dsn-${var.env}"
 }
 
+data "aws_ssm_parameter" "admin_api_key" {
+  name = "chatbot-admin-api-key-${var.env}"
+}
+
+data "aws_ssm_parameter" "token_secret" {
+  name = "chatbot-token-secret-${var.env}"
+}
+
 ################################
 # ECS Task Definition
 ################################
@@ -55,10 +63,30 @@ module "container_definition" {
   }
 
   environment = [
+    {
+      name  = "EVENT_BUS"
+      value = var.event_bus
+    },
     {
       name  = "AWS_EVENTBRIDGE_NAME"
       value = aws_cloudwatch_event_bus.message_bus.name
     },
+    {
+      name  = "AWS_SQS_QUEUE_URL"
+      value = aws_sqs_queue.message_queue.url
+    },
+    {
+      name  = "ENCRYPTION_KEY_PROVIDER"
+      value = "kms"
+    },
+    {
+      name  = "AWS_KMS_KEY_ID"
+      value = aws_kms_key.channel_credentials.arn
+    },
+    {
+      name  = "PUBLIC_BASE_URL"
+      value = format("https://%s:%d", aws_route53_record.service.fqdn, var.alb_listen_port)
+    },
   ]
 
   secrets = [
@@ -66,6 +94,14 @@ module "container_definition" {
       name      = "DATABASE_DSN"
       valueFrom = data.aws_ssm_parameter.database_dsn.arn
     },
+    {
+      name      = "ADMIN_API_KEY"
+      valueFrom = data.aws_ssm_parameter.admin_api_key.arn
+    },
+    {
+      name      = "TOKEN_SECRET"
+      valueFrom = data.aws_ssm_parameter.token_secret.arn
+    },
   ]
 
   // These 3 fields are optional for Fargate. So we just set to 0 to overwrite the default values.
@@ -57,6 +57,27 @@ data "aws_iam_policy_document" "inline_policy" {
     ]
   }
 
+  statement {
+    effect  = "Allow"
+    actions = [
+      "sqs:SendMessage",
+    ]
+    resources = [
+      aws_sqs_queue.message_queue.arn,
+    ]
+  }
+
+  statement {
+    effect  = "Allow"
+    actions = [
+      "kms:GenerateDataKey",
+      "kms:Decrypt",
+    ]
+    resources = [
+      aws_kms_key.channel_credentials.arn,
+    ]
+  }
+
   statement {
     effect  = "Allow"
     actions = [
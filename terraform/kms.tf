@@ -0,0 +1,19 @@
+################################
+# KMS
+################################
+
+# Wrap the data keys that encrypt channel credentials at rest
+resource "aws_kms_key" "channel_credentials" {
+  description         = "${var.app_name}-${var.env} channel credentials"
+  enable_key_rotation = true
+
+  tags = {
+    Name        = "${var.app_name}-${var.env}-channel-credentials"
+    Environment = var.env
+  }
+}
+
+resource "aws_kms_alias" "channel_credentials" {
+  name          = "alias/${var.app_name}-${var.env}-channel-credentials"
+  target_key_id = aws_kms_key.channel_credentials.key_id
+}
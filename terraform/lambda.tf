@@ -48,6 +48,16 @@ data "aws_iam_policy_document" "chatbot_worker_role_inline_policy" {
     ]
     resources = ["arn:aws:logs:*:*:*"]
   }
+
+  statement {
+    effect  = "Allow"
+    actions = [
+      "sqs:ReceiveMessage",
+      "sqs:DeleteMessage",
+      "sqs:GetQueueAttributes"
+    ]
+    resources = [aws_sqs_queue.message_queue.arn]
+  }
 }
 
 resource "aws_iam_role_policy" "post_camera_snapshot_lambda_inline_policy" {
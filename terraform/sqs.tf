@@ -0,0 +1,35 @@
+resource "aws_sqs_queue" "message_queue_dlq" {
+  name                      = "message-queue-dlq-${var.env}"
+  message_retention_seconds = 1209600
+
+  tags = {
+    Name        = "${var.app_name}-${var.env}"
+    Environment = var.env
+  }
+}
+
+resource "aws_sqs_queue" "message_queue" {
+  name                      = "message-queue-${var.env}"
+  message_retention_seconds = 345600
+
+  // Lambda recommends at least 6 times the function timeout
+  visibility_timeout_seconds = 6 * aws_lambda_function.worker.timeout
+
+  redrive_policy = jsonencode({
+    deadLetterTargetArn = aws_sqs_queue.message_queue_dlq.arn
+    maxReceiveCount     = 5
+  })
+
+  tags = {
+    Name        = "${var.app_name}-${var.env}"
+    Environment = var.env
+  }
+}
+
+resource "aws_lambda_event_source_mapping" "chatbot_worker_sqs" {
+  event_source_arn = aws_sqs_queue.message_queue.arn
+  function_name    = aws_lambda_function.worker.arn
+  batch_size       = 10
+  // Only failed messages of a batch are delivered again
+  function_response_types = ["ReportBatchItemFailures"]
+}
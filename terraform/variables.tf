@@ -52,4 +52,8 @@ variable "desired_task_count" {
 
 variable "max_task_count" {
   default = 10
-}
\ No newline at end of file
+}
+variable "event_bus" {
+  // eventbridge or sqs
+  default = "eventbridge"
+}